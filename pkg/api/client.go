@@ -3,9 +3,12 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/airplanedev/lib/pkg/build"
+	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,6 +19,10 @@ type IAPIClient interface {
 	GetTaskMetadata(ctx context.Context, slug string) (res TaskMetadata, err error)
 	GetView(ctx context.Context, req GetViewRequest) (res View, err error)
 	ListResources(ctx context.Context) (res ListResourcesResponse, err error)
+	// ListConfigs lists the config variables of an environment.
+	ListConfigs(ctx context.Context, req ListConfigsRequest) (res ListConfigsResponse, err error)
+	// ListSecrets lists the secrets of an environment. Their values are not included.
+	ListSecrets(ctx context.Context, req ListSecretsRequest) (res ListSecretsResponse, err error)
 	CreateBuildUpload(ctx context.Context, req CreateBuildUploadRequest) (res CreateBuildUploadResponse, err error)
 }
 
@@ -34,6 +41,7 @@ type Task struct {
 	Constraints                RunConstraints     `json:"constraints" yaml:"constraints"`
 	Env                        TaskEnv            `json:"env" yaml:"env"`
 	ResourceRequests           ResourceRequests   `json:"resourceRequests" yaml:"resourceRequests"`
+	ResourceLimits             ResourceLimits     `json:"resourceLimits" yaml:"resourceLimits"`
 	Resources                  Resources          `json:"resources" yaml:"resources"`
 	Kind                       build.TaskKind     `json:"kind" yaml:"kind"`
 	KindOptions                build.KindOptions  `json:"kindOptions" yaml:"kindOptions"`
@@ -86,6 +94,7 @@ type UpdateTaskRequest struct {
 	Constraints                RunConstraints            `json:"constraints"`
 	Env                        TaskEnv                   `json:"env"`
 	ResourceRequests           map[string]string         `json:"resourceRequests"`
+	ResourceLimits             map[string]string         `json:"resourceLimits"`
 	Resources                  map[string]string         `json:"resources"`
 	Kind                       build.TaskKind            `json:"kind"`
 	KindOptions                build.KindOptions         `json:"kindOptions"`
@@ -108,14 +117,55 @@ type UpdateViewRequest struct {
 }
 
 type UpdateExecuteRulesRequest struct {
-	DisallowSelfApprove *bool `json:"disallowSelfApprove"`
-	RequireRequests     *bool `json:"requireRequests"`
+	DisallowSelfApprove *bool     `json:"disallowSelfApprove"`
+	RequireRequests     *bool     `json:"requireRequests"`
+	RequiredApprovals   *int      `json:"requiredApprovals"`
+	ApproverGroupIDs    *[]string `json:"approverGroupIDs"`
 }
 
 type ListResourcesResponse struct {
 	Resources []Resource `json:"resources"`
 }
 
+type ListConfigsRequest struct {
+	EnvSlug string
+}
+
+type ListConfigsResponse struct {
+	Configs []Config `json:"configs"`
+}
+
+// Config is a config variable, without its value.
+type Config struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Tag      string `json:"tag"`
+	IsSecret bool   `json:"isSecret"`
+}
+
+// NameTag returns the name of the config, with its tag if it has one, e.g.
+// `db_url:prod`, as configs are referred to.
+func (c Config) NameTag() string {
+	if c.Tag == "" {
+		return c.Name
+	}
+	return c.Name + ":" + c.Tag
+}
+
+type ListSecretsRequest struct {
+	EnvSlug string
+}
+
+type ListSecretsResponse struct {
+	Secrets []Secret `json:"secrets"`
+}
+
+// Secret is a secret, without its value.
+type Secret struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
 type Resource struct {
 	ID         string                 `json:"id"`
 	Slug       string                 `json:"slug"`
@@ -172,17 +222,109 @@ const (
 	RoleResourceUser     RoleID = "resource_user"
 )
 
+// TaskRoles are the roles that can be granted on a single task.
+var TaskRoles = []RoleID{
+	RoleTaskViewer,
+	RoleTaskRequester,
+	RoleTaskExecuter,
+	RoleTaskAdmin,
+}
+
+// ResourceRequests are the compute resources, e.g. `cpu` and `memory`, that
+// are reserved for runs of a task, as Kubernetes-style quantities.
 type ResourceRequests map[string]string
 
+// ResourceLimits are the most compute resources that runs of a task can use.
+type ResourceLimits map[string]string
+
 type Resources map[string]string
 
+// TaskEnv are the env vars of a task.
 type TaskEnv map[string]EnvVarValue
 
+// Validate checks that every env var sets exactly one value or reference.
+func (e TaskEnv) Validate() error {
+	return validateEnv(e)
+}
+
+// EnvVars are the env vars of a view. They behave the same as TaskEnv.
 type EnvVars map[string]EnvVarValue
 
+// Validate checks that every env var sets exactly one value or reference.
+func (e EnvVars) Validate() error {
+	return validateEnv(e)
+}
+
+func validateEnv(env map[string]EnvVarValue) error {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := env[name].Validate(); err != nil {
+			return errors.Wrapf(err, "env var %s", name)
+		}
+	}
+	return nil
+}
+
+// EnvVarValue is either a literal value, or a reference to a config
+// variable, a secret or a field of a resource, which is resolved when the
+// task or view runs.
 type EnvVarValue struct {
 	Value  *string `json:"value,omitempty" yaml:"value,omitempty"`
 	Config *string `json:"config,omitempty" yaml:"config,omitempty"`
+	Secret *string `json:"secret,omitempty" yaml:"secret,omitempty"`
+	// Resource refers to a field of a resource as `<resource slug>.<field>`,
+	// e.g. `db.host`.
+	Resource *string `json:"resource,omitempty" yaml:"resource,omitempty"`
+}
+
+// Validate checks that exactly one of the fields of ev is set, and that
+// resource references are well-formed.
+func (ev EnvVarValue) Validate() error {
+	var set []string
+	for _, f := range []struct {
+		name  string
+		value *string
+	}{
+		{"value", ev.Value},
+		{"config", ev.Config},
+		{"secret", ev.Secret},
+		{"resource", ev.Resource},
+	} {
+		if f.value != nil {
+			set = append(set, f.name)
+		}
+	}
+	switch len(set) {
+	case 0:
+		return errors.New("expected one of value, config, secret or resource")
+	case 1:
+	default:
+		return errors.Errorf("expected only one of %s", strings.Join(set, ", "))
+	}
+
+	if ev.Resource != nil {
+		if _, _, err := ev.ResourceField(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResourceField returns the slug of the resource and the field that a
+// resource reference refers to.
+func (ev EnvVarValue) ResourceField() (slug, field string, err error) {
+	if ev.Resource == nil {
+		return "", "", errors.New("not a resource reference")
+	}
+	slug, field, ok := strings.Cut(*ev.Resource, ".")
+	if !ok || slug == "" || field == "" {
+		return "", "", errors.Errorf("invalid resource reference %q, expected <resource slug>.<field>", *ev.Resource)
+	}
+	return slug, field, nil
 }
 
 var _ yaml.Unmarshaler = &EnvVarValue{}
@@ -291,6 +433,33 @@ type Constraints struct {
 	Optional bool               `json:"optional" yaml:"optional,omitempty"`
 	Regex    string             `json:"regex" yaml:"regex,omitempty"`
 	Options  []ConstraintOption `json:"options,omitempty" yaml:"options,omitempty"`
+
+	// Min and Max bound integer and float values.
+	Min *float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max *float64 `json:"max,omitempty" yaml:"max,omitempty"`
+	// MinLength and MaxLength bound the length of text values and the
+	// number of items in list values.
+	MinLength *int `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength *int `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	// MinDate and MaxDate bound date and datetime values. They are in the
+	// same format as the values they bound.
+	MinDate string `json:"minDate,omitempty" yaml:"minDate,omitempty"`
+	MaxDate string `json:"maxDate,omitempty" yaml:"maxDate,omitempty"`
+
+	// VisibleIf hides the parameter unless the condition holds.
+	VisibleIf *ParamCondition `json:"visibleIf,omitempty" yaml:"visibleIf,omitempty"`
+	// RequiredIf makes an optional parameter required when the condition
+	// holds.
+	RequiredIf *ParamCondition `json:"requiredIf,omitempty" yaml:"requiredIf,omitempty"`
+}
+
+// ParamCondition is a condition on the value of another parameter.
+type ParamCondition struct {
+	// Param is the slug of the parameter that the condition refers to.
+	Param string `json:"param" yaml:"param"`
+	// Equals is the value that the parameter must have. If it is nil, the
+	// condition holds if the parameter has any value.
+	Equals Value `json:"equals,omitempty" yaml:"equals,omitempty"`
 }
 
 type ConstraintOption struct {
@@ -314,6 +483,9 @@ const (
 	TypeDate      Type = "date"
 	TypeDatetime  Type = "datetime"
 	TypeConfigVar Type = "configvar"
+	TypeJSON      Type = "json"
+	TypeList      Type = "list"
+	TypeObject    Type = "object"
 )
 
 // Component enumerates components.
@@ -324,6 +496,9 @@ const (
 	ComponentNone      Component = ""
 	ComponentEditorSQL Component = "editor-sql"
 	ComponentTextarea  Component = "textarea"
+	// ComponentMultiSelect is used by list parameters whose values are
+	// chosen from their options.
+	ComponentMultiSelect Component = "multiselect"
 )
 
 // RunConstraints represents run constraints.
@@ -344,6 +519,12 @@ type AgentLabel struct {
 type ExecuteRules struct {
 	DisallowSelfApprove bool `json:"disallowSelfApprove"`
 	RequireRequests     bool `json:"requireRequests"`
+	// RequiredApprovals is the number of approvals that requests need. Zero
+	// means one.
+	RequiredApprovals int `json:"requiredApprovals,omitempty"`
+	// ApproverGroupIDs restricts who can approve requests to members of
+	// these groups. If empty, anyone who can execute the task can approve.
+	ApproverGroupIDs []string `json:"approverGroupIDs,omitempty"`
 }
 
 type View struct {
@@ -373,5 +554,9 @@ type Schedule struct {
 	Name        string                 `json:"name,omitempty"`
 	Description string                 `json:"description,omitempty"`
 	CronExpr    string                 `json:"cronExpr"`
+	Timezone    string                 `json:"timezone,omitempty"`
+	StartAt     *time.Time             `json:"startAt,omitempty"`
+	EndAt       *time.Time             `json:"endAt,omitempty"`
+	Paused      bool                   `json:"paused,omitempty"`
 	ParamValues map[string]interface{} `json:"paramValues,omitempty"`
 }
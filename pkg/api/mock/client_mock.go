@@ -10,6 +10,9 @@ type MockClient struct {
 	Tasks     map[string]api.Task
 	Resources []api.Resource
 	Views     map[string]api.View
+	// Configs and Secrets are keyed by environment slug.
+	Configs map[string][]api.Config
+	Secrets map[string][]api.Secret
 }
 
 var _ api.IAPIClient = &MockClient{}
@@ -40,6 +43,18 @@ func (mc *MockClient) ListResources(ctx context.Context) (res api.ListResourcesR
 	}, nil
 }
 
+func (mc *MockClient) ListConfigs(ctx context.Context, req api.ListConfigsRequest) (res api.ListConfigsResponse, err error) {
+	return api.ListConfigsResponse{
+		Configs: mc.Configs[req.EnvSlug],
+	}, nil
+}
+
+func (mc *MockClient) ListSecrets(ctx context.Context, req api.ListSecretsRequest) (res api.ListSecretsResponse, err error) {
+	return api.ListSecretsResponse{
+		Secrets: mc.Secrets[req.EnvSlug],
+	}, nil
+}
+
 func (mc *MockClient) CreateBuildUpload(ctx context.Context, req api.CreateBuildUploadRequest) (res api.CreateBuildUploadResponse, err error) {
 	return api.CreateBuildUploadResponse{
 		WriteOnlyURL: "writeOnlyURL",
@@ -21,11 +21,15 @@ const (
 	TriggerKindUnknown  TriggerKind = ""
 	TriggerKindForm     TriggerKind = "form"
 	TriggerKindSchedule TriggerKind = "schedule"
+	TriggerKindWebhook  TriggerKind = "webhook"
+	TriggerKindEvent    TriggerKind = "event"
 )
 
 type TriggerKindConfig struct {
 	Form     *TriggerKindConfigForm     `json:"form,omitempty"`
 	Schedule *TriggerKindConfigSchedule `json:"schedule,omitempty"`
+	Webhook  *TriggerKindConfigWebhook  `json:"webhook,omitempty"`
+	Event    *TriggerKindConfigEvent    `json:"event,omitempty"`
 }
 
 type TriggerKindConfigForm struct {
@@ -35,6 +39,38 @@ type TriggerKindConfigForm struct {
 type TriggerKindConfigSchedule struct {
 	ParamValues map[string]interface{} `json:"paramValues"`
 	CronExpr    CronExpr               `json:"cronExpr"`
+	// Timezone is the IANA time zone that CronExpr is evaluated in. An empty
+	// timezone is UTC.
+	Timezone string     `json:"timezone,omitempty"`
+	StartAt  *time.Time `json:"startAt,omitempty"`
+	EndAt    *time.Time `json:"endAt,omitempty"`
+}
+
+type TriggerKindConfigWebhook struct {
+	// ParamMappings maps parameter slugs to JSON paths into the body of the
+	// request, e.g. `$.pull_request.number`.
+	ParamMappings map[string]string `json:"paramMappings,omitempty"`
+}
+
+type TriggerKindConfigEvent struct {
+	// Source is the integration that emits the event, e.g. `github`.
+	Source string `json:"source,omitempty"`
+	// EventType is the type of event that runs the task, e.g.
+	// `pull_request.opened`.
+	EventType string `json:"eventType"`
+	// ParamMappings maps parameter slugs to JSON paths into the payload of
+	// the event.
+	ParamMappings map[string]string `json:"paramMappings,omitempty"`
+}
+
+// TaskTrigger is a trigger that is deployed with a task, such as a webhook.
+// Schedules are deployed as Schedule.
+type TaskTrigger struct {
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Kind        TriggerKind       `json:"kind"`
+	KindConfig  TriggerKindConfig `json:"kindConfig"`
+	Paused      bool              `json:"paused,omitempty"`
 }
 
 type CronExpr struct {
@@ -0,0 +1,224 @@
+package buildcontext
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/airplanedev/lib/pkg/build/ignore"
+	"github.com/pkg/errors"
+)
+
+// DefaultTopN is the number of largest paths reported when
+// AnalyzeOptions.TopN is not set.
+const DefaultTopN = 10
+
+// dominantChildRatio is the share of a directory's bytes that a single
+// child must hold for the directory to be left out of Report.Largest in
+// favor of that child. This keeps the report pointing at the paths that
+// are worth ignoring (e.g. `.venv/lib/python3.9/site-packages/`) instead
+// of every ancestor along the way.
+const dominantChildRatio = 0.9
+
+// Report describes the contents of a build context after ignore
+// patterns have been applied.
+type Report struct {
+	// Root is the directory that was analyzed.
+	Root string
+	// TotalBytes is the sum of the sizes of all included files.
+	TotalBytes int64
+	// FileCount is the number of included files.
+	FileCount int
+	// Largest are the largest included paths, sorted by size.
+	Largest []PathSize
+}
+
+// PathSize describes the size of a single file or directory.
+type PathSize struct {
+	// Path is slash-separated and relative to the root. Directories
+	// have a trailing slash.
+	Path      string
+	IsDir     bool
+	Bytes     int64
+	FileCount int
+}
+
+type AnalyzeOptions struct {
+	// TopN is the number of largest paths to report.
+	//
+	// Defaults to DefaultTopN.
+	TopN int
+
+	// Ignore configures which ignore files are applied.
+	Ignore ignore.Options
+}
+
+// Analyze walks root, applying the same ignore rules as the archiver, and
+// reports how large the resulting context is.
+func Analyze(root string, opts AnalyzeOptions) (Report, error) {
+	if opts.TopN <= 0 {
+		opts.TopN = DefaultTopN
+	}
+
+	include, err := ignore.FuncWithOptions(root, opts.Ignore)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{Root: root}
+	sizes := map[string]*PathSize{}
+	// maxChild tracks the size of the largest child of each directory.
+	maxChild := map[string]int64{}
+
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+
+		ok, err := include(p, info)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return errors.Wrap(err, "getting relative path")
+		}
+		rel = filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			sizes[rel] = &PathSize{Path: rel + "/", IsDir: true}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		size := info.Size()
+		report.TotalBytes += size
+		report.FileCount++
+		sizes[rel] = &PathSize{Path: rel, Bytes: size, FileCount: 1}
+		for dir := filepath.ToSlash(filepath.Dir(rel)); dir != "."; dir = filepath.ToSlash(filepath.Dir(dir)) {
+			if ps, ok := sizes[dir]; ok {
+				ps.Bytes += size
+				ps.FileCount++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Report{}, errors.Wrapf(err, "analyzing %s", root)
+	}
+
+	for rel, ps := range sizes {
+		parent := filepath.ToSlash(filepath.Dir(rel))
+		if ps.Bytes > maxChild[parent] {
+			maxChild[parent] = ps.Bytes
+		}
+	}
+
+	var candidates []PathSize
+	for rel, ps := range sizes {
+		if ps.Bytes == 0 {
+			continue
+		}
+		if ps.IsDir && float64(maxChild[rel]) >= dominantChildRatio*float64(ps.Bytes) {
+			continue
+		}
+		candidates = append(candidates, *ps)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Bytes != candidates[j].Bytes {
+			return candidates[i].Bytes > candidates[j].Bytes
+		}
+		return candidates[i].Path < candidates[j].Path
+	})
+	if len(candidates) > opts.TopN {
+		candidates = candidates[:opts.TopN]
+	}
+	report.Largest = candidates
+
+	return report, nil
+}
+
+// Check returns an ErrContextTooLarge if the context exceeds limit bytes.
+//
+// A limit of zero or less disables the check.
+func (r Report) Check(limit int64) error {
+	if limit <= 0 || r.TotalBytes <= limit {
+		return nil
+	}
+	return errors.WithStack(ErrContextTooLarge{
+		Report: r,
+		Limit:  limit,
+	})
+}
+
+// String returns a one-line summary of the report.
+func (r Report) String() string {
+	return fmt.Sprintf("%s in %d files", FormatBytes(r.TotalBytes), r.FileCount)
+}
+
+// ErrContextTooLarge implements an explainable error.
+type ErrContextTooLarge struct {
+	Report Report
+	Limit  int64
+}
+
+// Error implementation.
+func (err ErrContextTooLarge) Error() string {
+	return fmt.Sprintf(
+		"context for %s is %s, which exceeds the limit of %s",
+		err.Report.Root,
+		err.Report.String(),
+		FormatBytes(err.Limit),
+	)
+}
+
+// ExplainError implementation.
+func (err ErrContextTooLarge) ExplainError() string {
+	if len(err.Report.Largest) == 0 {
+		return ""
+	}
+
+	msgs := []string{"The largest paths are:"}
+	for _, ps := range err.Report.Largest {
+		msgs = append(msgs, fmt.Sprintf("  %10s  %s", FormatBytes(ps.Bytes), ps.Path))
+	}
+	msgs = append(msgs,
+		"",
+		fmt.Sprintf("To exclude paths, add them to %s, for example:", filepath.Join(err.Report.Root, ".airplaneignore")),
+	)
+	for i, ps := range err.Report.Largest {
+		if i == 3 {
+			break
+		}
+		msgs = append(msgs, "  /"+ps.Path)
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// FormatBytes formats n as a human-readable size, e.g. "1.5 MB".
+func FormatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
@@ -0,0 +1,108 @@
+package buildcontext
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]int) {
+	for name, size := range files {
+		p := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(strings.Repeat("x", size)), 0644))
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	require := require.New(t)
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]int{
+		"main.py":                                100,
+		"data/a.csv":                             3000,
+		"data/b.csv":                             2000,
+		"env/lib/python3.9/site-packages/x/x.py": 6000,
+		"env/lib/python3.9/site-packages/y/y.py": 5000,
+		"node_modules/dep/index.js":              100000,
+		".airplaneignore":                        0,
+	})
+
+	report, err := Analyze(root, AnalyzeOptions{TopN: 4})
+	require.NoError(err)
+
+	require.Equal(int64(16100), report.TotalBytes)
+	require.Equal(6, report.FileCount)
+	require.Equal([]PathSize{
+		{Path: "env/lib/python3.9/site-packages/", IsDir: true, Bytes: 11000, FileCount: 2},
+		{Path: "env/lib/python3.9/site-packages/x/x.py", Bytes: 6000, FileCount: 1},
+		{Path: "data/", IsDir: true, Bytes: 5000, FileCount: 2},
+		{Path: "env/lib/python3.9/site-packages/y/y.py", Bytes: 5000, FileCount: 1},
+	}, report.Largest)
+}
+
+func TestAnalyzeAirplaneignore(t *testing.T) {
+	require := require.New(t)
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]int{
+		"main.py":    100,
+		"data/a.csv": 3000,
+	})
+	require.NoError(os.WriteFile(filepath.Join(root, ".airplaneignore"), []byte("data\n"), 0644))
+
+	report, err := Analyze(root, AnalyzeOptions{})
+	require.NoError(err)
+	require.Equal(int64(100+len("data\n")), report.TotalBytes)
+	require.Equal(2, report.FileCount)
+}
+
+func TestCheck(t *testing.T) {
+	require := require.New(t)
+
+	report := Report{
+		Root:       "/tmp/task",
+		TotalBytes: 2500,
+		FileCount:  2,
+		Largest: []PathSize{
+			{Path: "data/", IsDir: true, Bytes: 2400, FileCount: 1},
+			{Path: "main.py", Bytes: 100, FileCount: 1},
+		},
+	}
+
+	require.NoError(report.Check(0))
+	require.NoError(report.Check(2500))
+
+	err := report.Check(1000)
+	require.Error(err)
+	require.Equal("context for /tmp/task is 2.5 kB in 2 files, which exceeds the limit of 1.0 kB", err.Error())
+
+	var tooLarge ErrContextTooLarge
+	require.True(errors.As(err, &tooLarge))
+	require.Equal(`The largest paths are:
+      2.4 kB  data/
+       100 B  main.py
+
+To exclude paths, add them to /tmp/task/.airplaneignore, for example:
+  /data/
+  /main.py`, tooLarge.ExplainError())
+}
+
+func TestFormatBytes(t *testing.T) {
+	for _, test := range []struct {
+		In  int64
+		Out string
+	}{
+		{0, "0 B"},
+		{999, "999 B"},
+		{1000, "1.0 kB"},
+		{1500000, "1.5 MB"},
+		{2000000000, "2.0 GB"},
+	} {
+		require.Equal(t, test.Out, FormatBytes(test.In))
+	}
+}
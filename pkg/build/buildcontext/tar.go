@@ -0,0 +1,161 @@
+package buildcontext
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Epoch is the modification time given to every entry of a
+// deterministic archive.
+var Epoch = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// NormalizeHeader strips hdr of everything that depends on the local
+// filesystem rather than on the contents of the file: timestamps,
+// ownership and permission bits other than the executable bit.
+func NormalizeHeader(hdr *tar.Header) {
+	hdr.ModTime = Epoch
+	hdr.AccessTime = time.Time{}
+	hdr.ChangeTime = time.Time{}
+	hdr.Uid = 0
+	hdr.Gid = 0
+	hdr.Uname = ""
+	hdr.Gname = ""
+	hdr.Devmajor = 0
+	hdr.Devminor = 0
+	hdr.PAXRecords = nil
+	hdr.Xattrs = nil
+	// PAX and GNU formats are only needed for the fields cleared above
+	// or for long names, which the writer picks automatically.
+	hdr.Format = tar.FormatUnknown
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		hdr.Mode = 0755
+	case tar.TypeSymlink:
+		hdr.Mode = 0777
+	default:
+		if hdr.Mode&0111 != 0 {
+			hdr.Mode = 0755
+		} else {
+			hdr.Mode = 0644
+		}
+	}
+}
+
+// Writer writes deterministic, gzipped tarballs: identical inputs
+// produce byte-for-byte identical output regardless of where or when
+// the tarball is created.
+//
+// Every header passed to WriteHeader is normalized with NormalizeHeader.
+// Callers are responsible for writing entries in a stable order.
+type Writer struct {
+	gw *gzip.Writer
+	tw *tar.Writer
+}
+
+// NewWriter returns a Writer that writes a gzipped tarball into w.
+func NewWriter(w io.Writer) *Writer {
+	// The gzip header is left without a name or modification time.
+	gw := gzip.NewWriter(w)
+	return &Writer{
+		gw: gw,
+		tw: tar.NewWriter(gw),
+	}
+}
+
+// WriteHeader normalizes hdr and writes it.
+func (w *Writer) WriteHeader(hdr *tar.Header) error {
+	NormalizeHeader(hdr)
+	return w.tw.WriteHeader(hdr)
+}
+
+// Write writes to the current entry.
+func (w *Writer) Write(b []byte) (int, error) {
+	return w.tw.Write(b)
+}
+
+// Close flushes the tarball. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.tw.Close(); err != nil {
+		return errors.Wrap(err, "closing tar writer")
+	}
+	if err := w.gw.Close(); err != nil {
+		return errors.Wrap(err, "closing gzip writer")
+	}
+	return nil
+}
+
+// EntryWriter writes tar entries. It is implemented by *Writer.
+type EntryWriter interface {
+	io.Writer
+	WriteHeader(hdr *tar.Header) error
+}
+
+// WriteDir writes the contents of root into w, in lexical order, with
+// paths relative to root. Files and directories for which include
+// returns false are left out; include may be nil.
+func WriteDir(w EntryWriter, root string, include func(string, os.FileInfo) (bool, error)) error {
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+
+		if include != nil {
+			ok, err := include(p, info)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return errors.Wrap(err, "getting relative path")
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return errors.Wrapf(err, "reading link %s", p)
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return errors.Wrapf(err, "creating header for %s", p)
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := w.WriteHeader(hdr); err != nil {
+			return errors.Wrapf(err, "writing %s", p)
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return errors.Wrapf(err, "opening %s", p)
+		}
+		defer f.Close()
+		if _, err := io.Copy(w, f); err != nil {
+			return errors.Wrapf(err, "writing %s", p)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,89 @@
+package buildcontext
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func archiveDir(t *testing.T, root string) []byte {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	require.NoError(t, WriteDir(w, root, nil))
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestWriteDirDeterministic(t *testing.T) {
+	require := require.New(t)
+
+	files := map[string]int{
+		"b.txt":     10,
+		"a/c.txt":   20,
+		"a/b/d.txt": 30,
+	}
+	root1 := t.TempDir()
+	writeFiles(t, root1, files)
+	root2 := t.TempDir()
+	writeFiles(t, root2, files)
+
+	// Change everything about the second copy except the contents.
+	later := time.Now().Add(time.Hour)
+	require.NoError(filepath.Walk(root2, func(p string, info os.FileInfo, err error) error {
+		require.NoError(err)
+		if !info.IsDir() {
+			require.NoError(os.Chmod(p, 0600))
+		}
+		return os.Chtimes(p, later, later)
+	}))
+
+	require.Equal(archiveDir(t, root1), archiveDir(t, root2))
+}
+
+func TestWriteDirHeaders(t *testing.T) {
+	require := require.New(t)
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]int{
+		"z.txt":   1,
+		"a/b.txt": 2,
+		"run.sh":  3,
+	})
+	require.NoError(os.Chmod(filepath.Join(root, "run.sh"), 0700))
+
+	gr, err := gzip.NewReader(bytes.NewReader(archiveDir(t, root)))
+	require.NoError(err)
+	tr := tar.NewReader(gr)
+
+	type entry struct {
+		Name string
+		Mode int64
+	}
+	var entries []entry
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(err)
+		require.True(hdr.ModTime.Equal(Epoch))
+		require.Equal(0, hdr.Uid)
+		require.Equal(0, hdr.Gid)
+		require.Empty(hdr.Uname)
+		entries = append(entries, entry{hdr.Name, hdr.Mode})
+	}
+
+	require.Equal([]entry{
+		{"a/", 0755},
+		{"a/b.txt", 0644},
+		{"run.sh", 0755},
+		{"z.txt", 0644},
+	}, entries)
+}
@@ -11,6 +11,7 @@ import (
 	"text/template"
 	"unicode"
 
+	"github.com/airplanedev/lib/pkg/build/buildcontext"
 	"github.com/airplanedev/lib/pkg/build/ignore"
 	"github.com/airplanedev/lib/pkg/utils/bufiox"
 	"github.com/docker/docker/api/types"
@@ -31,6 +32,10 @@ type Response struct {
 	ImageURL string
 	// Optional, only if applicable
 	BuildID string
+	// Context describes the build context that was sent to the builder.
+	// Analyzing the context walks the whole root, so it is nil unless
+	// MaxContextBytes is set.
+	Context *buildcontext.Report
 }
 
 // Host returns the registry hostname.
@@ -65,6 +70,17 @@ type LocalConfig struct {
 
 	// BuildArgs is a map of build-time environment variables to use.
 	BuildArgs map[string]string
+
+	// MaxContextBytes is the maximum size of the build context, after
+	// ignore patterns are applied. Builds with a larger context fail
+	// with a buildcontext.ErrContextTooLarge.
+	//
+	// If zero, the size of the build context is not limited, and it is
+	// not reported in Response.Context.
+	MaxContextBytes int64
+
+	// Ignore configures which ignore files are applied to the build context.
+	Ignore ignore.Options
 }
 
 type DockerfileConfig struct {
@@ -82,6 +98,9 @@ type Builder struct {
 	auth     *RegistryAuth
 	buildEnv map[string]string
 	client   *client.Client
+
+	maxContextBytes int64
+	ignore          ignore.Options
 }
 
 // New returns a new local builder with c.
@@ -113,6 +132,9 @@ func New(c LocalConfig) (*Builder, error) {
 		auth:     c.Auth,
 		buildEnv: c.BuildArgs,
 		client:   client,
+
+		maxContextBytes: c.MaxContextBytes,
+		ignore:          c.Ignore,
 	}, nil
 }
 
@@ -135,12 +157,24 @@ func (b *Builder) Build(ctx context.Context, taskID, version string) (*Response,
 		uri = b.auth.Repo + "/" + uri
 	}
 
-	patterns, err := ignore.DockerignorePatterns(b.root)
-	if err != nil {
-		return nil, err
+	// Analyzing the context walks the whole root, so only do it when there
+	// is a limit to check.
+	var report *buildcontext.Report
+	if b.maxContextBytes > 0 {
+		r, err := buildcontext.Analyze(b.root, buildcontext.AnalyzeOptions{
+			Ignore: b.ignore,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Check(b.maxContextBytes); err != nil {
+			return nil, err
+		}
+		report = &r
 	}
+
 	tree, err := NewTree(TreeOptions{
-		ExcludePatterns: patterns,
+		Ignore: b.ignore,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "new tree")
@@ -216,6 +250,7 @@ func (b *Builder) Build(ctx context.Context, taskID, version string) (*Response,
 
 	return &Response{
 		ImageURL: uri,
+		Context:  report,
 	}, nil
 }
 
@@ -0,0 +1 @@
+util
@@ -7,26 +7,50 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
-	gitignore "github.com/sabhiram/go-gitignore"
 )
 
-// Returns an IgnoreFunc that can be used with airplanedev/archiver to filter
-// out files that match a default list or user-provided .airplaneignore.
+// Ignore files, in order of increasing precedence. Patterns from a file
+// override patterns from the files before it, and all of them override
+// the default excludes.
+const (
+	gitignoreFile      = ".gitignore"
+	dockerignoreFile   = ".dockerignore"
+	airplaneignoreFile = ".airplaneignore"
+)
+
+// SourceDefaults is the Source of the built-in default excludes.
+const SourceDefaults = "defaults"
+
+// Options configures which ignore files are read.
+//
+// Patterns are applied in the following order, with later patterns
+// taking precedence over earlier ones:
+//
+//  1. The default excludes (see Patterns).
+//  2. .gitignore, if Gitignore is set.
+//  3. .dockerignore.
+//  4. .airplaneignore.
+//  5. .airplaneignore files in subdirectories, and .gitignore files if
+//     Gitignore is set. These only apply inside of their own directory,
+//     and deeper files take precedence over shallower ones.
+type Options struct {
+	// Gitignore enables reading .gitignore files.
+	Gitignore bool
+}
+
+// Returns an IgnoreFunc that can be used with buildcontext.WriteDir to filter
+// out files that match a default list or user-provided ignore files.
 func Func(taskRootPath string) (func(filePath string, info os.FileInfo) (bool, error), error) {
-	excludes, err := Patterns(taskRootPath)
+	return FuncWithOptions(taskRootPath, Options{})
+}
+
+// FuncWithOptions is like Func, but reads ignore files according to opts.
+func FuncWithOptions(taskRootPath string, opts Options) (func(filePath string, info os.FileInfo) (bool, error), error) {
+	m, err := NewMatcher(taskRootPath, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	ig := gitignore.CompileIgnoreLines(excludes...)
-	hasInclusion := false
-	for _, pat := range excludes {
-		if strings.HasPrefix(pat, "!") {
-			hasInclusion = true
-			break
-		}
-	}
-
 	return func(filePath string, info os.FileInfo) (bool, error) {
 		// Ignore symbolic links. For example, in Node projects you occasionally see
 		// symbolic links to binaries like `.bin/foobar`  which don't exist.
@@ -39,77 +63,105 @@ func Func(taskRootPath string) (func(filePath string, info os.FileInfo) (bool, e
 			return false, errors.Wrap(err, "getting archive relative path")
 		}
 
-		skip := ig.MatchesPath(relFilePath)
-
-		// If we want to skip this file, and it's a directory, then we can skip it only if there
-		// are no inclusions.
-		// Ideally, we are smarter about this and skip the dir if there's no way for the inclusion
-		// pattern to match the dir. However, this is tricky with things like "!foo/" matching
-		// any directory named foo/ inside this directory.
-		if info.IsDir() && skip && !hasInclusion {
-			return false, nil
+		// As with git, files cannot be re-included if their parent directory
+		// is excluded, so excluded directories can always be skipped.
+		excluded, err := m.Match(relFilePath, info.IsDir())
+		if err != nil {
+			return false, err
 		}
-
-		return !skip, nil
+		return !excluded, nil
 	}, nil
 }
 
+// defaultExcludes are excluded regardless of any ignore files.
+//
+// We exclude the same files regardless of kind because you might have both JS and PY tasks and
+// want pyc files excluded just the same.
+// For inspiration, see:
+// https://github.com/github/gitignore
+// https://github.com/github/gitignore/blob/master/Go.gitignore
+// https://github.com/github/gitignore/blob/master/Node.gitignore
+// https://vercel.com/docs/build-step#ignored-files-and-folders
+var defaultExcludes = []string{
+	".env.local",
+	".env.*.local",
+	"*.pyc",
+	".git",
+	".gitmodules",
+	".hg",
+	".idea",
+	".next",
+	".now",
+	".npm",
+	".svn",
+	".*.swp",
+	".terraform",
+	".venv",
+	".vercel",
+	".yarn",
+	"__pycache__",
+	"node_modules",
+	"npm-debug.log",
+	// Local build artifacts created by `airplane dev`.
+	".airplane",
+}
+
+// Patterns returns the ignore patterns for path in .gitignore format, in
+// order of increasing precedence. It starts with a default set of excludes,
+// followed by the patterns from .dockerignore and .airplaneignore, and then
+// the patterns from any nested .airplaneignore files, rewritten to be
+// relative to path.
+//
+// Note that users can re-INCLUDE files using !, so if our default excludes
+// skip something necessary they can always add it back.
 func Patterns(path string) ([]string, error) {
-	// Start with default set of excludes.
-	// We exclude the same files regardless of kind because you might have both JS and PY tasks and
-	// want pyc files excluded just the same.
-	// For inspiration, see:
-	// https://github.com/github/gitignore
-	// https://github.com/github/gitignore/blob/master/Go.gitignore
-	// https://github.com/github/gitignore/blob/master/Node.gitignore
-	// https://vercel.com/docs/build-step#ignored-files-and-folders
-	excludes := []string{
-		".env.local",
-		".env.*.local",
-		"*.pyc",
-		".git",
-		".gitmodules",
-		".hg",
-		".idea",
-		".next",
-		".now",
-		".npm",
-		".svn",
-		".*.swp",
-		".terraform",
-		".venv",
-		".vercel",
-		".yarn",
-		"__pycache__",
-		"node_modules",
-		"npm-debug.log",
-		// Local build artifacts created by `airplane dev`.
-		".airplane",
+	m, err := NewMatcher(path, Options{})
+	if err != nil {
+		return nil, err
 	}
+	patterns, err := m.Patterns()
+	if err != nil {
+		return nil, err
+	}
+
+	excludes := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		excludes = append(excludes, p.rootRelative())
+	}
+	return excludes, nil
+}
 
-	// Allow user-specified ignore file. Note that users can re-INCLUDE files using !, so if our
-	// default excludes skip something necessary they can always add it back.
-	const ignorefile = ".airplaneignore"
-	bs, err := ioutil.ReadFile(filepath.Join(path, ignorefile))
+// readPatterns reads the patterns from an ignore file, skipping blank
+// lines and comments. If the file does not exist, no patterns are returned.
+func readPatterns(dir, file string) ([]Pattern, error) {
+	bs, err := ioutil.ReadFile(filepath.Join(dir, file))
 	switch {
 	case os.IsNotExist(err):
-		// Nothing additional to append
-		return excludes, nil
+		return nil, nil
 	case err != nil:
-		return nil, errors.Wrap(err, "opening "+ignorefile)
+		return nil, errors.Wrap(err, "opening "+file)
 	}
-	fileExcludes := []string{}
-	for _, ex := range strings.Split(string(bs), "\n") {
-		if ex != "" {
-			fileExcludes = append(fileExcludes, ex)
+
+	var patterns []Pattern
+	for i, line := range strings.Split(string(bs), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
+		patterns = append(patterns, Pattern{
+			Pattern: line,
+			Source:  file,
+			Line:    i + 1,
+		})
 	}
-	excludes = append(excludes, fileExcludes...)
-	return excludes, nil
+	return patterns, nil
 }
 
 // DockerignorePatterns returns the ignore patterns formatted according to
 // the .dockerignore format.
+//
+// Deprecated: the conversion is lossy. Use a Matcher, which applies
+// .gitignore semantics to both the archiver and the build context.
 func DockerignorePatterns(path string) ([]string, error) {
 	patterns, err := Patterns(path)
 	if err != nil {
@@ -143,3 +195,22 @@ func toDockerignore(g string) string {
 		return "**/" + g
 	}
 }
+
+// fromDockerignore converts from .dockerignore format to .gitignore
+// format. Patterns in a .dockerignore are always relative to the root,
+// so they are anchored with a leading slash.
+func fromDockerignore(d string) string {
+	d = strings.TrimSpace(d)
+
+	prefix := ""
+	if strings.HasPrefix(d, "!") {
+		prefix = "!"
+		d = strings.TrimSpace(d[1:])
+	}
+	d = strings.TrimPrefix(d, "./")
+	d = strings.TrimPrefix(d, "/")
+	if d == "" {
+		return ""
+	}
+	return prefix + "/" + d
+}
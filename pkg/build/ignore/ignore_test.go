@@ -1,6 +1,8 @@
 package ignore
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -22,3 +24,164 @@ func TestDockerignorePatterns(tt *testing.T) {
 		})
 	}
 }
+
+func writeIgnoreFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0644))
+	}
+	return root
+}
+
+func TestMatcher(tt *testing.T) {
+	root := writeIgnoreFiles(tt, map[string]string{
+		".gitignore":      "*.log\nbuild/\n",
+		".dockerignore":   "# comment\ndist\n!dist/keep.txt\n",
+		".airplaneignore": "secrets/\n!important.log\n!.yarn\n.yarn/cache\n",
+	})
+
+	for _, test := range []struct {
+		Path     string
+		IsDir    bool
+		Opts     Options
+		Excluded bool
+	}{
+		{Path: "main.js"},
+		{Path: "node_modules", IsDir: true, Excluded: true},
+		{Path: "lib/node_modules/dep/index.js", Excluded: true},
+		{Path: "lib/cache.pyc", Excluded: true},
+		// .gitignore is opt-in.
+		{Path: "debug.log"},
+		{Path: "debug.log", Opts: Options{Gitignore: true}, Excluded: true},
+		{Path: "build", IsDir: true, Opts: Options{Gitignore: true}, Excluded: true},
+		{Path: "build", Opts: Options{Gitignore: true}},
+		// .dockerignore patterns are relative to the root.
+		{Path: "dist", IsDir: true, Excluded: true},
+		{Path: "lib/dist", IsDir: true},
+		// Files cannot be re-included if their parent is excluded.
+		{Path: "dist/keep.txt", Excluded: true},
+		// .airplaneignore takes precedence over the other files.
+		{Path: "important.log", Opts: Options{Gitignore: true}},
+		{Path: "secrets/key.pem", Excluded: true},
+		{Path: ".yarn/releases/yarn.cjs"},
+		{Path: ".yarn/cache/dep.zip", Excluded: true},
+	} {
+		tt.Run(test.Path, func(t *testing.T) {
+			m, err := NewMatcher(root, test.Opts)
+			require.NoError(t, err)
+			excluded, err := m.Match(test.Path, test.IsDir)
+			require.NoError(t, err)
+			require.Equal(t, test.Excluded, excluded)
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	require := require.New(t)
+
+	root := writeIgnoreFiles(t, map[string]string{
+		".dockerignore":   "dist\n",
+		".airplaneignore": "*.csv\n!keep.csv\n",
+	})
+	m, err := NewMatcher(root, Options{})
+	require.NoError(err)
+
+	for _, test := range []struct {
+		Path  string
+		IsDir bool
+		Out   string
+	}{
+		{"main.js", false, "main.js is included: no pattern matched"},
+		{"node_modules", true, `node_modules is excluded by "node_modules" (defaults)`},
+		{"data/a.csv", false, `data/a.csv is excluded by "*.csv" (.airplaneignore:1)`},
+		{"keep.csv", false, `keep.csv is included by "!keep.csv" (.airplaneignore:2)`},
+		{"dist/keep.csv", false, `dist/keep.csv is excluded: its parent directory dist is excluded by "dist" (.dockerignore:1)`},
+	} {
+		e, err := m.Explain(test.Path, test.IsDir)
+		require.NoError(err)
+		require.Equal(test.Out, e.String())
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	require := require.New(t)
+
+	root := writeIgnoreFiles(t, map[string]string{
+		".dockerignore":   "dist\n",
+		".airplaneignore": "*.csv\n",
+	})
+	patterns, err := Patterns(root)
+	require.NoError(err)
+	require.Equal([]string{"/dist", "*.csv"}, patterns[len(patterns)-2:])
+}
+
+func TestFuncNested(t *testing.T) {
+	require := require.New(t)
+
+	root, err := filepath.Abs("./fixtures/nested")
+	require.NoError(err)
+	include, err := Func(root)
+	require.NoError(err)
+
+	var files []string
+	require.NoError(filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		require.NoError(err)
+		if p == root {
+			return nil
+		}
+		ok, err := include(p, info)
+		require.NoError(err)
+		if !ok {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			rel, err := filepath.Rel(root, p)
+			require.NoError(err)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	}))
+
+	require.Equal([]string{
+		".airplaneignore",
+		"main.js",
+		"other/index.js",
+		"pkg/a/.airplaneignore",
+		"pkg/a/index.js",
+		"pkg/a/keep.log",
+		"pkg/a/sub/data/small.csv",
+		"pkg/b/.airplaneignore",
+		"pkg/b/README.md",
+	}, files)
+}
+
+func TestExplainNested(t *testing.T) {
+	require := require.New(t)
+
+	m, err := NewMatcher("./fixtures/nested", Options{})
+	require.NoError(err)
+
+	e, err := m.Explain("pkg/a/keep.log", false)
+	require.NoError(err)
+	require.Equal(`pkg/a/keep.log is included by "!keep.log" (pkg/a/.airplaneignore:3)`, e.String())
+
+	e, err = m.Explain("pkg/b/lib/util.js", false)
+	require.NoError(err)
+	require.Equal(`pkg/b/lib/util.js is excluded by "*.js" (pkg/b/.airplaneignore:1)`, e.String())
+}
+
+func TestDockerignorePatternsNested(t *testing.T) {
+	require := require.New(t)
+
+	patterns, err := DockerignorePatterns("./fixtures/nested")
+	require.NoError(err)
+	require.Equal([]string{
+		"**/*.log",
+		"pkg/a/data",
+		"!pkg/a/**/keep.log",
+		"pkg/b/**/*.js",
+	}, patterns[len(patterns)-4:])
+}
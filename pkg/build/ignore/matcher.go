@@ -0,0 +1,323 @@
+package ignore
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/pkg/errors"
+)
+
+// Pattern is a single ignore pattern along with where it came from.
+type Pattern struct {
+	// Pattern is the pattern as it was written.
+	Pattern string
+	// Source is the ignore file the pattern was read from, relative to
+	// the root, or SourceDefaults for the built-in default excludes.
+	Source string
+	// Line is the 1-based line number of the pattern in Source. It is
+	// zero for the default excludes.
+	Line int
+
+	// gitignore is the pattern converted to .gitignore format.
+	gitignore string
+	// domain is the directory containing the ignore file, split into its
+	// path elements. Patterns only apply to paths inside of their domain.
+	domain  []string
+	matcher gitignore.Pattern
+	// basename is set for patterns without a slash, which only ever
+	// match the last element of a path.
+	basename bool
+}
+
+// String returns the pattern along with its location, e.g.
+// `"dist/" (.airplaneignore:3)`.
+func (p Pattern) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%q (%s)", p.Pattern, p.Source)
+	}
+	return fmt.Sprintf("%q (%s:%d)", p.Pattern, p.Source, p.Line)
+}
+
+// rootRelative returns the pattern in .gitignore format, rewritten so
+// that it has the same meaning when applied from the root.
+func (p Pattern) rootRelative() string {
+	if len(p.domain) == 0 {
+		return p.gitignore
+	}
+
+	g := p.gitignore
+	prefix := ""
+	if strings.HasPrefix(g, "!") {
+		prefix = "!"
+		g = g[1:]
+	}
+	dir := "/" + strings.Join(p.domain, "/")
+	if p.basename {
+		return prefix + dir + "/**/" + g
+	}
+	return prefix + dir + "/" + strings.TrimPrefix(g, "/")
+}
+
+// Matcher decides which files under a root are excluded, using .gitignore
+// semantics for every source of patterns.
+//
+// As with git, the last matching pattern wins, and a file cannot be
+// re-included if one of its parent directories is excluded.
+//
+// Ignore files in subdirectories (.airplaneignore, and .gitignore if
+// enabled) apply to the files inside of that subdirectory and take
+// precedence over ignore files in its parents. They are read lazily,
+// the first time a path inside of their directory is matched.
+type Matcher struct {
+	root string
+	opts Options
+	// patterns are read from the root and apply to every path.
+	patterns []Pattern
+
+	mu sync.Mutex
+	// nested caches the patterns read from ignore files in subdirectories,
+	// keyed by the slash-separated directory relative to the root.
+	nested map[string][]Pattern
+}
+
+// NewMatcher reads the ignore files in root according to opts.
+func NewMatcher(root string, opts Options) (*Matcher, error) {
+	var patterns []Pattern
+	for _, p := range defaultExcludes {
+		patterns = append(patterns, Pattern{Pattern: p, Source: SourceDefaults})
+	}
+
+	if opts.Gitignore {
+		ps, err := readPatterns(root, gitignoreFile)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, ps...)
+	}
+
+	// Like Docker, only a .dockerignore at the root is used.
+	ps, err := readPatterns(root, dockerignoreFile)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ps {
+		ps[i].gitignore = fromDockerignore(ps[i].Pattern)
+	}
+	patterns = append(patterns, ps...)
+
+	ps, err = readPatterns(root, airplaneignoreFile)
+	if err != nil {
+		return nil, err
+	}
+	patterns = append(patterns, ps...)
+
+	return &Matcher{
+		root:     root,
+		opts:     opts,
+		patterns: compilePatterns(patterns, nil),
+		nested:   map[string][]Pattern{},
+	}, nil
+}
+
+func compilePatterns(patterns []Pattern, domain []string) []Pattern {
+	var compiled []Pattern
+	for _, p := range patterns {
+		if p.gitignore == "" {
+			p.gitignore = strings.TrimSpace(p.Pattern)
+		}
+		if p.gitignore == "" {
+			continue
+		}
+		p.domain = domain
+		p.matcher = gitignore.ParsePattern(p.gitignore, domain)
+		p.basename = !strings.Contains(strings.TrimSuffix(strings.TrimPrefix(p.gitignore, "!"), "/"), "/")
+		compiled = append(compiled, p)
+	}
+	return compiled
+}
+
+// nestedPatterns returns the patterns from the ignore files in dir,
+// which is split into its path elements.
+func (m *Matcher) nestedPatterns(dir []string) ([]Pattern, error) {
+	key := strings.Join(dir, "/")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ps, ok := m.nested[key]; ok {
+		return ps, nil
+	}
+
+	files := []string{airplaneignoreFile}
+	if m.opts.Gitignore {
+		files = []string{gitignoreFile, airplaneignoreFile}
+	}
+	var patterns []Pattern
+	for _, file := range files {
+		ps, err := readPatterns(filepath.Join(m.root, filepath.FromSlash(key)), file)
+		if err != nil {
+			return nil, err
+		}
+		for i := range ps {
+			ps[i].Source = path.Join(key, file)
+		}
+		patterns = append(patterns, ps...)
+	}
+
+	patterns = compilePatterns(patterns, dir)
+	m.nested[key] = patterns
+	return patterns, nil
+}
+
+// Match reports whether path, relative to the root, is excluded.
+func (m *Matcher) Match(path string, isDir bool) (bool, error) {
+	e, err := m.Explain(path, isDir)
+	if err != nil {
+		return false, err
+	}
+	return e.Excluded, nil
+}
+
+// Explanation describes why a path is included or excluded.
+type Explanation struct {
+	// Path is the path that was explained, relative to the root.
+	Path string
+	// Excluded is true if the path is left out.
+	Excluded bool
+	// Pattern is the pattern that decided whether the path is excluded.
+	// If nil, no pattern matched and the path is included.
+	Pattern *Pattern
+	// Parent is set if the path is excluded because one of its parent
+	// directories is excluded, in which case Pattern matched Parent.
+	Parent string
+}
+
+// String returns a human-readable explanation.
+func (e Explanation) String() string {
+	switch {
+	case e.Pattern == nil:
+		return fmt.Sprintf("%s is included: no pattern matched", e.Path)
+	case e.Parent != "":
+		return fmt.Sprintf("%s is excluded: its parent directory %s is excluded by %s", e.Path, e.Parent, e.Pattern)
+	case e.Excluded:
+		return fmt.Sprintf("%s is excluded by %s", e.Path, e.Pattern)
+	default:
+		return fmt.Sprintf("%s is included by %s", e.Path, e.Pattern)
+	}
+}
+
+// Explain reports whether path, relative to the root, is excluded and
+// which pattern decided it.
+func (m *Matcher) Explain(path string, isDir bool) (Explanation, error) {
+	path = filepath.ToSlash(filepath.Clean(path))
+	e := Explanation{Path: path}
+	if path == "." || path == "" {
+		return e, nil
+	}
+
+	parts := strings.Split(path, "/")
+	// Check parent directories first: once a directory is excluded,
+	// nothing inside of it can be re-included.
+	for i := 1; i < len(parts); i++ {
+		p, err := m.lastMatch(parts[:i], true)
+		if err != nil {
+			return Explanation{}, err
+		}
+		if p != nil && !isInclusion(p) {
+			e.Excluded = true
+			e.Pattern = p
+			e.Parent = strings.Join(parts[:i], "/")
+			return e, nil
+		}
+	}
+
+	p, err := m.lastMatch(parts, isDir)
+	if err != nil {
+		return Explanation{}, err
+	}
+	if p != nil {
+		e.Pattern = p
+		e.Excluded = !isInclusion(p)
+	}
+	return e, nil
+}
+
+// lastMatch returns the pattern with the highest precedence that matches
+// path, if any.
+func (m *Matcher) lastMatch(path []string, isDir bool) (*Pattern, error) {
+	// Ignore files in deeper directories take precedence.
+	for i := len(path) - 1; i >= 1; i-- {
+		ps, err := m.nestedPatterns(path[:i])
+		if err != nil {
+			return nil, err
+		}
+		if p := lastMatch(ps, path, isDir); p != nil {
+			return p, nil
+		}
+	}
+	return lastMatch(m.patterns, path, isDir), nil
+}
+
+// lastMatch returns the last pattern in patterns that matches path, if any.
+func lastMatch(patterns []Pattern, path []string, isDir bool) *Pattern {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		p := &patterns[i]
+		target := path
+		if p.basename {
+			// Parent directories are matched separately, see Explain.
+			target = make([]string, 0, len(p.domain)+1)
+			target = append(target, p.domain...)
+			target = append(target, path[len(path)-1])
+		}
+		if p.matcher.Match(target, isDir) != gitignore.NoMatch {
+			return p
+		}
+	}
+	return nil
+}
+
+// Patterns returns every pattern that applies under the root, in order of
+// increasing precedence, including the patterns from ignore files in
+// subdirectories that are not themselves excluded.
+func (m *Matcher) Patterns() ([]Pattern, error) {
+	patterns := append([]Pattern{}, m.patterns...)
+	err := filepath.Walk(m.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || p == m.root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(m.root, p)
+		if err != nil {
+			return errors.Wrap(err, "getting relative path")
+		}
+		excluded, err := m.Match(rel, true)
+		if err != nil {
+			return err
+		}
+		if excluded {
+			return filepath.SkipDir
+		}
+
+		ps, err := m.nestedPatterns(strings.Split(filepath.ToSlash(rel), "/"))
+		if err != nil {
+			return err
+		}
+		patterns = append(patterns, ps...)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading ignore files in %s", m.root)
+	}
+	return patterns, nil
+}
+
+func isInclusion(p *Pattern) bool {
+	return strings.HasPrefix(p.gitignore, "!")
+}
@@ -1,60 +1,72 @@
 package build
 
 import (
+	"archive/tar"
+	"bytes"
 	"io"
 	"io/ioutil"
-	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
-	"github.com/docker/docker/pkg/archive"
+	"github.com/airplanedev/lib/pkg/build/buildcontext"
+	"github.com/airplanedev/lib/pkg/build/ignore"
 	"github.com/pkg/errors"
 )
 
 // Tree represents the build tree.
 //
-// It uses docker's archive library to create
-// a tarball and pass it onto the build operation.
+// The tree is never materialized on disk: source directories are
+// streamed straight into the tarball when the tree is archived, and
+// generated files (Dockerfile, shims) are injected as virtual entries.
+//
+// Archives are deterministic: virtual entries are written first, in
+// lexical order, followed by the contents of each source in lexical
+// order, from the last source copied to the first. Every header is
+// normalized with buildcontext.NormalizeHeader. Each path appears once:
+// virtual entries take precedence over sources, and later sources over
+// earlier ones.
 type Tree struct {
-	root string
 	opts TreeOptions
+
+	// sources are the directories that will be streamed into the archive.
+	sources []string
+	// dirs and files are virtual entries, keyed by their slash-separated
+	// path relative to the root of the tree.
+	dirs  map[string]struct{}
+	files map[string][]byte
 }
 
 type TreeOptions struct {
-	// ExcludePatterns is a list of .dockerignore-style ignores.
-	// These files will not be copied to the temporary directory.
-	ExcludePatterns []string
+	// Ignore configures which ignore files are read from each copied
+	// directory. Ignored files will not be included in the archive.
+	Ignore ignore.Options
 }
 
-// NewTree returns a new tree in a temporary directory.
+// NewTree returns a new, empty tree.
 func NewTree(opts TreeOptions) (*Tree, error) {
-	tmpdir, err := ioutil.TempDir("", "airplane_context_*")
-	if err != nil {
-		return nil, errors.Wrap(err, "tempdir")
-	}
-	return &Tree{root: tmpdir, opts: opts}, nil
+	return &Tree{
+		opts:  opts,
+		dirs:  map[string]struct{}{},
+		files: map[string][]byte{},
+	}, nil
 }
 
-// Copy copies src into the tree.
+// Copy adds the contents of src to the tree.
+//
+// The files are not read until the tree is archived. Virtual entries,
+// and sources copied later, take precedence over files of the same name
+// in src.
 func (t *Tree) Copy(src string) error {
-	r, err := archive.TarWithOptions(src, &archive.TarOptions{
-		Compression:     archive.Uncompressed,
-		ExcludePatterns: t.opts.ExcludePatterns,
-	})
-	if err != nil {
-		return errors.Wrap(err, "tar with options")
-	}
-
-	if err := archive.Unpack(r, t.root, &archive.TarOptions{}); err != nil {
-		return errors.Wrapf(err, "unpacking %s", t.root)
-	}
-
+	t.sources = append(t.sources, src)
 	return nil
 }
 
 // MkdirAll creates dir relative to root
 func (t *Tree) MkdirAll(dir string) error {
-	if err := os.MkdirAll(filepath.Join(t.root, dir), 0777); err != nil {
-		return errors.Wrap(err, "making directory")
+	for _, d := range parents(treePath(dir)) {
+		t.dirs[d] = struct{}{}
 	}
 	return nil
 }
@@ -66,19 +78,177 @@ func (t *Tree) Write(dst string, r io.Reader) error {
 		return errors.Wrap(err, "write")
 	}
 
-	return ioutil.WriteFile(filepath.Join(t.root, dst), buf, 0600)
+	p := treePath(dst)
+	if p == "." {
+		return errors.Errorf("write: invalid path %q", dst)
+	}
+	if err := t.MkdirAll(path.Dir(p)); err != nil {
+		return err
+	}
+	t.files[p] = buf
+	return nil
 }
 
-// Archive archives the tree and returns a tarball.
+// Archive archives the tree and returns a gzipped tarball.
+//
+// The tarball is produced lazily as the returned reader is consumed.
 func (t *Tree) Archive() (io.ReadCloser, error) {
-	r, err := archive.Tar(t.root, archive.Gzip)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(t.writeArchive(pw))
+	}()
+	return pr, nil
+}
+
+func (t *Tree) writeArchive(w io.Writer) error {
+	tw := buildcontext.NewWriter(w)
+
+	if err := t.writeVirtual(tw); err != nil {
+		return err
+	}
+
+	// Sources are written last to first, so that when several sources
+	// contain the same path, the last one wins, as if they had been copied
+	// on top of each other.
+	sw := newShadowWriter(tw, t)
+	for i := len(t.sources) - 1; i >= 0; i-- {
+		if err := t.writeSource(sw, t.sources[i]); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+// writeVirtual writes all virtual directories and files into tw.
+func (t *Tree) writeVirtual(tw *buildcontext.Writer) error {
+	dirs := make([]string, 0, len(t.dirs))
+	for d := range t.dirs {
+		dirs = append(dirs, d)
+	}
+	// Parents sort before their children.
+	sort.Strings(dirs)
+	for _, d := range dirs {
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     d + "/",
+			Mode:     0777,
+		}); err != nil {
+			return errors.Wrapf(err, "writing %s", d)
+		}
+	}
+
+	files := make([]string, 0, len(t.files))
+	for f := range t.files {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		buf := t.files[f]
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     f,
+			Mode:     0600,
+			Size:     int64(len(buf)),
+		}); err != nil {
+			return errors.Wrapf(err, "writing %s", f)
+		}
+		if _, err := io.Copy(tw, bytes.NewReader(buf)); err != nil {
+			return errors.Wrapf(err, "writing %s", f)
+		}
+	}
+
+	return nil
+}
+
+// writeSource streams the contents of src into sw, skipping any ignored
+// files.
+func (t *Tree) writeSource(sw *shadowWriter, src string) error {
+	include, err := ignore.FuncWithOptions(src, t.opts.Ignore)
 	if err != nil {
-		return nil, errors.Wrap(err, "tar")
+		return err
+	}
+
+	if err := buildcontext.WriteDir(sw, src, include); err != nil {
+		return errors.Wrapf(err, "archiving %s", src)
 	}
-	return r, nil
+	return nil
+}
+
+// shadowWriter drops entries, along with their contents, whose path has
+// already been written, so that every path appears in the archive once.
+// Entries below a path that was written as a file are dropped too.
+type shadowWriter struct {
+	w *buildcontext.Writer
+	// written maps the paths written so far to whether they are
+	// directories.
+	written  map[string]bool
+	skipping bool
+}
+
+func newShadowWriter(w *buildcontext.Writer, t *Tree) *shadowWriter {
+	written := map[string]bool{}
+	for d := range t.dirs {
+		written[d] = true
+	}
+	for f := range t.files {
+		written[f] = false
+	}
+	return &shadowWriter{w: w, written: written}
+}
+
+func (s *shadowWriter) WriteHeader(hdr *tar.Header) error {
+	name := treePath(hdr.Name)
+	s.skipping = s.shadowed(name)
+	if s.skipping {
+		return nil
+	}
+	s.written[name] = hdr.Typeflag == tar.TypeDir
+	return s.w.WriteHeader(hdr)
+}
+
+func (s *shadowWriter) shadowed(name string) bool {
+	if _, ok := s.written[name]; ok {
+		return true
+	}
+	for _, p := range parents(path.Dir(name)) {
+		if isDir, ok := s.written[p]; ok && !isDir {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *shadowWriter) Write(b []byte) (int, error) {
+	if s.skipping {
+		return len(b), nil
+	}
+	return s.w.Write(b)
 }
 
 // Close discards the tree.
+//
+// Since nothing is written to disk, there is nothing to clean up; Close
+// is kept so callers can treat the tree like any other resource.
 func (t *Tree) Close() error {
-	return os.RemoveAll(t.root)
+	return nil
+}
+
+// treePath normalizes p into a clean, slash-separated path relative
+// to the root of the tree.
+func treePath(p string) string {
+	p = path.Clean("/" + filepath.ToSlash(p))
+	if p == "/" {
+		return "."
+	}
+	return strings.TrimPrefix(p, "/")
+}
+
+// parents returns p and all of its parent directories, excluding the root.
+func parents(p string) []string {
+	var dirs []string
+	for ; p != "." && p != "/"; p = path.Dir(p) {
+		dirs = append(dirs, p)
+	}
+	return dirs
 }
@@ -0,0 +1,295 @@
+package build
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/archive"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeArchive(t *testing.T) {
+	require := require.New(t)
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.js":                   "main",
+		"lib/util.js":               "util",
+		"node_modules/dep/index.js": "dep",
+		".airplane/Dockerfile":      "stale",
+	})
+
+	tree, err := NewTree(TreeOptions{})
+	require.NoError(err)
+	defer tree.Close()
+
+	require.NoError(tree.MkdirAll(".airplane"))
+	require.NoError(tree.Write(".airplane/Dockerfile", strings.NewReader("FROM scratch")))
+	require.NoError(tree.Copy(root))
+
+	r, err := tree.Archive()
+	require.NoError(err)
+	defer r.Close()
+
+	entries := readArchive(t, r)
+	require.Equal(map[string]string{
+		".airplane/":           "",
+		".airplane/Dockerfile": "FROM scratch",
+		"lib/":                 "",
+		"lib/util.js":          "util",
+		"main.js":              "main",
+	}, entries)
+}
+
+func TestTreeWriteCreatesParents(t *testing.T) {
+	require := require.New(t)
+
+	tree, err := NewTree(TreeOptions{})
+	require.NoError(err)
+	require.NoError(tree.Write("a/b/c.txt", strings.NewReader("c")))
+
+	r, err := tree.Archive()
+	require.NoError(err)
+	defer r.Close()
+
+	require.Equal(map[string]string{
+		"a/":        "",
+		"a/b/":      "",
+		"a/b/c.txt": "c",
+	}, readArchive(t, r))
+}
+
+func TestTreeArchiveOverlappingSources(t *testing.T) {
+	require := require.New(t)
+
+	first := t.TempDir()
+	writeFiles(t, first, map[string]string{
+		"shared.txt":  "first",
+		"first.txt":   "first",
+		"lib/a.js":    "first",
+		"conf/x.json": "first",
+		"Dockerfile":  "first",
+	})
+	second := t.TempDir()
+	writeFiles(t, second, map[string]string{
+		"shared.txt": "second",
+		"lib/b.js":   "second",
+		"conf":       "second",
+	})
+
+	tree, err := NewTree(TreeOptions{})
+	require.NoError(err)
+	require.NoError(tree.Write("Dockerfile", strings.NewReader("virtual")))
+	require.NoError(tree.Copy(first))
+	require.NoError(tree.Copy(second))
+
+	r, err := tree.Archive()
+	require.NoError(err)
+	defer r.Close()
+
+	gz, err := gzip.NewReader(r)
+	require.NoError(err)
+	tr := tar.NewReader(gz)
+	entries := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(err)
+		_, dup := entries[hdr.Name]
+		require.False(dup, "duplicate entry %s", hdr.Name)
+		buf, err := ioutil.ReadAll(tr)
+		require.NoError(err)
+		entries[hdr.Name] = string(buf)
+	}
+
+	require.Equal(map[string]string{
+		"Dockerfile": "virtual",
+		"shared.txt": "second",
+		"first.txt":  "first",
+		"lib/":       "",
+		"lib/a.js":   "first",
+		"lib/b.js":   "second",
+		"conf":       "second",
+	}, entries)
+}
+
+// BenchmarkTreeArchive compares streaming the build context against the
+// previous approach of copying the root into a temporary directory and
+// archiving that.
+func BenchmarkTreeArchive(b *testing.B) {
+	root := b.TempDir()
+	files := map[string]string{}
+	content := strings.Repeat("x", 16*1024)
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 20; j++ {
+			files[fmt.Sprintf("pkg%d/file%d.txt", i, j)] = content
+		}
+	}
+	writeFiles(b, root, files)
+	patterns := []string{"**/node_modules"}
+	dockerfile := "FROM scratch"
+
+	b.Run("stream", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tree, err := NewTree(TreeOptions{})
+			require.NoError(b, err)
+			require.NoError(b, tree.Write(".airplane/Dockerfile", strings.NewReader(dockerfile)))
+			require.NoError(b, tree.Copy(root))
+			r, err := tree.Archive()
+			require.NoError(b, err)
+			_, err = io.Copy(ioutil.Discard, r)
+			require.NoError(b, err)
+			require.NoError(b, r.Close())
+			require.NoError(b, tree.Close())
+		}
+	})
+
+	b.Run("tempdir", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r, cleanup, err := tempdirArchive(root, patterns, dockerfile)
+			require.NoError(b, err)
+			_, err = io.Copy(ioutil.Discard, r)
+			require.NoError(b, err)
+			require.NoError(b, r.Close())
+			cleanup()
+		}
+	})
+}
+
+// tempdirArchive reproduces the previous Tree implementation, which
+// copied the root into a temporary directory before archiving it.
+func tempdirArchive(root string, patterns []string, dockerfile string) (io.ReadCloser, func(), error) {
+	tmpdir, err := ioutil.TempDir("", "airplane_context_*")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmpdir) }
+
+	if err := os.MkdirAll(filepath.Join(tmpdir, ".airplane"), 0777); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmpdir, ".airplane/Dockerfile"), []byte(dockerfile), 0600); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	r, err := archive.TarWithOptions(root, &archive.TarOptions{
+		Compression:     archive.Uncompressed,
+		ExcludePatterns: patterns,
+	})
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if err := archive.Unpack(r, tmpdir, &archive.TarOptions{}); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	bc, err := archive.Tar(tmpdir, archive.Gzip)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return bc, cleanup, nil
+}
+
+func writeFiles(tb testing.TB, root string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		require.NoError(tb, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(tb, ioutil.WriteFile(p, []byte(content), 0644))
+	}
+}
+
+// readArchive returns the contents of a gzipped tarball keyed by entry name.
+func readArchive(tb testing.TB, r io.Reader) map[string]string {
+	gr, err := gzip.NewReader(r)
+	require.NoError(tb, err)
+	tr := tar.NewReader(gr)
+
+	entries := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(tb, err)
+		buf, err := ioutil.ReadAll(tr)
+		require.NoError(tb, err)
+		entries[hdr.Name] = string(buf)
+	}
+	return entries
+}
+
+func TestTreeArchiveDeterministic(t *testing.T) {
+	require := require.New(t)
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.js":     "main",
+		"lib/util.js": "util",
+	})
+
+	archive := func() []byte {
+		tree, err := NewTree(TreeOptions{})
+		require.NoError(err)
+		require.NoError(tree.Write(".airplane/Dockerfile", strings.NewReader("FROM scratch")))
+		require.NoError(tree.Copy(root))
+		r, err := tree.Archive()
+		require.NoError(err)
+		defer r.Close()
+		buf, err := ioutil.ReadAll(r)
+		require.NoError(err)
+		return buf
+	}
+
+	first := archive()
+	later := time.Now().Add(time.Hour)
+	require.NoError(os.Chtimes(filepath.Join(root, "main.js"), later, later))
+	require.Equal(first, archive())
+}
+
+func TestTreeArchiveNestedIgnore(t *testing.T) {
+	require := require.New(t)
+
+	root, err := filepath.Abs("./ignore/fixtures/nested")
+	require.NoError(err)
+
+	tree, err := NewTree(TreeOptions{})
+	require.NoError(err)
+	require.NoError(tree.Copy(root))
+
+	r, err := tree.Archive()
+	require.NoError(err)
+	defer r.Close()
+
+	var files []string
+	for name := range readArchive(t, r) {
+		if !strings.HasSuffix(name, "/") {
+			files = append(files, name)
+		}
+	}
+	require.ElementsMatch([]string{
+		".airplaneignore",
+		"main.js",
+		"other/index.js",
+		"pkg/a/.airplaneignore",
+		"pkg/a/index.js",
+		"pkg/a/keep.log",
+		"pkg/a/sub/data/small.csv",
+		"pkg/b/.airplaneignore",
+		"pkg/b/README.md",
+	}, files)
+}
@@ -0,0 +1,283 @@
+// Package cron parses cron expressions and computes when they run.
+//
+// Expressions have the five standard fields (minute, hour, day of month,
+// month and day of week), or are one of the descriptors @yearly, @annually,
+// @monthly, @weekly, @daily, @midnight and @hourly. Fields support lists,
+// ranges, steps and the names of months and days of the week, e.g.
+// `*/15 9-17 * JAN-JUN MON,FRI`.
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/pkg/errors"
+)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	expr api.CronExpr
+
+	// Bit sets of the values of each field.
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+	// The day fields are restricted, rather than `*`. If both are
+	// restricted, a day matches if either matches.
+	domRestricted, dowRestricted bool
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField     = field{name: "minute", min: 0, max: 59}
+	hourField       = field{name: "hour", min: 0, max: 23}
+	dayOfMonthField = field{name: "day of month", min: 1, max: 31}
+	monthField      = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Sunday is both 0 and 7.
+	dayOfWeekField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// Parse parses a cron expression.
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@") {
+		d, ok := descriptors[strings.ToLower(expr)]
+		if !ok {
+			return Schedule{}, errors.Errorf("unknown descriptor %s", expr)
+		}
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, errors.Errorf("expected 5 fields (minute, hour, day of month, month and day of week), got %d", len(fields))
+	}
+
+	s := Schedule{
+		expr: api.CronExpr{
+			Minute:     fields[0],
+			Hour:       fields[1],
+			DayOfMonth: fields[2],
+			Month:      fields[3],
+			DayOfWeek:  fields[4],
+		},
+	}
+	var err error
+	if s.minute, err = minuteField.parse(fields[0]); err != nil {
+		return Schedule{}, err
+	}
+	if s.hour, err = hourField.parse(fields[1]); err != nil {
+		return Schedule{}, err
+	}
+	if s.dayOfMonth, err = dayOfMonthField.parse(fields[2]); err != nil {
+		return Schedule{}, err
+	}
+	if s.month, err = monthField.parse(fields[3]); err != nil {
+		return Schedule{}, err
+	}
+	if s.dayOfWeek, err = dayOfWeekField.parse(fields[4]); err != nil {
+		return Schedule{}, err
+	}
+	if s.dayOfWeek&(1<<7) != 0 {
+		s.dayOfWeek |= 1
+	}
+	s.domRestricted = isRestricted(fields[2])
+	s.dowRestricted = isRestricted(fields[4])
+	return s, nil
+}
+
+// FromCronExpr parses a cron expression in the format used by the API.
+func FromCronExpr(ce api.CronExpr) (Schedule, error) {
+	return Parse(ce.String())
+}
+
+// CronExpr returns the expression in the format used by the API.
+// Descriptors are expanded to their five fields.
+func (s Schedule) CronExpr() api.CronExpr {
+	return s.expr
+}
+
+// String returns the expression, with descriptors expanded.
+func (s Schedule) String() string {
+	return s.expr.String()
+}
+
+func isStar(f string) bool {
+	return f == "*" || f == "?"
+}
+
+// isRestricted returns true if the day field f is restricted. As in Vixie
+// cron, fields that start with a wildcard, such as `*/2` or `?`, are not.
+func isRestricted(f string) bool {
+	rng := f
+	if i := strings.IndexAny(f, "/,"); i >= 0 {
+		rng = f[:i]
+	}
+	return !isStar(rng)
+}
+
+// parse parses a field into a bit set of its values.
+func (f field) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		b, err := f.parsePart(part)
+		if err != nil {
+			return 0, errors.Wrapf(err, "invalid %s %q", f.name, s)
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+// parsePart parses an item of a list: `*`, a value, or a range, with an
+// optional step.
+func (f field) parsePart(s string) (uint64, error) {
+	rng, step, hasStep := s, 1, false
+	if i := strings.Index(s, "/"); i >= 0 {
+		hasStep = true
+		var err error
+		rng = s[:i]
+		step, err = strconv.Atoi(s[i+1:])
+		if err != nil || step <= 0 {
+			return 0, errors.Errorf("invalid step %q", s[i+1:])
+		}
+	}
+
+	var start, end int
+	switch {
+	case isStar(rng):
+		start, end = f.min, f.max
+	case strings.Contains(rng, "-"):
+		i := strings.Index(rng, "-")
+		var err error
+		if start, err = f.value(rng[:i]); err != nil {
+			return 0, err
+		}
+		if end, err = f.value(rng[i+1:]); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, errors.Errorf("range %s is backwards", rng)
+		}
+	default:
+		var err error
+		if start, err = f.value(rng); err != nil {
+			return 0, err
+		}
+		end = start
+		if hasStep {
+			// `n/step` runs from n to the end of the range.
+			end = f.max
+		}
+	}
+
+	var bits uint64
+	for v := start; v <= end; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+// value parses a single value of a field.
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Errorf("invalid value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, errors.Errorf("value %d is out of range %d-%d", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time after t at which the schedule runs, in the
+// location of t. It returns the zero time if the schedule never runs, e.g.
+// for `0 0 30 2 *`.
+func (s Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// Start at the next whole minute.
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Schedules that run at all run within a few years, even on leap days.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !has(s.month, int(t.Month())) {
+			t = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			// Add the duration rather than using time.Date, which moves
+			// times that are skipped by DST backwards.
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// NextN returns the next n times after t at which the schedule runs.
+func (s Schedule) NextN(t time.Time, n int) []time.Time {
+	var times []time.Time
+	for len(times) < n {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+// forward returns next, unless DST moved it to before t, in which case the
+// next hour after t is returned.
+func forward(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Duration(60-t.Minute()) * time.Minute)
+}
+
+func (s Schedule) matchesDay(t time.Time) bool {
+	dom := has(s.dayOfMonth, t.Day())
+	dow := has(s.dayOfWeek, int(t.Weekday()))
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
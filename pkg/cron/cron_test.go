@@ -0,0 +1,149 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		expr string
+		err  string
+	}{
+		{expr: "* * * * *"},
+		{expr: "*/15 9-17 * JAN-JUN mon,FRI"},
+		{expr: "0 0 1,15 * ?"},
+		{expr: "5/10 * * * 7"},
+		{expr: "@hourly"},
+		{expr: "@every 5m", err: "unknown descriptor @every 5m"},
+		{expr: "* * * *", err: "expected 5 fields (minute, hour, day of month, month and day of week), got 4"},
+		{expr: "60 * * * *", err: `invalid minute "60": value 60 is out of range 0-59`},
+		{expr: "* 5-2 * * *", err: `invalid hour "5-2": range 5-2 is backwards`},
+		{expr: "* * 0 * *", err: `invalid day of month "0": value 0 is out of range 1-31`},
+		{expr: "* * * foo *", err: `invalid month "foo": invalid value "foo"`},
+		{expr: "*/0 * * * *", err: `invalid minute "*/0": invalid step "0"`},
+	} {
+		t.Run(test.expr, func(t *testing.T) {
+			_, err := Parse(test.expr)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCronExpr(t *testing.T) {
+	require := require.New(t)
+
+	s, err := Parse("@weekly")
+	require.NoError(err)
+	require.Equal(api.CronExpr{Minute: "0", Hour: "0", DayOfMonth: "*", Month: "*", DayOfWeek: "0"}, s.CronExpr())
+
+	s, err = FromCronExpr(s.CronExpr())
+	require.NoError(err)
+	require.Equal("0 0 * * 0", s.String())
+}
+
+func TestNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	start := time.Date(2022, 3, 12, 22, 30, 15, 0, ny)
+
+	for _, test := range []struct {
+		expr     string
+		expected []string
+	}{
+		{
+			expr: "*/20 * * * *",
+			expected: []string{
+				"2022-03-12T22:40:00-05:00",
+				"2022-03-12T23:00:00-05:00",
+				"2022-03-12T23:20:00-05:00",
+			},
+		},
+		{
+			// The clocks skip from 2am to 3am on 2022-03-13.
+			expr: "30 2 * * *",
+			expected: []string{
+				"2022-03-14T02:30:00-04:00",
+				"2022-03-15T02:30:00-04:00",
+			},
+		},
+		{
+			expr: "0 9 * * MON-FRI",
+			expected: []string{
+				"2022-03-14T09:00:00-04:00",
+				"2022-03-15T09:00:00-04:00",
+			},
+		},
+		{
+			// Either day field matches when both are restricted.
+			expr: "0 0 13 * 5",
+			expected: []string{
+				"2022-03-13T00:00:00-05:00",
+				"2022-03-18T00:00:00-04:00",
+				"2022-03-25T00:00:00-04:00",
+			},
+		},
+		{
+			// Day fields that start with `*` are not restricted, so both
+			// day fields must match.
+			expr: "0 0 */2 * MON",
+			expected: []string{
+				"2022-03-21T00:00:00-04:00",
+				"2022-04-11T00:00:00-04:00",
+				"2022-04-25T00:00:00-04:00",
+			},
+		},
+		{
+			// `?` is a wildcard, so only Mondays match.
+			expr: "0 0 ? * MON",
+			expected: []string{
+				"2022-03-14T00:00:00-04:00",
+				"2022-03-21T00:00:00-04:00",
+				"2022-03-28T00:00:00-04:00",
+			},
+		},
+		{
+			// `n/1` runs from n to the end of the range.
+			expr: "58/1 * * * *",
+			expected: []string{
+				"2022-03-12T22:58:00-05:00",
+				"2022-03-12T22:59:00-05:00",
+				"2022-03-12T23:58:00-05:00",
+			},
+		},
+		{
+			expr: "0 12 29 2 *",
+			expected: []string{
+				"2024-02-29T12:00:00-05:00",
+			},
+		},
+		{
+			expr: "@yearly",
+			expected: []string{
+				"2023-01-01T00:00:00-05:00",
+			},
+		},
+		{
+			expr: "0 0 30 2 *",
+		},
+	} {
+		t.Run(test.expr, func(t *testing.T) {
+			require := require.New(t)
+
+			s, err := Parse(test.expr)
+			require.NoError(err)
+			var actual []string
+			for _, next := range s.NextN(start, len(test.expected)+1)[:len(test.expected)] {
+				actual = append(actual, next.Format(time.RFC3339))
+			}
+			require.Equal(test.expected, actual)
+		})
+	}
+}
@@ -2,12 +2,15 @@ package archive
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
 
-	"github.com/airplanedev/archiver"
 	"github.com/airplanedev/lib/pkg/api"
+	"github.com/airplanedev/lib/pkg/build/buildcontext"
 	"github.com/airplanedev/lib/pkg/build/ignore"
 	"github.com/airplanedev/lib/pkg/utils/logger"
 	"github.com/pkg/errors"
@@ -23,22 +26,61 @@ type apiArchiver struct {
 	client   api.IAPIClient
 	uploader Uploader
 
+	maxContextBytes int64
+	ignore          ignore.Options
+
 	uploadArchiveSingleFlightGroup singleflight.Group
 	uploadedArchives               map[string]string
 }
 
 var _ Archiver = &apiArchiver{}
 
+// APIArchiverOptions configures an API archiver.
+type APIArchiverOptions struct {
+	// MaxContextBytes is the maximum size of a task root, after ignore
+	// patterns are applied. Larger roots fail to archive with a
+	// buildcontext.ErrContextTooLarge.
+	//
+	// If zero, the size is not limited.
+	MaxContextBytes int64
+
+	// Ignore configures which ignore files are applied to task roots.
+	Ignore ignore.Options
+}
+
 func NewAPIArchiver(logger logger.Logger, client api.IAPIClient, uploader Uploader) Archiver {
+	return NewAPIArchiverWithOptions(logger, client, uploader, APIArchiverOptions{})
+}
+
+func NewAPIArchiverWithOptions(logger logger.Logger, client api.IAPIClient, uploader Uploader, opts APIArchiverOptions) Archiver {
 	return &apiArchiver{
 		uploadedArchives: make(map[string]string),
 		logger:           logger,
 		client:           client,
 		uploader:         uploader,
+		maxContextBytes:  opts.MaxContextBytes,
+		ignore:           opts.Ignore,
 	}
 }
 
 func (d *apiArchiver) Archive(ctx context.Context, root string) (string, int, error) {
+	// Analyzing the root walks all of it, so only do it when there is a
+	// limit to check.
+	if d.maxContextBytes > 0 {
+		report, err := buildcontext.Analyze(root, buildcontext.AnalyzeOptions{
+			Ignore: d.ignore,
+		})
+		if err != nil {
+			return "", 0, err
+		}
+		d.logger.Debug("Archiving %s (%s)", root, report)
+		if err := report.Check(d.maxContextBytes); err != nil {
+			return "", 0, err
+		}
+	} else {
+		d.logger.Debug("Archiving %s", root)
+	}
+
 	tmpdir, err := ioutil.TempDir("", "airplane-builds-")
 	if err != nil {
 		return "", 0, errors.Wrap(err, "creating temporary directory for remote build")
@@ -46,12 +88,15 @@ func (d *apiArchiver) Archive(ctx context.Context, root string) (string, int, er
 	defer os.RemoveAll(tmpdir)
 
 	archivePath := path.Join(tmpdir, "archive.tar.gz")
-	if err := archiveTaskDir(root, archivePath); err != nil {
+	hash, err := archiveTaskDir(root, archivePath, d.ignore)
+	if err != nil {
 		return "", 0, err
 	}
 
-	uploadIDRes, err, _ := d.uploadArchiveSingleFlightGroup.Do(root, func() (interface{}, error) {
-		return d.uploadArchive(ctx, archivePath, root)
+	// Archives are deterministic, so identical contents can share an upload
+	// even if they live under different roots.
+	uploadIDRes, err, _ := d.uploadArchiveSingleFlightGroup.Do(hash, func() (interface{}, error) {
+		return d.uploadArchive(ctx, archivePath, hash)
 	})
 	if err != nil {
 		return "", 0, err
@@ -65,11 +110,11 @@ type uploadRes struct {
 	sizeBytes int
 }
 
-func (d *apiArchiver) uploadArchive(ctx context.Context, archivePath, rootPath string) (uploadRes, error) {
-	// Check if anyone has uploaded an archive for this path.
-	uid, ok := d.uploadedArchives[rootPath]
+func (d *apiArchiver) uploadArchive(ctx context.Context, archivePath, hash string) (uploadRes, error) {
+	// Check if anyone has uploaded an archive with the same contents.
+	uid, ok := d.uploadedArchives[hash]
 	if ok {
-		// Somebody has already uploaded the path. Re-use the upload ID.
+		// Somebody has already uploaded the contents. Re-use the upload ID.
 		return uploadRes{uploadID: uid}, nil
 	}
 
@@ -99,35 +144,36 @@ func (d *apiArchiver) uploadArchive(ctx context.Context, archivePath, rootPath s
 	uploadID := upload.Upload.ID
 
 	// Populate the cache so that we can reuse the upload.
-	d.uploadedArchives[rootPath] = uploadID
+	d.uploadedArchives[hash] = uploadID
 
 	return uploadRes{uploadID: uploadID, sizeBytes: sizeBytes}, nil
 }
 
-func archiveTaskDir(root string, archivePath string) error {
-	// mholt/archiver takes a list of "sources" (files/directories) that will
-	// be included in the root of the archive. In our case, we want the root of
-	// the archive to be the contents of the task directory, rather than the
-	// task directory itself.
-	var sources []string
-	if files, err := ioutil.ReadDir(root); err != nil {
-		return errors.Wrap(err, "inspecting files in task root")
-	} else {
-		for _, f := range files {
-			sources = append(sources, path.Join(root, f.Name()))
-		}
+// archiveTaskDir writes a deterministic tarball of root to archivePath and
+// returns the hex-encoded SHA-256 hash of its contents.
+func archiveTaskDir(root string, archivePath string, opts ignore.Options) (string, error) {
+	include, err := ignore.FuncWithOptions(root, opts)
+	if err != nil {
+		return "", err
 	}
 
-	var err error
-	arch := archiver.NewTarGz()
-	arch.Tar.IncludeFunc, err = ignore.Func(root)
+	f, err := os.Create(archivePath)
 	if err != nil {
-		return err
+		return "", errors.Wrap(err, "creating archive")
 	}
+	defer f.Close()
 
-	if err := arch.Archive(sources, archivePath); err != nil {
-		return errors.Wrap(err, "building archive")
+	h := sha256.New()
+	w := buildcontext.NewWriter(io.MultiWriter(f, h))
+	if err := buildcontext.WriteDir(w, root, include); err != nil {
+		return "", errors.Wrap(err, "building archive")
+	}
+	if err := w.Close(); err != nil {
+		return "", errors.Wrap(err, "building archive")
+	}
+	if err := f.Close(); err != nil {
+		return "", errors.Wrap(err, "closing archive")
 	}
 
-	return nil
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
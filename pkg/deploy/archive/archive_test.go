@@ -2,11 +2,14 @@ package archive
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/airplanedev/lib/pkg/api/mock"
+	"github.com/airplanedev/lib/pkg/build/buildcontext"
 	"github.com/airplanedev/lib/pkg/utils/logger"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -57,3 +60,37 @@ func TestArchive(t *testing.T) {
 		})
 	}
 }
+
+func TestArchiveMaxContextBytes(t *testing.T) {
+	require := require.New(t)
+	fixturesPath, _ := filepath.Abs("./fixtures")
+
+	uploader := &MockUploader{}
+	archiver := NewAPIArchiverWithOptions(&logger.MockLogger{}, &mock.MockClient{}, uploader, APIArchiverOptions{
+		MaxContextBytes: 1,
+	})
+
+	_, _, err := archiver.Archive(context.Background(), fixturesPath)
+	var tooLarge buildcontext.ErrContextTooLarge
+	require.True(errors.As(err, &tooLarge))
+	require.Equal(0, uploader.UploadCount)
+}
+
+func TestArchiveDedupesByContent(t *testing.T) {
+	require := require.New(t)
+
+	var roots []string
+	for i := 0; i < 2; i++ {
+		root := t.TempDir()
+		require.NoError(os.WriteFile(filepath.Join(root, "main.js"), []byte("main"), 0644))
+		roots = append(roots, root)
+	}
+
+	uploader := &MockUploader{}
+	archiver := NewAPIArchiver(&logger.MockLogger{}, &mock.MockClient{}, uploader)
+	for _, root := range roots {
+		_, _, err := archiver.Archive(context.Background(), root)
+		require.NoError(err)
+	}
+	require.Equal(1, uploader.UploadCount)
+}
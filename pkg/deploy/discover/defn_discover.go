@@ -25,8 +25,10 @@ type DefnDiscoverer struct {
 	MissingTaskHandler func(context.Context, definitions.DefinitionInterface) (*api.TaskMetadata, error)
 }
 
-var _ TaskDiscoverer = &DefnDiscoverer{}
+var _ MultiTaskDiscoverer = &DefnDiscoverer{}
 
+// IsAirplaneTask returns the slug of the task that file defines. For files
+// that define several tasks, it returns the slug of the first one.
 func (dd *DefnDiscoverer) IsAirplaneTask(ctx context.Context, file string) (string, error) {
 	if !definitions.IsTaskDef(file) {
 		return "", nil
@@ -38,23 +40,21 @@ func (dd *DefnDiscoverer) IsAirplaneTask(ctx context.Context, file string) (stri
 	}
 	defer dir.Close()
 
-	def, err := dir.ReadDefinition()
+	defs, err := dir.ReadDefinitions()
 	if err != nil {
 		return "", err
 	}
 
-	return def.GetSlug(), nil
+	return defs[0].GetSlug(), nil
 }
 
+// GetTaskConfig returns the config of the task that file defines. For files
+// that define several tasks, it returns the config of the first one; use
+// GetTaskConfigs to get all of them.
 func (dd *DefnDiscoverer) GetTaskConfig(ctx context.Context, file string) (*TaskConfig, error) {
 	if !definitions.IsTaskDef(file) {
-		// Check if there is a file in the same directory with the same name that is a task defn.
-		fileWithoutExtension := strings.TrimSuffix(file, filepath.Ext(file))
-		for _, tde := range definitions.TaskDefExtensions {
-			fileWithTaskDefExtension := fileWithoutExtension + tde
-			if fsx.Exists(fileWithTaskDefExtension) {
-				return dd.GetTaskConfig(ctx, fileWithTaskDefExtension)
-			}
+		if defnFile := taskDefForFile(file); defnFile != "" {
+			return dd.GetTaskConfig(ctx, defnFile)
 		}
 		return nil, nil
 	}
@@ -65,11 +65,61 @@ func (dd *DefnDiscoverer) GetTaskConfig(ctx context.Context, file string) (*Task
 	}
 	defer dir.Close()
 
-	def, err := dir.ReadDefinition()
+	defs, err := dir.ReadDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	return dd.getTaskConfig(ctx, dir, defs[0])
+}
+
+// GetTaskConfigs is like GetTaskConfig, but supports definition files that
+// define several tasks. Tasks that should not be discovered are left out.
+func (dd *DefnDiscoverer) GetTaskConfigs(ctx context.Context, file string) ([]TaskConfig, error) {
+	if !definitions.IsTaskDef(file) {
+		if defnFile := taskDefForFile(file); defnFile != "" {
+			return dd.GetTaskConfigs(ctx, defnFile)
+		}
+		return nil, nil
+	}
+
+	dir, err := taskdir.Open(file)
 	if err != nil {
 		return nil, err
 	}
+	defer dir.Close()
+
+	defs, err := dir.ReadDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	var tcs []TaskConfig
+	for _, def := range defs {
+		tc, err := dd.getTaskConfig(ctx, dir, def)
+		if err != nil {
+			return nil, err
+		}
+		if tc != nil {
+			tcs = append(tcs, *tc)
+		}
+	}
+	return tcs, nil
+}
+
+// taskDefForFile returns the task definition in the same directory as file
+// with the same name, e.g. `my_task.task.yaml` for `my_task.sql`, or an
+// empty string if there is none.
+func taskDefForFile(file string) string {
+	fileWithoutExtension := strings.TrimSuffix(file, filepath.Ext(file))
+	for _, tde := range definitions.TaskDefExtensions {
+		fileWithTaskDefExtension := fileWithoutExtension + tde
+		if fsx.Exists(fileWithTaskDefExtension) {
+			return fileWithTaskDefExtension
+		}
+	}
+	return ""
+}
 
+func (dd *DefnDiscoverer) getTaskConfig(ctx context.Context, dir taskdir.TaskDirectory, def definitions.DefinitionInterface) (*TaskConfig, error) {
 	tc := TaskConfig{
 		Def:    def,
 		Source: dd.ConfigSource(),
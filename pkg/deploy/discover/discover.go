@@ -64,6 +64,14 @@ type TaskDiscoverer interface {
 	ConfigSource() ConfigSource
 }
 
+// MultiTaskDiscoverer is a TaskDiscoverer that can discover several tasks in
+// a single file.
+type MultiTaskDiscoverer interface {
+	TaskDiscoverer
+	// GetTaskConfigs is like GetTaskConfig, but returns a task config for each task in the file.
+	GetTaskConfigs(ctx context.Context, file string) ([]TaskConfig, error)
+}
+
 type ViewDiscoverer interface {
 	// GetTaskConfig converts an Airplane task file into a fully-qualified task definition.
 	// If the task should not be discovered as an Airplane task, a nil task config is returned.
@@ -132,19 +140,17 @@ func (d *Discoverer) Discover(ctx context.Context, paths ...string) ([]TaskConfi
 		} else {
 			// We found a file.
 			for _, td := range d.TaskDiscoverers {
-				taskConfig, err := td.GetTaskConfig(ctx, p)
+				taskConfigs, err := getTaskConfigs(ctx, td, p)
 				if err != nil {
 					return nil, nil, err
 				}
-				if taskConfig == nil {
-					// This file is not an Airplane task.
-					continue
-				}
-				slug := taskConfig.Def.GetSlug()
-				if _, ok := taskConfigsBySlug[slug]; !ok {
-					taskConfigsBySlug[slug] = []TaskConfig{}
+				for _, taskConfig := range taskConfigs {
+					slug := taskConfig.Def.GetSlug()
+					if _, ok := taskConfigsBySlug[slug]; !ok {
+						taskConfigsBySlug[slug] = []TaskConfig{}
+					}
+					taskConfigsBySlug[slug] = append(taskConfigsBySlug[slug], taskConfig)
 				}
-				taskConfigsBySlug[slug] = append(taskConfigsBySlug[slug], *taskConfig)
 			}
 			for _, vd := range d.ViewDiscoverers {
 				viewConfig, err := vd.GetViewConfig(ctx, p)
@@ -167,6 +173,19 @@ func (d *Discoverer) Discover(ctx context.Context, paths ...string) ([]TaskConfi
 	return deduplicateConfigs(taskConfigsBySlug, d.TaskDiscoverers), deduplicateConfigs(viewConfigsBySlug, d.ViewDiscoverers), nil
 }
 
+// getTaskConfigs returns the task configs that td discovers in file, which
+// is empty if the file is not an Airplane task.
+func getTaskConfigs(ctx context.Context, td TaskDiscoverer, file string) ([]TaskConfig, error) {
+	if mtd, ok := td.(MultiTaskDiscoverer); ok {
+		return mtd.GetTaskConfigs(ctx, file)
+	}
+	taskConfig, err := td.GetTaskConfig(ctx, file)
+	if err != nil || taskConfig == nil {
+		return nil, err
+	}
+	return []TaskConfig{*taskConfig}, nil
+}
+
 // deduplicateConfigs returns a list of configs unique by slug, sorted by slug
 // from a map of slug -> [task config, ...]. Configs are chosen based on order of Discoverers & order of discovery.
 func deduplicateConfigs[C interface{ GetSource() ConfigSource }, D ConfigDiscoverer](taskConfigsBySlug map[string][]C, configDiscoverers []D) []C {
@@ -432,3 +432,111 @@ func TestDiscover(t *testing.T) {
 		})
 	}
 }
+
+func TestDiscoverMultipleTasksPerDefn(t *testing.T) {
+	fixturesPath, _ := filepath.Abs("./fixtures/multi")
+	apiClient := &mock.MockClient{
+		Tasks: map[string]api.Task{
+			"list_users":  {ID: "tsk123", Slug: "list_users", Kind: build.TaskKindSQL},
+			"delete_user": {ID: "tsk456", Slug: "delete_user", Kind: build.TaskKindSQL},
+			"get_status":  {ID: "tsk789", Slug: "get_status", Kind: build.TaskKindREST},
+		},
+	}
+	d := &Discoverer{
+		TaskDiscoverers: []TaskDiscoverer{
+			&DefnDiscoverer{
+				Client: apiClient,
+				Logger: &logger.MockLogger{},
+			},
+		},
+		Client: apiClient,
+		Logger: &logger.MockLogger{},
+	}
+
+	for _, test := range []struct {
+		name        string
+		path        string
+		slugs       []string
+		taskIDs     []string
+		entrypoints []string
+		expectedErr bool
+	}{
+		{
+			name:    "tasks list",
+			path:    "./fixtures/multi/users.task.yaml",
+			slugs:   []string{"delete_user", "list_users"},
+			taskIDs: []string{"tsk456", "tsk123"},
+			entrypoints: []string{
+				fixturesPath + "/delete_user.sql",
+				fixturesPath + "/list_users.sql",
+			},
+		},
+		{
+			name:    "documents",
+			path:    "./fixtures/multi/documents.task.yaml",
+			slugs:   []string{"get_status", "list_users"},
+			taskIDs: []string{"tsk789", "tsk123"},
+			entrypoints: []string{
+				"",
+				fixturesPath + "/list_users.sql",
+			},
+		},
+		{
+			name:        "invalid entry",
+			path:        "./fixtures/multi/invalid.task.yaml",
+			expectedErr: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			taskConfigs, _, err := d.Discover(context.Background(), test.path)
+			if test.expectedErr {
+				require.Error(err)
+				require.Contains(err.Error(), "(tasks.1)")
+				return
+			}
+			require.NoError(err)
+
+			require.Len(taskConfigs, len(test.slugs))
+			for i, tc := range taskConfigs {
+				require.Equal(test.slugs[i], tc.Def.GetSlug())
+				require.Equal(test.taskIDs[i], tc.TaskID)
+				require.Equal(test.entrypoints[i], tc.TaskEntrypoint)
+				require.Equal(ConfigSourceDefn, tc.Source)
+			}
+		})
+	}
+}
+
+func TestDefnDiscovererMultipleTasksPerDefn(t *testing.T) {
+	require := require.New(t)
+
+	fixturesPath, _ := filepath.Abs("./fixtures/multi")
+	dd := &DefnDiscoverer{
+		Client: &mock.MockClient{
+			Tasks: map[string]api.Task{
+				"list_users":  {ID: "tsk123", Slug: "list_users", Kind: build.TaskKindSQL},
+				"delete_user": {ID: "tsk456", Slug: "delete_user", Kind: build.TaskKindSQL},
+			},
+		},
+		Logger: &logger.MockLogger{},
+	}
+
+	slug, err := dd.IsAirplaneTask(context.Background(), "./fixtures/multi/users.task.yaml")
+	require.NoError(err)
+	require.Equal("list_users", slug)
+
+	tc, err := dd.GetTaskConfig(context.Background(), "./fixtures/multi/users.task.yaml")
+	require.NoError(err)
+	require.NotNil(tc)
+	require.Equal("list_users", tc.Def.GetSlug())
+	require.Equal("tsk123", tc.TaskID)
+	require.Equal(fixturesPath+"/list_users.sql", tc.TaskEntrypoint)
+
+	tcs, err := dd.GetTaskConfigs(context.Background(), "./fixtures/multi/users.task.yaml")
+	require.NoError(err)
+	require.Len(tcs, 2)
+	require.Equal("delete_user", tcs[1].Def.GetSlug())
+	require.Equal("tsk456", tcs[1].TaskID)
+}
@@ -0,0 +1,5 @@
+// Linked to https://app.airplane.dev/t/my_task [do not edit this line]
+
+export default async function(params: { name: string }) {
+  console.log(params.name);
+}
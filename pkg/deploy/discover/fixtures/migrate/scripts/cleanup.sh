@@ -0,0 +1,4 @@
+#!/bin/bash
+# Linked to https://app.airplane.dev/t/cleanup [do not edit this line]
+
+echo "cleaning up"
@@ -0,0 +1,4 @@
+# Linked to https://app.airplane.dev/t/deleted [do not edit this line]
+
+def main(params):
+    pass
@@ -0,0 +1 @@
+export default async function(params) {}
@@ -0,0 +1,205 @@
+package discover
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/airplanedev/lib/pkg/deploy/taskdir/definitions"
+	"github.com/airplanedev/lib/pkg/runtime"
+	"github.com/airplanedev/lib/pkg/utils/fsx"
+	"github.com/airplanedev/lib/pkg/utils/logger"
+	"github.com/pkg/errors"
+)
+
+// Migration moves the configuration of a task that is linked to a script to
+// a task definition file next to the script.
+type Migration struct {
+	Slug string
+	// ScriptPath is the path of the linked script.
+	ScriptPath string
+	// Script is the code of the script, without the comment that links it.
+	Script []byte
+	// DefnPath is the path of the task definition file to create.
+	DefnPath string
+	// Defn is the contents of the task definition file.
+	Defn []byte
+}
+
+// String describes the migration, e.g. for a dry run.
+func (m Migration) String() string {
+	return fmt.Sprintf("%s: create %s and unlink %s", m.Slug, m.DefnPath, m.ScriptPath)
+}
+
+// ScriptMigrator migrates tasks that are linked to scripts, with a `Linked
+// to` comment, to task definition files.
+//
+// Migrations are planned first, so that they can be shown to users before
+// any files are changed.
+type ScriptMigrator struct {
+	Client  api.IAPIClient
+	Logger  logger.Logger
+	EnvSlug string
+	// Format is the format of task definition files, defaults to YAML.
+	Format definitions.DefFormat
+}
+
+// Plan returns the migrations of every linked script under paths. Files are
+// not changed.
+//
+// Scripts whose tasks are missing or archived, or that already have a task
+// definition file, are skipped with a warning.
+func (sm *ScriptMigrator) Plan(ctx context.Context, paths ...string) ([]Migration, error) {
+	var migrations []Migration
+	for _, p := range paths {
+		err := filepath.WalkDir(p, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if ignoredDirectories[entry.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			m, err := sm.plan(ctx, path)
+			if err != nil {
+				return err
+			}
+			if m != nil {
+				migrations = append(migrations, *m)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "planning migration of %s", p)
+		}
+	}
+	return migrations, nil
+}
+
+// plan returns the migration of the script at path, or nil if it should not
+// be migrated.
+func (sm *ScriptMigrator) plan(ctx context.Context, path string) (*Migration, error) {
+	slug := runtime.Slug(path)
+	if slug == "" {
+		return nil, nil
+	}
+
+	task, err := sm.Client.GetTask(ctx, api.GetTaskRequest{
+		Slug:    slug,
+		EnvSlug: sm.EnvSlug,
+	})
+	if err != nil {
+		var merr *api.TaskMissingError
+		if !errors.As(err, &merr) {
+			return nil, errors.Wrap(err, "unable to get task")
+		}
+
+		sm.Logger.Warning(`Task with slug %s does not exist, skipping migration of %s.`, slug, path)
+		return nil, nil
+	}
+	if task.IsArchived {
+		sm.Logger.Warning(`Task with slug %s is archived, skipping migration of %s.`, slug, path)
+		return nil, nil
+	}
+
+	format := sm.Format
+	if format == definitions.DefFormatUnknown {
+		format = definitions.DefFormatYAML
+	}
+	ext := definitions.YamlTaskDefExtensions[0]
+	if format == definitions.DefFormatJSON {
+		ext = definitions.JSONTaskDefExtensions[0]
+	}
+	defnPath := strings.TrimSuffix(path, filepath.Ext(path)) + ext
+	if fsx.Exists(defnPath) {
+		sm.Logger.Warning(`%s already exists, skipping migration of %s.`, defnPath, path)
+		return nil, nil
+	}
+
+	def, err := definitions.NewDefinitionFromTask_0_3(ctx, sm.Client, task)
+	if err != nil {
+		return nil, err
+	}
+	// The definition file is written next to the script.
+	if err := def.SetEntrypoint(filepath.Base(path)); err != nil {
+		return nil, errors.Wrapf(err, "setting entrypoint of %s", slug)
+	}
+	defn, err := def.GenerateCommentedFile(format)
+	if err != nil {
+		return nil, errors.Wrapf(err, "generating task definition of %s", slug)
+	}
+
+	r, err := runtime.Lookup(path, task.Kind)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot determine how to migrate %q - check your CLI is up to date", path)
+	}
+	code, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading script")
+	}
+
+	return &Migration{
+		Slug:       slug,
+		ScriptPath: path,
+		Script:     runtime.Unlink(r, code),
+		DefnPath:   defnPath,
+		Defn:       defn,
+	}, nil
+}
+
+// Apply writes the task definition files of migrations, and removes the
+// comments that link their scripts.
+//
+// Each migration is applied as a whole: if its script can't be rewritten,
+// its definition file is removed again.
+func (sm *ScriptMigrator) Apply(migrations []Migration) error {
+	for _, m := range migrations {
+		if err := applyMigration(m); err != nil {
+			return err
+		}
+		sm.Logger.Debug("Migrated %s to %s", m.Slug, m.DefnPath)
+	}
+	return nil
+}
+
+func applyMigration(m Migration) error {
+	info, err := os.Stat(m.ScriptPath)
+	if err != nil {
+		return errors.Wrap(err, "describing script")
+	}
+
+	// The script is written to a temporary file first, so that it is
+	// replaced in a single rename.
+	tmp, err := ioutil.TempFile(filepath.Dir(m.ScriptPath), "."+filepath.Base(m.ScriptPath)+".*")
+	if err != nil {
+		return errors.Wrapf(err, "unlinking %s", m.ScriptPath)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(m.Script)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	if err != nil {
+		return errors.Wrapf(err, "unlinking %s", m.ScriptPath)
+	}
+
+	if err := ioutil.WriteFile(m.DefnPath, m.Defn, 0644); err != nil {
+		return errors.Wrapf(err, "writing %s", m.DefnPath)
+	}
+	if err := os.Rename(tmp.Name(), m.ScriptPath); err != nil {
+		os.Remove(m.DefnPath)
+		return errors.Wrapf(err, "unlinking %s", m.ScriptPath)
+	}
+	return nil
+}
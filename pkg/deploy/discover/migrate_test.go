@@ -0,0 +1,184 @@
+package discover
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/airplanedev/lib/pkg/api/mock"
+	"github.com/airplanedev/lib/pkg/build"
+	"github.com/airplanedev/lib/pkg/utils/logger"
+	"github.com/airplanedev/lib/pkg/utils/pointers"
+	"github.com/otiai10/copy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScriptMigrator(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	require.NoError(copy.Copy("./fixtures/migrate", dir))
+
+	sm := &ScriptMigrator{
+		Client: &mock.MockClient{
+			Tasks: map[string]api.Task{
+				"my_task": {
+					ID:   "tsk123",
+					Name: "My task",
+					Slug: "my_task",
+					Kind: build.TaskKindNode,
+					KindOptions: build.KindOptions{
+						"entrypoint":  "my_task.ts",
+						"nodeVersion": "18",
+					},
+					Parameters: api.Parameters{
+						{Name: "Name", Slug: "name", Type: api.TypeString},
+					},
+					InterpolationMode: "jst",
+				},
+				"cleanup": {
+					ID:   "tsk456",
+					Name: "Cleanup",
+					Slug: "cleanup",
+					Kind: build.TaskKindShell,
+					KindOptions: build.KindOptions{
+						"entrypoint": "scripts/cleanup.sh",
+					},
+					InterpolationMode: "jst",
+				},
+			},
+		},
+		Logger: &logger.MockLogger{},
+	}
+
+	// Planning doesn't change any files.
+	migrations, err := sm.Plan(context.Background(), dir)
+	require.NoError(err)
+	require.Len(migrations, 2)
+	require.NoFileExists(filepath.Join(dir, "my_task.task.yaml"))
+
+	require.Equal("my_task", migrations[0].Slug)
+	require.Equal(filepath.Join(dir, "my_task.ts"), migrations[0].ScriptPath)
+	require.Equal(filepath.Join(dir, "my_task.task.yaml"), migrations[0].DefnPath)
+	require.Equal(`name: My task
+slug: my_task
+parameters:
+- name: Name
+  slug: name
+  type: shorttext
+node:
+  entrypoint: my_task.ts
+  nodeVersion: "18"
+`, string(migrations[0].Defn))
+
+	require.Equal("cleanup", migrations[1].Slug)
+	require.Equal(filepath.Join(dir, "scripts/cleanup.task.yaml"), migrations[1].DefnPath)
+	require.Contains(string(migrations[1].Defn), "shell:\n  # The path to the .sh file containing the logic for this task.")
+	require.Contains(string(migrations[1].Defn), "  entrypoint: cleanup.sh\n")
+
+	require.NoError(sm.Apply(migrations))
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "my_task.task.yaml"))
+	require.NoError(err)
+	require.Equal(migrations[0].Defn, buf)
+	buf, err = ioutil.ReadFile(filepath.Join(dir, "my_task.ts"))
+	require.NoError(err)
+	require.Equal(`export default async function(params: { name: string }) {
+  console.log(params.name);
+}
+`, string(buf))
+
+	// Scripts keep their permissions.
+	info, err := os.Stat(filepath.Join(dir, "scripts/cleanup.sh"))
+	require.NoError(err)
+	require.Equal(os.FileMode(0755), info.Mode().Perm())
+	buf, err = ioutil.ReadFile(filepath.Join(dir, "scripts/cleanup.sh"))
+	require.NoError(err)
+	require.Equal("#!/bin/bash\necho \"cleaning up\"\n", string(buf))
+
+	// Migrated scripts are no longer linked.
+	migrations, err = sm.Plan(context.Background(), dir)
+	require.NoError(err)
+	require.Empty(migrations)
+}
+
+func TestScriptMigratorApplyFailure(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "my_task.ts")
+	require.NoError(ioutil.WriteFile(scriptPath, []byte("// Linked to https://app.airplane.dev/t/my_task [do not edit this line]\n"), 0644))
+
+	sm := &ScriptMigrator{Logger: &logger.MockLogger{}}
+	err := sm.Apply([]Migration{{
+		Slug:       "my_task",
+		ScriptPath: scriptPath,
+		Script:     []byte("unlinked"),
+		DefnPath:   filepath.Join(dir, "missing", "my_task.task.yaml"),
+		Defn:       []byte("slug: my_task\n"),
+	}})
+	require.Error(err)
+
+	// The script is left as it was, without temporary files next to it.
+	buf, err := ioutil.ReadFile(scriptPath)
+	require.NoError(err)
+	require.Contains(string(buf), "Linked to")
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(err)
+	require.Len(entries, 1)
+}
+
+func TestScriptMigratorKeepsTaskSettings(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	require.NoError(copy.Copy("./fixtures/migrate", dir))
+
+	sm := &ScriptMigrator{
+		Client: &mock.MockClient{
+			Tasks: map[string]api.Task{
+				"cleanup": {
+					ID:   "tsk456",
+					Name: "Cleanup",
+					Slug: "cleanup",
+					Kind: build.TaskKindShell,
+					KindOptions: build.KindOptions{
+						"entrypoint": "scripts/cleanup.sh",
+					},
+					InterpolationMode:          "jst",
+					ResourceRequests:           api.ResourceRequests{"cpu": "500m", "memory": "1Gi"},
+					RequireExplicitPermissions: true,
+					Permissions: api.Permissions{
+						{RoleID: api.RoleTaskAdmin, SubUserID: pointers.String("usr1")},
+					},
+					Triggers: []api.Trigger{
+						{
+							Slug: pointers.String("nightly"),
+							Kind: api.TriggerKindSchedule,
+							KindConfig: api.TriggerKindConfig{
+								Schedule: &api.TriggerKindConfigSchedule{
+									CronExpr: api.CronExpr{Minute: "0", Hour: "2", DayOfMonth: "*", Month: "*", DayOfWeek: "*"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Logger: &logger.MockLogger{},
+	}
+
+	migrations, err := sm.Plan(context.Background(), dir)
+	require.NoError(err)
+	require.Len(migrations, 1)
+	require.NoError(sm.Apply(migrations))
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "scripts/cleanup.task.yaml"))
+	require.NoError(err)
+	require.Contains(string(buf), "compute:\n  requests:\n    cpu: 500m\n    memory: 1Gi\n")
+	require.Contains(string(buf), "permissions:\n  roles:\n    task_admin:\n      users:\n      - usr1\n")
+	require.Contains(string(buf), "schedules:\n  nightly:\n    cron: 0 2 * * *\n")
+}
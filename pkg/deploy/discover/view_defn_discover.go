@@ -38,11 +38,7 @@ func (dd *ViewDefnDiscoverer) GetViewConfig(ctx context.Context, file string) (*
 	if err = d.Unmarshal(format, buf); err != nil {
 		switch err := errors.Cause(err).(type) {
 		case definitions.ErrSchemaValidation:
-			errorMsgs := []string{}
-			for _, verr := range err.Errors {
-				errorMsgs = append(errorMsgs, fmt.Sprintf("%s: %s", verr.Field(), verr.Description()))
-			}
-			return nil, definitions.NewErrReadDefinition(fmt.Sprintf("Error reading %s", file), errorMsgs...)
+			return nil, definitions.NewErrReadDefinitionWithPositions(fmt.Sprintf("Error reading %s", file), file, buf, err.FieldErrors(format, buf)...)
 		default:
 			return nil, errors.Wrap(err, "unmarshalling view definition")
 		}
@@ -0,0 +1,108 @@
+package definitions
+
+import (
+	"encoding/json"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/pkg/errors"
+)
+
+// ComputeDefinition_0_3 configures the CPU and memory of runs. If a task
+// definition has no compute section, they are managed in the Airplane UI
+// instead.
+type ComputeDefinition_0_3 struct {
+	// Requests are reserved for each run.
+	Requests *ComputeResourcesDefinition_0_3 `json:"requests,omitempty"`
+	// Limits are the most that a run can use.
+	Limits *ComputeResourcesDefinition_0_3 `json:"limits,omitempty"`
+}
+
+// ComputeResourcesDefinition_0_3 are amounts of compute resources, as
+// Kubernetes-style quantities, e.g. `500m` CPUs or `2Gi` of memory.
+type ComputeResourcesDefinition_0_3 struct {
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+var _ json.Unmarshaler = &ComputeResourcesDefinition_0_3{}
+
+func (c *ComputeResourcesDefinition_0_3) UnmarshalJSON(b []byte) error {
+	// Quantities can be written as numbers, e.g. `cpu: 2`.
+	var raw struct {
+		CPU    json.RawMessage `json:"cpu"`
+		Memory json.RawMessage `json:"memory"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	for _, field := range []struct {
+		raw json.RawMessage
+		s   *string
+	}{
+		{raw.CPU, &c.CPU},
+		{raw.Memory, &c.Memory},
+	} {
+		if len(field.raw) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(field.raw, field.s); err == nil {
+			continue
+		}
+		var n json.Number
+		if err := json.Unmarshal(field.raw, &n); err != nil {
+			return errors.New("expected a string or number")
+		}
+		*field.s = n.String()
+	}
+	return nil
+}
+
+const (
+	computeCPU    = "cpu"
+	computeMemory = "memory"
+)
+
+func (c *ComputeResourcesDefinition_0_3) toAPI() map[string]string {
+	m := map[string]string{}
+	if c == nil {
+		return m
+	}
+	if c.CPU != "" {
+		m[computeCPU] = c.CPU
+	}
+	if c.Memory != "" {
+		m[computeMemory] = c.Memory
+	}
+	return m
+}
+
+func newComputeResourcesFromAPI(m map[string]string) *ComputeResourcesDefinition_0_3 {
+	if m[computeCPU] == "" && m[computeMemory] == "" {
+		return nil
+	}
+	return &ComputeResourcesDefinition_0_3{
+		CPU:    m[computeCPU],
+		Memory: m[computeMemory],
+	}
+}
+
+// addComputeToUpdateTaskRequest sets the resource requests and limits of
+// req. Requests leave them unchanged if the definition has no compute
+// section.
+func (d *Definition_0_3) addComputeToUpdateTaskRequest(req *api.UpdateTaskRequest) {
+	if d.Compute == nil {
+		return
+	}
+	req.ResourceRequests = d.Compute.Requests.toAPI()
+	req.ResourceLimits = d.Compute.Limits.toAPI()
+}
+
+func (d *Definition_0_3) convertComputeFromTask(t *api.Task) {
+	compute := ComputeDefinition_0_3{
+		Requests: newComputeResourcesFromAPI(t.ResourceRequests),
+		Limits:   newComputeResourcesFromAPI(t.ResourceLimits),
+	}
+	if compute.Requests != nil || compute.Limits != nil {
+		d.Compute = &compute
+	}
+}
@@ -0,0 +1,30 @@
+package definitions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeUnmarshal(t *testing.T) {
+	require := require.New(t)
+
+	d := Definition_0_3{}
+	err := d.Unmarshal(DefFormatYAML, []byte(`name: Hello World
+slug: hello_world
+python:
+  entrypoint: hello_world.py
+compute:
+  requests:
+    cpu: 0.5
+    memory: 512Mi
+  limits:
+    cpu: 2
+    memory: "1e9"
+`))
+	require.NoError(err)
+	require.Equal(&ComputeDefinition_0_3{
+		Requests: &ComputeResourcesDefinition_0_3{CPU: "0.5", Memory: "512Mi"},
+		Limits:   &ComputeResourcesDefinition_0_3{CPU: "2", Memory: "1e9"},
+	}, d.Compute)
+}
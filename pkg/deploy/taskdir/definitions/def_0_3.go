@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -26,6 +27,7 @@ type Definition_0_3 struct {
 	Description string                    `json:"description,omitempty"`
 	Parameters  []ParameterDefinition_0_3 `json:"parameters,omitempty"`
 	Resources   ResourceDefinition_0_3    `json:"resources,omitempty"`
+	Compute     *ComputeDefinition_0_3    `json:"compute,omitempty"`
 
 	Image  *ImageDefinition_0_3  `json:"docker,omitempty"`
 	Node   *NodeDefinition_0_3   `json:"node,omitempty"`
@@ -41,7 +43,13 @@ type Definition_0_3 struct {
 	Timeout            DefaultTimeoutDefinition `json:"timeout,omitempty"`
 	Runtime            build.TaskRuntime        `json:"runtime,omitempty"`
 
+	Permissions *PermissionsDefinition_0_3 `json:"permissions,omitempty"`
+
 	Schedules map[string]ScheduleDefinition_0_3 `json:"schedules,omitempty"`
+	Triggers  map[string]TriggerDefinition_0_3  `json:"triggers,omitempty"`
+
+	// Environments overrides fields of the definition per environment slug.
+	Environments map[string]EnvironmentDefinition_0_3 `json:"environments,omitempty"`
 
 	buildConfig  build.BuildConfig
 	defnFilePath string
@@ -479,10 +487,18 @@ type RESTDefinition_0_3 struct {
 	Path      string                 `json:"path"`
 	URLParams map[string]interface{} `json:"urlParams,omitempty"`
 	Headers   map[string]interface{} `json:"headers,omitempty"`
-	BodyType  string                 `json:"bodyType"`
+	BodyType  RESTBodyType           `json:"bodyType"`
 	Body      interface{}            `json:"body,omitempty"`
-	FormData  map[string]interface{} `json:"formData,omitempty"`
-	Configs   []string               `json:"configs,omitempty"`
+	// BodyFile is the path to a file containing the body, relative to the
+	// definition. It can be set instead of Body.
+	BodyFile string                 `json:"bodyFile,omitempty"`
+	FormData map[string]interface{} `json:"formData,omitempty"`
+	Configs  []string               `json:"configs,omitempty"`
+
+	// Contents of BodyFile, cached
+	bodyFileContents string `json:"-"`
+	bodyFileLoaded   bool   `json:"-"`
+	absoluteBodyFile string `json:"-"`
 }
 
 func (d *RESTDefinition_0_3) fillInUpdateTaskRequest(ctx context.Context, client api.IAPIClient, req *api.UpdateTaskRequest) error {
@@ -538,7 +554,7 @@ func (d *RESTDefinition_0_3) hydrateFromTask(ctx context.Context, client api.IAP
 	}
 	if v, ok := t.KindOptions["bodyType"]; ok {
 		if sv, ok := v.(string); ok {
-			d.BodyType = sv
+			d.BodyType = RESTBodyType(sv)
 		} else {
 			return errors.Errorf("expected string bodyType, got %T instead", v)
 		}
@@ -584,13 +600,17 @@ func (d *RESTDefinition_0_3) getKindOptions() (build.KindOptions, error) {
 	if d.FormData == nil {
 		d.FormData = map[string]interface{}{}
 	}
+	body, err := d.GetBody()
+	if err != nil {
+		return nil, err
+	}
 	return build.KindOptions{
 		"method":    d.Method,
 		"path":      d.Path,
 		"urlParams": d.URLParams,
 		"headers":   d.Headers,
-		"bodyType":  d.BodyType,
-		"body":      d.Body,
+		"bodyType":  string(d.BodyType),
+		"body":      body,
 		"formData":  d.FormData,
 	}, nil
 }
@@ -621,6 +641,25 @@ type ParameterDefinition_0_3 struct {
 	Required    DefaultTrueDefinition  `json:"required,omitempty"`
 	Options     []OptionDefinition_0_3 `json:"options,omitempty"`
 	Regex       string                 `json:"regex,omitempty"`
+
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	MinDate   string   `json:"minDate,omitempty"`
+	MaxDate   string   `json:"maxDate,omitempty"`
+
+	VisibleIf  *ParamConditionDefinition_0_3 `json:"visibleIf,omitempty"`
+	RequiredIf *ParamConditionDefinition_0_3 `json:"requiredIf,omitempty"`
+}
+
+// ParamConditionDefinition_0_3 is a condition on the value of another
+// parameter, used by visibleIf and requiredIf.
+type ParamConditionDefinition_0_3 struct {
+	Param string `json:"param"`
+	// Equals is the value the parameter must have. If it is omitted, the
+	// condition holds if the parameter has any value.
+	Equals interface{} `json:"equals,omitempty"`
 }
 
 type OptionDefinition_0_3 struct {
@@ -653,12 +692,32 @@ func (o *OptionDefinition_0_3) UnmarshalJSON(b []byte) error {
 }
 
 type ScheduleDefinition_0_3 struct {
-	Name        string                 `json:"name,omitempty"`
-	Description string                 `json:"description,omitempty"`
-	CronExpr    string                 `json:"cron"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	CronExpr    string `json:"cron"`
+	// Timezone is the IANA time zone that CronExpr is evaluated in. An empty
+	// timezone is UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// StartDate and EndDate bound when the schedule runs. They are either
+	// dates, e.g. 2022-01-31, in the time zone of the schedule, or RFC 3339
+	// datetimes.
+	StartDate string `json:"startDate,omitempty"`
+	EndDate   string `json:"endDate,omitempty"`
+	// Paused schedules are deployed, but do not run.
+	Paused      bool                   `json:"paused,omitempty"`
 	ParamValues map[string]interface{} `json:"paramValues,omitempty"`
 }
 
+// EnvironmentDefinition_0_3 overrides fields of a task definition in a single
+// environment. Each field is merged key by key over the top-level value, see
+// Definition_0_3.ForEnvironment.
+type EnvironmentDefinition_0_3 struct {
+	EnvVars     api.TaskEnv                       `json:"envVars,omitempty"`
+	Resources   ResourceDefinition_0_3            `json:"resources,omitempty"`
+	Constraints map[string]string                 `json:"constraints,omitempty"`
+	Schedules   map[string]ScheduleDefinition_0_3 `json:"schedules,omitempty"`
+}
+
 //go:embed schema_0_3.json
 var schemaStr string
 
@@ -695,7 +754,7 @@ func NewDefinition_0_3(name string, slug string, kind build.TaskKind, entrypoint
 		def.REST = &RESTDefinition_0_3{
 			Method:   "POST",
 			Path:     "/",
-			BodyType: "json",
+			BodyType: RESTBodyTypeJSON,
 			Body:     "{}",
 		}
 	default:
@@ -748,6 +807,11 @@ func (d Definition_0_3) GenerateCommentedFile(format DefFormat) ([]byte, error)
 		len(d.Parameters) > 0 ||
 		len(d.Resources.Attachments) > 0 ||
 		len(d.Constraints) > 0 ||
+		len(d.Environments) > 0 ||
+		d.Permissions != nil ||
+		d.Compute != nil ||
+		len(d.Schedules) > 0 ||
+		len(d.Triggers) > 0 ||
 		d.RequireRequests ||
 		!d.AllowSelfApprovals.IsZero() ||
 		!d.Timeout.IsZero() {
@@ -947,7 +1011,102 @@ func (d Definition_0_3) taskKind() (taskKind_0_3, error) {
 	}
 }
 
-func (d Definition_0_3) GetUpdateTaskRequest(ctx context.Context, client api.IAPIClient) (api.UpdateTaskRequest, error) {
+// ForEnvironment returns a copy of the definition with the overrides for
+// envSlug applied. Env vars, resource attachments, constraints and schedules
+// are merged over the top-level values, with overrides taking precedence.
+// If the definition has no overrides for envSlug, it is returned as is.
+func (d Definition_0_3) ForEnvironment(envSlug string) (Definition_0_3, error) {
+	env, ok := d.Environments[envSlug]
+	if !ok {
+		return d, nil
+	}
+	d.Environments = nil
+
+	if len(env.EnvVars) > 0 {
+		taskKind, err := d.taskKind()
+		if err != nil {
+			return Definition_0_3{}, err
+		}
+		// Copy the kind so that the original definition is not modified.
+		switch k := taskKind.(type) {
+		case *ImageDefinition_0_3:
+			c := *k
+			c.EnvVars = mergeTaskEnv(k.EnvVars, env.EnvVars)
+			d.Image = &c
+		case *NodeDefinition_0_3:
+			c := *k
+			c.EnvVars = mergeTaskEnv(k.EnvVars, env.EnvVars)
+			d.Node = &c
+		case *PythonDefinition_0_3:
+			c := *k
+			c.EnvVars = mergeTaskEnv(k.EnvVars, env.EnvVars)
+			d.Python = &c
+		case *ShellDefinition_0_3:
+			c := *k
+			c.EnvVars = mergeTaskEnv(k.EnvVars, env.EnvVars)
+			d.Shell = &c
+		default:
+			kind, _ := d.Kind()
+			return Definition_0_3{}, errors.Errorf("env vars are not supported by %s tasks", kind)
+		}
+	}
+
+	if len(env.Resources.Attachments) > 0 {
+		attachments := make(map[string]string, len(d.Resources.Attachments)+len(env.Resources.Attachments))
+		for alias, slug := range d.Resources.Attachments {
+			attachments[alias] = slug
+		}
+		for alias, slug := range env.Resources.Attachments {
+			attachments[alias] = slug
+		}
+		d.Resources = ResourceDefinition_0_3{Attachments: attachments}
+	}
+
+	if len(env.Constraints) > 0 {
+		constraints := make(map[string]string, len(d.Constraints)+len(env.Constraints))
+		for key, val := range d.Constraints {
+			constraints[key] = val
+		}
+		for key, val := range env.Constraints {
+			constraints[key] = val
+		}
+		d.Constraints = constraints
+	}
+
+	if len(env.Schedules) > 0 {
+		schedules := make(map[string]ScheduleDefinition_0_3, len(d.Schedules)+len(env.Schedules))
+		for slug, sched := range d.Schedules {
+			schedules[slug] = sched
+		}
+		for slug, sched := range env.Schedules {
+			schedules[slug] = sched
+		}
+		d.Schedules = schedules
+	}
+
+	return d, nil
+}
+
+func mergeTaskEnv(base, overrides api.TaskEnv) api.TaskEnv {
+	env := make(api.TaskEnv, len(base)+len(overrides))
+	for k, v := range base {
+		env[k] = v
+	}
+	for k, v := range overrides {
+		env[k] = v
+	}
+	return env
+}
+
+// GetUpdateTaskRequest returns the request that deploys this definition to
+// the environment envSlug, with the overrides for that environment applied.
+// An empty envSlug targets the default environment.
+func (d Definition_0_3) GetUpdateTaskRequest(ctx context.Context, client api.IAPIClient, envSlug string) (api.UpdateTaskRequest, error) {
+	d, err := d.ForEnvironment(envSlug)
+	if err != nil {
+		return api.UpdateTaskRequest{}, err
+	}
+
 	req := api.UpdateTaskRequest{
 		Slug:        d.Slug,
 		Name:        d.Name,
@@ -958,6 +1117,7 @@ func (d Definition_0_3) GetUpdateTaskRequest(ctx context.Context, client api.IAP
 			RequireRequests: &d.RequireRequests,
 		},
 		Resources: make(map[string]string),
+		EnvSlug:   envSlug,
 	}
 
 	if err := d.addParametersToUpdateTaskRequest(ctx, &req); err != nil {
@@ -976,12 +1136,17 @@ func (d Definition_0_3) GetUpdateTaskRequest(ctx context.Context, client api.IAP
 				Value: val,
 			})
 		}
+		sort.Slice(labels, func(i, j int) bool {
+			return labels[i].Key < labels[j].Key
+		})
 		req.Constraints = api.RunConstraints{
 			Labels: labels,
 		}
 	}
 
 	req.ExecuteRules.DisallowSelfApprove = pointers.Bool(!d.AllowSelfApprovals.Value())
+	d.addPermissionsToUpdateTaskRequest(&req)
+	d.addComputeToUpdateTaskRequest(&req)
 
 	if err := d.addKindSpecificsToUpdateTaskRequest(ctx, client, &req); err != nil {
 		return api.UpdateTaskRequest{}, err
@@ -990,6 +1155,17 @@ func (d Definition_0_3) GetUpdateTaskRequest(ctx context.Context, client api.IAP
 	return req, nil
 }
 
+// ParametersToAPI converts the parameters of a definition to the format used
+// by the API.
+func ParametersToAPI(parameters []ParameterDefinition_0_3) (api.Parameters, error) {
+	var req api.UpdateTaskRequest
+	d := Definition_0_3{Parameters: parameters}
+	if err := d.addParametersToUpdateTaskRequest(context.Background(), &req); err != nil {
+		return nil, err
+	}
+	return req.Parameters, nil
+}
+
 func (d Definition_0_3) addParametersToUpdateTaskRequest(ctx context.Context, req *api.UpdateTaskRequest) error {
 	req.Parameters = make([]api.Parameter, len(d.Parameters))
 	for i, pd := range d.Parameters {
@@ -999,23 +1175,16 @@ func (d Definition_0_3) addParametersToUpdateTaskRequest(ctx context.Context, re
 			Desc: pd.Description,
 		}
 
-		switch pd.Type {
-		case "shorttext":
-			param.Type = "string"
-		case "longtext":
-			param.Type = "string"
-			param.Component = api.ComponentTextarea
-		case "sql":
-			param.Type = "string"
-			param.Component = api.ComponentEditorSQL
-		case "boolean", "upload", "integer", "float", "date", "datetime", "configvar":
-			param.Type = api.Type(pd.Type)
-		default:
-			return errors.Errorf("unknown parameter type: %s", pd.Type)
+		var err error
+		if param.Type, param.Component, err = paramTypeToAPI(pd.Type); err != nil {
+			return err
 		}
 
 		if pd.Default != nil {
-			if pd.Type == "configvar" {
+			if isStructuredParamType(pd.Type) {
+				// JSON values are passed through as is.
+				param.Default = pd.Default
+			} else if pd.Type == "configvar" {
 				switch reflect.ValueOf(pd.Default).Kind() {
 				case reflect.Map:
 					m, ok := pd.Default.(map[string]interface{})
@@ -1050,11 +1219,25 @@ func (d Definition_0_3) addParametersToUpdateTaskRequest(ctx context.Context, re
 			}
 		}
 
-		if !pd.Required.Value() {
+		// Parameters with a requiredIf condition are optional unless the
+		// condition holds.
+		if !pd.Required.Value() || pd.RequiredIf != nil {
 			param.Constraints.Optional = true
 		}
 
 		param.Constraints.Regex = pd.Regex
+		param.Constraints.Min = pd.Min
+		param.Constraints.Max = pd.Max
+		param.Constraints.MinLength = pd.MinLength
+		param.Constraints.MaxLength = pd.MaxLength
+		param.Constraints.MinDate = pd.MinDate
+		param.Constraints.MaxDate = pd.MaxDate
+		if pd.VisibleIf != nil {
+			param.Constraints.VisibleIf = &api.ParamCondition{Param: pd.VisibleIf.Param, Equals: pd.VisibleIf.Equals}
+		}
+		if pd.RequiredIf != nil {
+			param.Constraints.RequiredIf = &api.ParamCondition{Param: pd.RequiredIf.Param, Equals: pd.RequiredIf.Equals}
+		}
 
 		if len(pd.Options) > 0 {
 			param.Constraints.Options = make([]api.ConstraintOption, len(pd.Options))
@@ -1081,6 +1264,35 @@ func (d Definition_0_3) addParametersToUpdateTaskRequest(ctx context.Context, re
 	return nil
 }
 
+// paramTypeToAPI returns the API type and component of parameters of the
+// definition type typ.
+func paramTypeToAPI(typ string) (api.Type, api.Component, error) {
+	switch typ {
+	case "shorttext":
+		return api.TypeString, api.ComponentNone, nil
+	case "longtext":
+		return api.TypeString, api.ComponentTextarea, nil
+	case "sql":
+		return api.TypeString, api.ComponentEditorSQL, nil
+	case "boolean", "upload", "integer", "float", "date", "datetime", "configvar", "json", "list", "object":
+		return api.Type(typ), api.ComponentNone, nil
+	case "multiselect":
+		return api.TypeList, api.ComponentMultiSelect, nil
+	default:
+		return "", api.ComponentNone, errors.Errorf("unknown parameter type: %s", typ)
+	}
+}
+
+// isStructuredParamType returns true if values of the parameter type typ are
+// JSON values rather than scalars.
+func isStructuredParamType(typ string) bool {
+	switch typ {
+	case "json", "list", "multiselect", "object":
+		return true
+	}
+	return false
+}
+
 func (d Definition_0_3) addResourcesToUpdateTaskRequest(ctx context.Context, client api.IAPIClient, req *api.UpdateTaskRequest) error {
 	if len(d.Resources.Attachments) == 0 {
 		return nil
@@ -1147,8 +1359,14 @@ func (d Definition_0_3) GetDefnFilePath() string {
 
 func (d *Definition_0_3) SetDefnFilePath(filePath string) {
 	d.defnFilePath = filePath
+	if d.REST != nil {
+		d.REST.setDefnFilePath(filePath)
+	}
 }
 
+// UpgradeJST is a no-op: 0.3 definitions always use JavaScript templates.
+// Upgrades between versions of the definition format are handled by
+// UpgradeDefinition.
 func (d *Definition_0_3) UpgradeJST() error {
 	return nil
 }
@@ -1224,21 +1442,32 @@ func (d *Definition_0_3) SetWorkdir(taskroot, workdir string) error {
 	return nil
 }
 
-func (d *Definition_0_3) GetSchedules() map[string]api.Schedule {
-	if len(d.Schedules) == 0 {
-		return nil
+func (d *Definition_0_3) GetSchedules(envSlug string) (map[string]api.Schedule, error) {
+	def, err := d.ForEnvironment(envSlug)
+	if err != nil {
+		return nil, err
+	}
+	if len(def.Schedules) == 0 {
+		return nil, nil
 	}
 
 	schedules := make(map[string]api.Schedule)
-	for slug, def := range d.Schedules {
-		schedules[slug] = api.Schedule{
-			Name:        def.Name,
-			Description: def.Description,
-			CronExpr:    def.CronExpr,
-			ParamValues: def.ParamValues,
-		}
+	for slug, sched := range def.Schedules {
+		schedules[slug] = sched.apiSchedule()
+	}
+	return schedules, nil
+}
+
+func (d *Definition_0_3) GetTriggers() map[string]api.TaskTrigger {
+	if len(d.Triggers) == 0 {
+		return nil
+	}
+
+	triggers := make(map[string]api.TaskTrigger)
+	for slug, def := range d.Triggers {
+		triggers[slug] = def.apiTrigger()
 	}
-	return schedules
+	return triggers
 }
 
 func NewDefinitionFromTask_0_3(ctx context.Context, client api.IAPIClient, t api.Task) (Definition_0_3, error) {
@@ -1271,28 +1500,33 @@ func NewDefinitionFromTask_0_3(ctx context.Context, client api.IAPIClient, t api
 
 	d.AllowSelfApprovals.value = pointers.Bool(!t.ExecuteRules.DisallowSelfApprove)
 	d.Timeout.value = t.Timeout
+	d.convertPermissionsFromTask(&t)
+	d.convertComputeFromTask(&t)
 
 	schedules := make(map[string]ScheduleDefinition_0_3)
+	triggers := make(map[string]TriggerDefinition_0_3)
 	for _, trigger := range t.Triggers {
-		if trigger.Kind != api.TriggerKindSchedule || trigger.Slug == nil {
-			// Trigger is not a schedule deployed via code
+		if trigger.Slug == nil {
+			// Trigger is not deployed via code
 			continue
 		}
-		if trigger.ArchivedAt != nil || trigger.DisabledAt != nil {
-			// Trigger is archived or disabled, so don't add to task defn file
+		if trigger.ArchivedAt != nil {
+			// Trigger is archived, so don't add to task defn file
 			continue
 		}
 
-		schedules[*trigger.Slug] = ScheduleDefinition_0_3{
-			Name:        trigger.Name,
-			Description: trigger.Description,
-			CronExpr:    trigger.KindConfig.Schedule.CronExpr.String(),
-			ParamValues: trigger.KindConfig.Schedule.ParamValues,
+		if trigger.Kind == api.TriggerKindSchedule {
+			schedules[*trigger.Slug] = NewScheduleDefinitionFromTrigger_0_3(trigger)
+		} else if def, ok := NewTriggerDefinitionFromTrigger_0_3(trigger); ok {
+			triggers[*trigger.Slug] = def
 		}
 	}
 	if len(schedules) > 0 {
 		d.Schedules = schedules
 	}
+	if len(triggers) > 0 {
+		d.Triggers = triggers
+	}
 
 	return d, nil
 }
@@ -1321,14 +1555,25 @@ func (d *Definition_0_3) convertParametersFromTask(ctx context.Context, client a
 			default:
 				return errors.Errorf("unexpected component for type=string: %s", param.Component)
 			}
-		case "boolean", "upload", "integer", "float", "date", "datetime", "configvar":
+		case "list":
+			switch param.Component {
+			case api.ComponentMultiSelect:
+				p.Type = "multiselect"
+			case api.ComponentNone:
+				p.Type = "list"
+			default:
+				return errors.Errorf("unexpected component for type=list: %s", param.Component)
+			}
+		case "boolean", "upload", "integer", "float", "date", "datetime", "configvar", "json", "object":
 			p.Type = string(param.Type)
 		default:
 			return errors.Errorf("unknown parameter type: %s", param.Type)
 		}
 
 		if param.Default != nil {
-			if param.Type == "configvar" {
+			if isStructuredParamType(p.Type) {
+				p.Default = param.Default
+			} else if param.Type == "configvar" {
 				switch k := reflect.ValueOf(param.Default).Kind(); k {
 				case reflect.Map:
 					configName, err := extractConfigVarValue(param.Default)
@@ -1351,9 +1596,28 @@ func (d *Definition_0_3) convertParametersFromTask(ctx context.Context, client a
 			}
 		}
 
-		p.Required.value = pointers.Bool(!param.Constraints.Optional)
+		if param.Constraints.RequiredIf != nil {
+			p.RequiredIf = &ParamConditionDefinition_0_3{
+				Param:  param.Constraints.RequiredIf.Param,
+				Equals: param.Constraints.RequiredIf.Equals,
+			}
+		} else {
+			p.Required.value = pointers.Bool(!param.Constraints.Optional)
+		}
+		if param.Constraints.VisibleIf != nil {
+			p.VisibleIf = &ParamConditionDefinition_0_3{
+				Param:  param.Constraints.VisibleIf.Param,
+				Equals: param.Constraints.VisibleIf.Equals,
+			}
+		}
 
 		p.Regex = param.Constraints.Regex
+		p.Min = param.Constraints.Min
+		p.Max = param.Constraints.Max
+		p.MinLength = param.Constraints.MinLength
+		p.MaxLength = param.Constraints.MaxLength
+		p.MinDate = param.Constraints.MinDate
+		p.MaxDate = param.Constraints.MaxDate
 
 		if len(param.Constraints.Options) > 0 {
 			p.Options = make([]OptionDefinition_0_3, len(param.Constraints.Options))
@@ -9,6 +9,7 @@ import (
 	"github.com/airplanedev/lib/pkg/api/mock"
 	"github.com/airplanedev/lib/pkg/build"
 	"github.com/airplanedev/lib/pkg/utils/pointers"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
@@ -752,6 +753,75 @@ func TestTaskToDefinition_0_3(t *testing.T) {
 				AllowSelfApprovals: DefaultTrueDefinition{pointers.Bool(false)},
 			},
 		},
+		{
+			name: "check permissions",
+			task: api.Task{
+				Name:      "Test Task",
+				Slug:      "test_task",
+				Arguments: []string{"{{JSON.stringify(params)}}"},
+				Kind:      build.TaskKindPython,
+				KindOptions: build.KindOptions{
+					"entrypoint": "main.py",
+				},
+				RequireExplicitPermissions: true,
+				Permissions: api.Permissions{
+					{RoleID: api.RoleTaskViewer, SubGroupID: pointers.String("grp1")},
+					{RoleID: api.RoleTaskAdmin, SubUserID: pointers.String("usr1")},
+					{RoleID: api.RoleTaskViewer, SubUserID: pointers.String("usr2")},
+					{Action: "tasks.get", SubUserID: pointers.String("usr3")},
+				},
+				ExecuteRules: api.ExecuteRules{
+					RequireRequests:   true,
+					RequiredApprovals: 2,
+					ApproverGroupIDs:  []string{"grp2"},
+				},
+			},
+			definition: Definition_0_3{
+				Name: "Test Task",
+				Slug: "test_task",
+				Python: &PythonDefinition_0_3{
+					Entrypoint: "main.py",
+				},
+				RequireRequests:    true,
+				AllowSelfApprovals: DefaultTrueDefinition{pointers.Bool(true)},
+				Permissions: &PermissionsDefinition_0_3{
+					Roles: map[api.RoleID]PrincipalsDefinition_0_3{
+						api.RoleTaskViewer: {Users: []string{"usr2"}, Groups: []string{"grp1"}},
+						api.RoleTaskAdmin:  {Users: []string{"usr1"}},
+					},
+					Approvals: &ApprovalsDefinition_0_3{
+						Required: 2,
+						Groups:   []string{"grp2"},
+					},
+				},
+			},
+		},
+		{
+			name: "check compute",
+			task: api.Task{
+				Name:      "Test Task",
+				Slug:      "test_task",
+				Arguments: []string{"{{JSON.stringify(params)}}"},
+				Kind:      build.TaskKindPython,
+				KindOptions: build.KindOptions{
+					"entrypoint": "main.py",
+				},
+				ResourceRequests: api.ResourceRequests{"cpu": "500m", "memory": "1Gi"},
+				ResourceLimits:   api.ResourceLimits{"memory": "4Gi"},
+			},
+			definition: Definition_0_3{
+				Name: "Test Task",
+				Slug: "test_task",
+				Python: &PythonDefinition_0_3{
+					Entrypoint: "main.py",
+				},
+				Compute: &ComputeDefinition_0_3{
+					Requests: &ComputeResourcesDefinition_0_3{CPU: "500m", Memory: "1Gi"},
+					Limits:   &ComputeResourcesDefinition_0_3{Memory: "4Gi"},
+				},
+				AllowSelfApprovals: DefaultTrueDefinition{pointers.Bool(true)},
+			},
+		},
 		{
 			name: "check default execute rules",
 			task: api.Task{
@@ -915,6 +985,30 @@ func TestTaskToDefinition_0_3(t *testing.T) {
 							},
 						},
 					},
+					{
+						Name: "on push",
+						Slug: pointers.String("on_push"),
+						Kind: api.TriggerKindWebhook,
+						KindConfig: api.TriggerKindConfig{
+							Webhook: &api.TriggerKindConfigWebhook{
+								ParamMappings: map[string]string{
+									"example_param": "$.ref",
+								},
+							},
+						},
+					},
+					{
+						Name: "on pull request",
+						Slug: pointers.String("on_pull_request"),
+						Kind: api.TriggerKindEvent,
+						KindConfig: api.TriggerKindConfig{
+							Event: &api.TriggerKindConfigEvent{
+								Source:    "github",
+								EventType: "pull_request.opened",
+							},
+						},
+						DisabledAt: &exampleTime,
+					},
 				},
 			},
 			definition: Definition_0_3{
@@ -933,6 +1027,12 @@ func TestTaskToDefinition_0_3(t *testing.T) {
 					},
 				},
 				Schedules: map[string]ScheduleDefinition_0_3{
+					"disabled_trigger": {
+						Name:        "disabled trigger",
+						Description: "disabled trigger",
+						CronExpr:    exampleCron.String(),
+						Paused:      true,
+					},
 					"good_schedule": {
 						Name:        "good schedule",
 						Description: "good schedule",
@@ -942,6 +1042,24 @@ func TestTaskToDefinition_0_3(t *testing.T) {
 						},
 					},
 				},
+				Triggers: map[string]TriggerDefinition_0_3{
+					"on_push": {
+						Name: "on push",
+						Webhook: &WebhookTriggerDefinition_0_3{
+							ParamMappings: map[string]string{
+								"example_param": "$.ref",
+							},
+						},
+					},
+					"on_pull_request": {
+						Name:   "on pull request",
+						Paused: true,
+						Event: &EventTriggerDefinition_0_3{
+							Source: "github",
+							Type:   "pull_request.opened",
+						},
+					},
+				},
 				AllowSelfApprovals: DefaultTrueDefinition{pointers.Bool(true)},
 			},
 		},
@@ -1016,6 +1134,7 @@ func TestDefinitionToUpdateTaskRequest_0_3(t *testing.T) {
 	for _, test := range []struct {
 		name       string
 		definition Definition_0_3
+		envSlug    string
 		request    api.UpdateTaskRequest
 		resources  []api.Resource
 	}{
@@ -1203,6 +1322,144 @@ func TestDefinitionToUpdateTaskRequest_0_3(t *testing.T) {
 				Timeout: 3600,
 			},
 		},
+		{
+			name: "test update permissions",
+			definition: Definition_0_3{
+				Name: "Test Task",
+				Slug: "test_task",
+				Python: &PythonDefinition_0_3{
+					Entrypoint: "main.py",
+				},
+				RequireRequests: true,
+				Permissions: &PermissionsDefinition_0_3{
+					Roles: map[api.RoleID]PrincipalsDefinition_0_3{
+						api.RoleTaskViewer: {Users: []string{"usr2"}, Groups: []string{"grp1"}},
+						api.RoleTaskAdmin:  {Users: []string{"usr1"}},
+					},
+					Approvals: &ApprovalsDefinition_0_3{Required: 2},
+				},
+			},
+			request: api.UpdateTaskRequest{
+				Name:       "Test Task",
+				Slug:       "test_task",
+				Parameters: []api.Parameter{},
+				Resources:  map[string]string{},
+				Configs:    &[]api.ConfigAttachment{},
+				Kind:       build.TaskKindPython,
+				KindOptions: build.KindOptions{
+					"entrypoint": "main.py",
+				},
+				RequireExplicitPermissions: pointers.Bool(true),
+				Permissions: &api.Permissions{
+					{RoleID: api.RoleTaskAdmin, SubUserID: pointers.String("usr1")},
+					{RoleID: api.RoleTaskViewer, SubUserID: pointers.String("usr2")},
+					{RoleID: api.RoleTaskViewer, SubGroupID: pointers.String("grp1")},
+				},
+				ExecuteRules: api.UpdateExecuteRulesRequest{
+					DisallowSelfApprove: pointers.Bool(false),
+					RequireRequests:     pointers.Bool(true),
+					RequiredApprovals:   pointers.Int(2),
+					ApproverGroupIDs:    &[]string{},
+				},
+				Timeout: 3600,
+			},
+		},
+		{
+			name: "test update team permissions",
+			definition: Definition_0_3{
+				Name: "Test Task",
+				Slug: "test_task",
+				Python: &PythonDefinition_0_3{
+					Entrypoint: "main.py",
+				},
+				Permissions: &PermissionsDefinition_0_3{
+					Roles: map[api.RoleID]PrincipalsDefinition_0_3{},
+				},
+			},
+			request: api.UpdateTaskRequest{
+				Name:       "Test Task",
+				Slug:       "test_task",
+				Parameters: []api.Parameter{},
+				Resources:  map[string]string{},
+				Configs:    &[]api.ConfigAttachment{},
+				Kind:       build.TaskKindPython,
+				KindOptions: build.KindOptions{
+					"entrypoint": "main.py",
+				},
+				RequireExplicitPermissions: pointers.Bool(false),
+				Permissions:                &api.Permissions{},
+				ExecuteRules: api.UpdateExecuteRulesRequest{
+					DisallowSelfApprove: pointers.Bool(false),
+					RequireRequests:     pointers.Bool(false),
+					RequiredApprovals:   pointers.Int(0),
+					ApproverGroupIDs:    &[]string{},
+				},
+				Timeout: 3600,
+			},
+		},
+		{
+			name: "test update approvals without roles",
+			definition: Definition_0_3{
+				Name: "Test Task",
+				Slug: "test_task",
+				Python: &PythonDefinition_0_3{
+					Entrypoint: "main.py",
+				},
+				RequireRequests: true,
+				Permissions: &PermissionsDefinition_0_3{
+					Approvals: &ApprovalsDefinition_0_3{Required: 1, Groups: []string{"grp1"}},
+				},
+			},
+			request: api.UpdateTaskRequest{
+				Name:       "Test Task",
+				Slug:       "test_task",
+				Parameters: []api.Parameter{},
+				Resources:  map[string]string{},
+				Configs:    &[]api.ConfigAttachment{},
+				Kind:       build.TaskKindPython,
+				KindOptions: build.KindOptions{
+					"entrypoint": "main.py",
+				},
+				ExecuteRules: api.UpdateExecuteRulesRequest{
+					DisallowSelfApprove: pointers.Bool(false),
+					RequireRequests:     pointers.Bool(true),
+					RequiredApprovals:   pointers.Int(1),
+					ApproverGroupIDs:    &[]string{"grp1"},
+				},
+				Timeout: 3600,
+			},
+		},
+		{
+			name: "test update compute",
+			definition: Definition_0_3{
+				Name: "Test Task",
+				Slug: "test_task",
+				Python: &PythonDefinition_0_3{
+					Entrypoint: "main.py",
+				},
+				Compute: &ComputeDefinition_0_3{
+					Requests: &ComputeResourcesDefinition_0_3{CPU: "2", Memory: "512Mi"},
+				},
+			},
+			request: api.UpdateTaskRequest{
+				Name:       "Test Task",
+				Slug:       "test_task",
+				Parameters: []api.Parameter{},
+				Resources:  map[string]string{},
+				Configs:    &[]api.ConfigAttachment{},
+				Kind:       build.TaskKindPython,
+				KindOptions: build.KindOptions{
+					"entrypoint": "main.py",
+				},
+				ResourceRequests: map[string]string{"cpu": "2", "memory": "512Mi"},
+				ResourceLimits:   map[string]string{},
+				ExecuteRules: api.UpdateExecuteRulesRequest{
+					DisallowSelfApprove: pointers.Bool(false),
+					RequireRequests:     pointers.Bool(false),
+				},
+				Timeout: 3600,
+			},
+		},
 		{
 			name: "test update default execute rules",
 			definition: Definition_0_3{
@@ -1577,6 +1834,75 @@ func TestDefinitionToUpdateTaskRequest_0_3(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "environment overrides",
+			definition: Definition_0_3{
+				Name: "Image Task",
+				Slug: "image_task",
+				Resources: ResourceDefinition_0_3{
+					Attachments: map[string]string{
+						"db":    "local_db",
+						"cache": "redis",
+					},
+				},
+				Image: &ImageDefinition_0_3{
+					Image:   "ubuntu:latest",
+					Command: "echo",
+					EnvVars: api.TaskEnv{
+						"LOG_LEVEL": api.EnvVarValue{Value: pointers.String("debug")},
+						"API_KEY":   api.EnvVarValue{Config: pointers.String("api_key")},
+					},
+				},
+				Constraints: map[string]string{"region": "us-west-2"},
+				Environments: map[string]EnvironmentDefinition_0_3{
+					"prod": {
+						EnvVars: api.TaskEnv{
+							"LOG_LEVEL": api.EnvVarValue{Value: pointers.String("warn")},
+						},
+						Resources: ResourceDefinition_0_3{
+							Attachments: map[string]string{"db": "prod_db"},
+						},
+						Constraints: map[string]string{"cluster": "prod"},
+					},
+				},
+			},
+			envSlug: "prod",
+			request: api.UpdateTaskRequest{
+				Name:       "Image Task",
+				Slug:       "image_task",
+				Parameters: []api.Parameter{},
+				Configs:    &[]api.ConfigAttachment{},
+				Command:    []string{},
+				Arguments:  []string{"echo"},
+				Kind:       build.TaskKindImage,
+				Image:      pointers.String("ubuntu:latest"),
+				Env: api.TaskEnv{
+					"LOG_LEVEL": api.EnvVarValue{Value: pointers.String("warn")},
+					"API_KEY":   api.EnvVarValue{Config: pointers.String("api_key")},
+				},
+				Resources: map[string]string{
+					"db":    "prod_db_id",
+					"cache": "redis_id",
+				},
+				Constraints: api.RunConstraints{
+					Labels: []api.AgentLabel{
+						{Key: "cluster", Value: "prod"},
+						{Key: "region", Value: "us-west-2"},
+					},
+				},
+				ExecuteRules: api.UpdateExecuteRulesRequest{
+					DisallowSelfApprove: pointers.Bool(false),
+					RequireRequests:     pointers.Bool(false),
+				},
+				Timeout: 3600,
+				EnvSlug: "prod",
+			},
+			resources: []api.Resource{
+				{ID: "local_db_id", Slug: "local_db"},
+				{ID: "prod_db_id", Slug: "prod_db"},
+				{ID: "redis_id", Slug: "redis"},
+			},
+		},
 	} {
 		t.Run(test.name, func(t *testing.T) {
 			assert := require.New(t)
@@ -1584,13 +1910,60 @@ func TestDefinitionToUpdateTaskRequest_0_3(t *testing.T) {
 			client := &mock.MockClient{
 				Resources: test.resources,
 			}
-			req, err := test.definition.GetUpdateTaskRequest(ctx, client)
+			req, err := test.definition.GetUpdateTaskRequest(ctx, client, test.envSlug)
 			assert.NoError(err)
 			assert.Equal(test.request, req)
 		})
 	}
 }
 
+func TestDefinitionForEnvironment_0_3(t *testing.T) {
+	require := require.New(t)
+
+	def := Definition_0_3{
+		Name: "Node Task",
+		Slug: "node_task",
+		Node: &NodeDefinition_0_3{
+			Entrypoint:  "main.ts",
+			NodeVersion: "16",
+			EnvVars: api.TaskEnv{
+				"LOG_LEVEL": {Value: pointers.String("debug")},
+			},
+		},
+		Schedules: map[string]ScheduleDefinition_0_3{
+			"daily": {CronExpr: "0 0 * * *"},
+		},
+		Environments: map[string]EnvironmentDefinition_0_3{
+			"prod": {
+				EnvVars: api.TaskEnv{
+					"LOG_LEVEL": {Value: pointers.String("warn")},
+				},
+				Schedules: map[string]ScheduleDefinition_0_3{
+					"hourly": {CronExpr: "0 * * * *"},
+				},
+			},
+		},
+	}
+
+	prod, err := def.ForEnvironment("prod")
+	require.NoError(err)
+	require.Nil(prod.Environments)
+	require.Equal(api.TaskEnv{"LOG_LEVEL": {Value: pointers.String("warn")}}, prod.Node.EnvVars)
+	require.Equal(map[string]ScheduleDefinition_0_3{
+		"daily":  {CronExpr: "0 0 * * *"},
+		"hourly": {CronExpr: "0 * * * *"},
+	}, prod.Schedules)
+
+	// The original definition is not modified.
+	require.Equal(api.TaskEnv{"LOG_LEVEL": {Value: pointers.String("debug")}}, def.Node.EnvVars)
+	require.Len(def.Schedules, 1)
+
+	// Environments without overrides use the top-level values.
+	staging, err := def.ForEnvironment("staging")
+	require.NoError(err)
+	require.Equal(def, staging)
+}
+
 func TestDefinitionGetSchedules_0_3(t *testing.T) {
 	require := require.New(t)
 
@@ -1607,7 +1980,8 @@ func TestDefinitionGetSchedules_0_3(t *testing.T) {
 		},
 	}
 
-	schedules := def.GetSchedules()
+	schedules, err := def.GetSchedules("")
+	require.NoError(err)
 	require.Len(schedules, 1)
 	require.Contains(schedules, "foo")
 
@@ -1619,3 +1993,121 @@ func TestDefinitionGetSchedules_0_3(t *testing.T) {
 	require.Contains(scheduleDef.ParamValues, "param_one")
 	require.Equal(scheduleDef.ParamValues["param_one"], 5.5)
 }
+
+func TestDefinitionGetSchedulesForEnvironment_0_3(t *testing.T) {
+	require := require.New(t)
+
+	def := Definition_0_3{
+		Schedules: map[string]ScheduleDefinition_0_3{
+			"daily":  {Name: "Daily", CronExpr: "0 0 * * *"},
+			"hourly": {Name: "Hourly", CronExpr: "0 * * * *"},
+		},
+		Environments: map[string]EnvironmentDefinition_0_3{
+			"prod": {
+				Schedules: map[string]ScheduleDefinition_0_3{
+					"daily":  {Name: "Daily", CronExpr: "30 6 * * *"},
+					"weekly": {Name: "Weekly", CronExpr: "0 0 * * 1"},
+				},
+			},
+		},
+	}
+
+	schedules, err := def.GetSchedules("prod")
+	require.NoError(err)
+	require.Len(schedules, 3)
+	require.Equal("30 6 * * *", schedules["daily"].CronExpr)
+	require.Equal("0 * * * *", schedules["hourly"].CronExpr)
+	require.Equal("0 0 * * 1", schedules["weekly"].CronExpr)
+
+	schedules, err = def.GetSchedules("staging")
+	require.NoError(err)
+	require.Len(schedules, 2)
+	require.Equal("0 0 * * *", schedules["daily"].CronExpr)
+}
+
+func TestDefinitionEnvironmentsSchema_0_3(t *testing.T) {
+	require := require.New(t)
+
+	d := Definition_0_3{}
+	err := d.Unmarshal(DefFormatYAML, []byte(`name: Node Task
+slug: node_task
+node:
+  entrypoint: main.ts
+  nodeVersion: "16"
+environments:
+  prod:
+    resources: [prod_db]
+    envVars:
+      LOG_LEVEL: warn
+`))
+	require.NoError(err)
+	require.Equal(map[string]EnvironmentDefinition_0_3{
+		"prod": {
+			Resources: ResourceDefinition_0_3{Attachments: map[string]string{"prod_db": "prod_db"}},
+			EnvVars:   api.TaskEnv{"LOG_LEVEL": {Value: pointers.String("warn")}},
+		},
+	}, d.Environments)
+
+	// Only some fields can be overridden.
+	err = d.Unmarshal(DefFormatYAML, []byte(`name: Node Task
+slug: node_task
+node:
+  entrypoint: main.ts
+  nodeVersion: "16"
+environments:
+  prod:
+    timeout: 60
+`))
+	var serr ErrSchemaValidation
+	require.True(errors.As(err, &serr))
+}
+
+func TestParametersRoundTrip_0_3(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	def := Definition_0_3{
+		Name: "Python Task",
+		Slug: "python_task",
+		Parameters: []ParameterDefinition_0_3{
+			{Name: "Blob", Slug: "blob", Type: "json", Default: map[string]interface{}{"a": []interface{}{float64(1)}}},
+			{Name: "Tags", Slug: "tags", Type: "list", Default: []interface{}{"a", "b"}},
+			{Name: "Colors", Slug: "colors", Type: "multiselect", Options: []OptionDefinition_0_3{
+				{Value: "red"},
+				{Value: "blue"},
+			}},
+			{Name: "Payload", Slug: "payload", Type: "object", Default: map[string]interface{}{"key": "value"}},
+			{Name: "Count", Slug: "count", Type: "integer", Min: pointers.Float64(1), Max: pointers.Float64(10)},
+			{Name: "Day", Slug: "day", Type: "date", MinDate: "2022-01-01", MaxDate: "2022-12-31", VisibleIf: &ParamConditionDefinition_0_3{
+				Param:  "count",
+				Equals: float64(2),
+			}},
+			{Name: "Note", Slug: "note", Type: "longtext", MaxLength: pointers.Int(100), RequiredIf: &ParamConditionDefinition_0_3{
+				Param: "day",
+			}},
+		},
+		Python: &PythonDefinition_0_3{Entrypoint: "main.py"},
+	}
+	require.NoError(def.Validate())
+
+	req, err := def.GetUpdateTaskRequest(ctx, &mock.MockClient{}, "")
+	require.NoError(err)
+	require.Equal(api.TypeJSON, req.Parameters[0].Type)
+	require.Equal(map[string]interface{}{"a": []interface{}{float64(1)}}, req.Parameters[0].Default)
+	require.Equal(api.TypeList, req.Parameters[1].Type)
+	require.Equal(api.ComponentNone, req.Parameters[1].Component)
+	require.Equal(api.TypeList, req.Parameters[2].Type)
+	require.Equal(api.ComponentMultiSelect, req.Parameters[2].Component)
+	require.Equal(api.TypeObject, req.Parameters[3].Type)
+
+	// Converting the task back yields the same parameters.
+	d := Definition_0_3{}
+	require.NoError(d.convertParametersFromTask(ctx, &mock.MockClient{}, &api.Task{Parameters: req.Parameters}))
+	for i := range def.Parameters {
+		if def.Parameters[i].RequiredIf == nil {
+			def.Parameters[i].Required = NewDefaultTrueDefinition(true)
+		}
+	}
+	require.True(req.Parameters[6].Constraints.Optional)
+	require.Equal(def.Parameters, d.Parameters)
+}
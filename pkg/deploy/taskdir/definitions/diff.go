@@ -0,0 +1,464 @@
+package definitions
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ChangeKind describes how a field changed.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// ChangeSection groups related changes.
+type ChangeSection string
+
+const (
+	// SectionTask covers top-level fields such as the name or timeout, as
+	// well as the task kind and its options, e.g. `node.entrypoint`.
+	SectionTask       ChangeSection = "task"
+	SectionParameters ChangeSection = "parameters"
+	SectionEnv        ChangeSection = "env"
+	SectionSchedules  ChangeSection = "schedules"
+	SectionTriggers   ChangeSection = "triggers"
+	SectionResources  ChangeSection = "resources"
+)
+
+// Change is a single field-level difference between two definitions.
+type Change struct {
+	Section ChangeSection
+	// Key identifies the changed item within its section: the field for
+	// SectionTask, the parameter slug, the env var name, the schedule or
+	// trigger slug, or the resource alias.
+	Key string
+	// Field is the field of a parameter, schedule or trigger that changed,
+	// e.g. `type`. It is empty if the item was added or removed as a whole.
+	Field string
+	Kind  ChangeKind
+	// Old is the remote value and New is the local value. Old is nil for
+	// additions and New is nil for removals.
+	Old interface{}
+	New interface{}
+}
+
+// String renders the change on a single line, e.g.
+// `~ parameter name: type "shorttext" -> "longtext"`.
+func (c Change) String() string {
+	var b strings.Builder
+	switch c.Kind {
+	case ChangeAdded:
+		b.WriteString("+ ")
+	case ChangeRemoved:
+		b.WriteString("- ")
+	default:
+		b.WriteString("~ ")
+	}
+
+	switch c.Section {
+	case SectionParameters:
+		b.WriteString("parameter ")
+	case SectionEnv:
+		b.WriteString("env var ")
+	case SectionSchedules:
+		b.WriteString("schedule ")
+	case SectionTriggers:
+		b.WriteString("trigger ")
+	case SectionResources:
+		b.WriteString("resource ")
+	}
+	b.WriteString(c.Key)
+	if c.Field != "" {
+		b.WriteString(": " + c.Field)
+	}
+
+	switch c.Kind {
+	case ChangeAdded:
+		b.WriteString(" = " + formatDiffValue(c.New))
+	case ChangeRemoved:
+		if c.Field != "" || c.Section == SectionTask {
+			b.WriteString(" (was " + formatDiffValue(c.Old) + ")")
+		}
+	default:
+		b.WriteString(" " + formatDiffValue(c.Old) + " -> " + formatDiffValue(c.New))
+	}
+	return b.String()
+}
+
+// DefinitionDiff is the set of changes that deploying a local definition
+// would make to a remote task.
+type DefinitionDiff struct {
+	// Changes are grouped by section, in a stable order.
+	Changes []Change
+}
+
+// IsEmpty returns true if the definitions are equivalent.
+func (d DefinitionDiff) IsEmpty() bool {
+	return len(d.Changes) == 0
+}
+
+// Section returns the changes in a single section.
+func (d DefinitionDiff) Section(section ChangeSection) []Change {
+	var changes []Change
+	for _, c := range d.Changes {
+		if c.Section == section {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// String renders the diff for deploy previews, one change per line.
+func (d DefinitionDiff) String() string {
+	if d.IsEmpty() {
+		return "No changes."
+	}
+	lines := make([]string, 0, len(d.Changes))
+	for _, c := range d.Changes {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// kindFields are the top-level fields that configure the task kind.
+var kindFields = []string{"docker", "node", "python", "shell", "sql", "rest"}
+
+// Diff compares a local definition to a remote one, typically created with
+// NewDefinitionFromTask, and returns what deploying local to the environment
+// envSlug would change.
+//
+// Fields are compared after normalization, so omitting a field is
+// equivalent to setting it to its default value.
+func Diff(local, remote DefinitionInterface, envSlug string) (DefinitionDiff, error) {
+	l, err := diffableDefinition(local)
+	if err != nil {
+		return DefinitionDiff{}, errors.Wrap(err, "reading local definition")
+	}
+	r, err := diffableDefinition(remote)
+	if err != nil {
+		return DefinitionDiff{}, errors.Wrap(err, "reading remote definition")
+	}
+
+	var changes []Change
+	add := func(c Change) { changes = append(changes, c) }
+
+	// Task kind and its options.
+	lkind, lopts := splitKind(l)
+	rkind, ropts := splitKind(r)
+	lenv, _ := lopts["envVars"].(map[string]interface{})
+	renv, _ := ropts["envVars"].(map[string]interface{})
+	delete(lopts, "envVars")
+	delete(ropts, "envVars")
+	if lkind != rkind {
+		add(Change{Section: SectionTask, Key: "kind", Kind: ChangeModified, Old: rkind, New: lkind})
+	} else {
+		for _, c := range diffMaps(ropts, lopts) {
+			c.Section = SectionTask
+			c.Key = lkind + "." + c.Key
+			add(c)
+		}
+	}
+
+	lparams, lorder := paramsBySlug(l)
+	rparams, rorder := paramsBySlug(r)
+
+	// Remaining top-level fields.
+	delete(l, "parameters")
+	delete(r, "parameters")
+	delete(l, "schedules")
+	delete(r, "schedules")
+	delete(l, "triggers")
+	delete(r, "triggers")
+	for _, c := range diffMaps(r, l) {
+		c.Section = SectionTask
+		add(c)
+	}
+
+	// Parameters, keyed by slug.
+	for _, c := range diffNested(rparams, lparams) {
+		c.Section = SectionParameters
+		add(c)
+	}
+	if !reflect.DeepEqual(commonOrder(lorder, rparams), commonOrder(rorder, lparams)) {
+		add(Change{Section: SectionParameters, Key: "order", Kind: ChangeModified, Old: rorder, New: lorder})
+	}
+
+	// Env vars, keyed by name.
+	for _, c := range diffMaps(renv, lenv) {
+		c.Section = SectionEnv
+		add(c)
+	}
+
+	// Schedules, keyed by slug.
+	lscheds, err := schedulesBySlug(local, envSlug)
+	if err != nil {
+		return DefinitionDiff{}, err
+	}
+	rscheds, err := schedulesBySlug(remote, envSlug)
+	if err != nil {
+		return DefinitionDiff{}, err
+	}
+	for _, c := range diffNested(rscheds, lscheds) {
+		c.Section = SectionSchedules
+		add(c)
+	}
+
+	// Triggers, keyed by slug.
+	ltriggers, err := triggersBySlug(local)
+	if err != nil {
+		return DefinitionDiff{}, err
+	}
+	rtriggers, err := triggersBySlug(remote)
+	if err != nil {
+		return DefinitionDiff{}, err
+	}
+	for _, c := range diffNested(rtriggers, ltriggers) {
+		c.Section = SectionTriggers
+		add(c)
+	}
+
+	// Resources, keyed by alias.
+	for _, c := range diffMaps(stringMap(remote.GetResourceAttachments()), stringMap(local.GetResourceAttachments())) {
+		c.Section = SectionResources
+		add(c)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return sectionOrder(changes[i].Section) < sectionOrder(changes[j].Section)
+	})
+	return DefinitionDiff{Changes: changes}, nil
+}
+
+func sectionOrder(s ChangeSection) int {
+	for i, section := range []ChangeSection{SectionTask, SectionParameters, SectionEnv, SectionSchedules, SectionTriggers, SectionResources} {
+		if s == section {
+			return i
+		}
+	}
+	return -1
+}
+
+// diffableDefinition returns the normalized fields of a definition, with
+// the fields that are compared separately removed.
+func diffableDefinition(d DefinitionInterface) (map[string]interface{}, error) {
+	buf, err := d.Marshal(DefFormatJSON)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return nil, errors.Wrap(err, "unmarshalling definition")
+	}
+	// Resources are compared through GetResourceAttachments, since they
+	// can be written either as a list or as a map.
+	delete(m, "resources")
+	// REST bodies are compared by content, since they can be read from a
+	// file.
+	if def, ok := d.(*Definition_0_3); ok && def.REST != nil && def.REST.BodyFile != "" {
+		body, err := def.REST.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		if rest, ok := m["rest"].(map[string]interface{}); ok {
+			delete(rest, "bodyFile")
+			rest["body"] = body
+		}
+	}
+	return m, nil
+}
+
+// splitKind removes the task kind from m, returning the kind and its
+// options.
+func splitKind(m map[string]interface{}) (string, map[string]interface{}) {
+	for _, kind := range kindFields {
+		if opts, ok := m[kind]; ok {
+			delete(m, kind)
+			o, _ := opts.(map[string]interface{})
+			if o == nil {
+				o = map[string]interface{}{}
+			}
+			return kind, o
+		}
+	}
+	return "", map[string]interface{}{}
+}
+
+// paramsBySlug returns the parameters of a definition by slug, along with
+// their order.
+func paramsBySlug(m map[string]interface{}) (map[string]map[string]interface{}, []string) {
+	params, _ := m["parameters"].([]interface{})
+	bySlug := map[string]map[string]interface{}{}
+	var order []string
+	for _, p := range params {
+		param, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		slug, _ := param["slug"].(string)
+		bySlug[slug] = param
+		order = append(order, slug)
+	}
+	return bySlug, order
+}
+
+func schedulesBySlug(d DefinitionInterface, envSlug string) (map[string]map[string]interface{}, error) {
+	schedules, err := d.GetSchedules(envSlug)
+	if err != nil {
+		return nil, err
+	}
+	bySlug := map[string]map[string]interface{}{}
+	for slug, s := range schedules {
+		def := ScheduleDefinition_0_3{
+			Name:        s.Name,
+			Description: s.Description,
+			CronExpr:    s.CronExpr,
+			Timezone:    s.Timezone,
+			Paused:      s.Paused,
+			ParamValues: s.ParamValues,
+		}
+		if s.StartAt != nil {
+			def.StartDate = s.StartAt.UTC().Format(time.RFC3339)
+		}
+		if s.EndAt != nil {
+			def.EndDate = s.EndAt.UTC().Format(time.RFC3339)
+		}
+		buf, err := json.Marshal(def)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling schedule")
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(buf, &m); err != nil {
+			return nil, errors.Wrap(err, "unmarshalling schedule")
+		}
+		bySlug[slug] = m
+	}
+	return bySlug, nil
+}
+
+func triggersBySlug(d DefinitionInterface) (map[string]map[string]interface{}, error) {
+	bySlug := map[string]map[string]interface{}{}
+	for slug, t := range d.GetTriggers() {
+		def := TriggerDefinition_0_3{
+			Name:        t.Name,
+			Description: t.Description,
+			Paused:      t.Paused,
+		}
+		def.setKindConfig(t.Kind, t.KindConfig)
+		buf, err := json.Marshal(def)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling trigger")
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(buf, &m); err != nil {
+			return nil, errors.Wrap(err, "unmarshalling trigger")
+		}
+		bySlug[slug] = m
+	}
+	return bySlug, nil
+}
+
+// commonOrder returns the slugs in order that also exist in other, so that
+// additions and removals are not reported as reorderings.
+func commonOrder(order []string, other map[string]map[string]interface{}) []string {
+	common := []string{}
+	for _, slug := range order {
+		if _, ok := other[slug]; ok {
+			common = append(common, slug)
+		}
+	}
+	return common
+}
+
+// diffMaps returns the changes between the values of old and new, keyed by
+// map key, in key order.
+func diffMaps(old, new map[string]interface{}) []Change {
+	var changes []Change
+	for _, k := range unionKeys(old, new) {
+		o, inOld := old[k]
+		n, inNew := new[k]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Key: k, Kind: ChangeAdded, New: n})
+		case !inNew:
+			changes = append(changes, Change{Key: k, Kind: ChangeRemoved, Old: o})
+		case !reflect.DeepEqual(o, n):
+			changes = append(changes, Change{Key: k, Kind: ChangeModified, Old: o, New: n})
+		}
+	}
+	return changes
+}
+
+// diffNested is like diffMaps, but reports the individual fields that
+// changed for items that exist on both sides.
+func diffNested(old, new map[string]map[string]interface{}) []Change {
+	keys := map[string]bool{}
+	for k := range old {
+		keys[k] = true
+	}
+	for k := range new {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []Change
+	for _, k := range sorted {
+		o, inOld := old[k]
+		n, inNew := new[k]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Key: k, Kind: ChangeAdded, New: n})
+		case !inNew:
+			changes = append(changes, Change{Key: k, Kind: ChangeRemoved, Old: o})
+		default:
+			for _, c := range diffMaps(o, n) {
+				c.Field = c.Key
+				c.Key = k
+				changes = append(changes, c)
+			}
+		}
+	}
+	return changes
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func stringMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func formatDiffValue(v interface{}) string {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(buf)
+}
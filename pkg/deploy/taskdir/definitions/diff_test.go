@@ -0,0 +1,172 @@
+package definitions
+
+import (
+	"testing"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/airplanedev/lib/pkg/utils/pointers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	remote := Definition_0_3{
+		Name: "My task",
+		Slug: "my_task",
+		Parameters: []ParameterDefinition_0_3{
+			{Name: "Name", Slug: "name", Type: "shorttext"},
+			{Name: "Count", Slug: "count", Type: "integer"},
+		},
+		Resources: ResourceDefinition_0_3{Attachments: map[string]string{"db": "db"}},
+		Node: &NodeDefinition_0_3{
+			Entrypoint:  "my_task.ts",
+			NodeVersion: "16",
+			EnvVars: api.TaskEnv{
+				"API_KEY": {Config: pointers.String("api_key")},
+				"DEBUG":   {Value: pointers.String("false")},
+			},
+		},
+		Schedules: map[string]ScheduleDefinition_0_3{
+			"daily":  {CronExpr: "0 0 * * *"},
+			"hourly": {CronExpr: "0 * * * *"},
+		},
+		Triggers: map[string]TriggerDefinition_0_3{
+			"on_push": {Webhook: &WebhookTriggerDefinition_0_3{}},
+		},
+	}
+
+	t.Run("no changes", func(t *testing.T) {
+		require := require.New(t)
+		local := remote
+		// Defaults are equivalent to omitted fields.
+		local.Timeout = DefaultTimeoutDefinition{3600}
+		local.AllowSelfApprovals = NewDefaultTrueDefinition(true)
+
+		diff, err := Diff(&local, &remote, "")
+		require.NoError(err)
+		require.True(diff.IsEmpty())
+		require.Equal("No changes.", diff.String())
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		require := require.New(t)
+		local := remote
+		local.Name = "Your task"
+		local.Timeout = DefaultTimeoutDefinition{60}
+		local.Parameters = []ParameterDefinition_0_3{
+			{Name: "Name", Slug: "name", Type: "longtext"},
+			{Name: "Dry run", Slug: "dry_run", Type: "boolean"},
+		}
+		local.Resources = ResourceDefinition_0_3{Attachments: map[string]string{"warehouse": "db"}}
+		local.Node = &NodeDefinition_0_3{
+			Entrypoint:  "my_task.ts",
+			NodeVersion: "18",
+			EnvVars: api.TaskEnv{
+				"API_KEY": {Config: pointers.String("api_key")},
+				"DEBUG":   {Value: pointers.String("true")},
+			},
+		}
+		local.Schedules = map[string]ScheduleDefinition_0_3{
+			"daily":  {CronExpr: "0 12 * * *"},
+			"weekly": {CronExpr: "0 0 * * 0"},
+		}
+		local.Triggers = map[string]TriggerDefinition_0_3{
+			"on_push": {Paused: true, Webhook: &WebhookTriggerDefinition_0_3{}},
+		}
+
+		diff, err := Diff(&local, &remote, "")
+		require.NoError(err)
+		require.Equal([]Change{
+			{Section: SectionTask, Key: "node.nodeVersion", Kind: ChangeModified, Old: "16", New: "18"},
+			{Section: SectionTask, Key: "name", Kind: ChangeModified, Old: "My task", New: "Your task"},
+			{Section: SectionTask, Key: "timeout", Kind: ChangeAdded, New: float64(60)},
+			{Section: SectionParameters, Key: "count", Kind: ChangeRemoved, Old: map[string]interface{}{
+				"name": "Count", "slug": "count", "type": "integer",
+			}},
+			{Section: SectionParameters, Key: "dry_run", Kind: ChangeAdded, New: map[string]interface{}{
+				"name": "Dry run", "slug": "dry_run", "type": "boolean",
+			}},
+			{Section: SectionParameters, Key: "name", Field: "type", Kind: ChangeModified, Old: "shorttext", New: "longtext"},
+			{Section: SectionEnv, Key: "DEBUG", Kind: ChangeModified,
+				Old: map[string]interface{}{"value": "false"},
+				New: map[string]interface{}{"value": "true"},
+			},
+			{Section: SectionSchedules, Key: "daily", Field: "cron", Kind: ChangeModified, Old: "0 0 * * *", New: "0 12 * * *"},
+			{Section: SectionSchedules, Key: "hourly", Kind: ChangeRemoved, Old: map[string]interface{}{"cron": "0 * * * *"}},
+			{Section: SectionSchedules, Key: "weekly", Kind: ChangeAdded, New: map[string]interface{}{"cron": "0 0 * * 0"}},
+			{Section: SectionTriggers, Key: "on_push", Field: "paused", Kind: ChangeAdded, New: true},
+			{Section: SectionResources, Key: "db", Kind: ChangeRemoved, Old: "db"},
+			{Section: SectionResources, Key: "warehouse", Kind: ChangeAdded, New: "db"},
+		}, diff.Changes)
+
+		require.Equal(`~ node.nodeVersion "16" -> "18"
+~ name "My task" -> "Your task"
++ timeout = 60
+- parameter count
++ parameter dry_run = {"name":"Dry run","slug":"dry_run","type":"boolean"}
+~ parameter name: type "shorttext" -> "longtext"
+~ env var DEBUG {"value":"false"} -> {"value":"true"}
+~ schedule daily: cron "0 0 * * *" -> "0 12 * * *"
+- schedule hourly
++ schedule weekly = {"cron":"0 0 * * 0"}
++ trigger on_push: paused = true
+- resource db
++ resource warehouse = "db"`, diff.String())
+	})
+
+	t.Run("environment schedules", func(t *testing.T) {
+		require := require.New(t)
+		deployed := remote
+		deployed.Schedules = map[string]ScheduleDefinition_0_3{
+			"daily":  {CronExpr: "0 6 * * *"},
+			"hourly": {CronExpr: "0 * * * *"},
+		}
+		local := remote
+		local.Environments = map[string]EnvironmentDefinition_0_3{
+			"prod": {Schedules: map[string]ScheduleDefinition_0_3{"daily": {CronExpr: "0 6 * * *"}}},
+		}
+
+		schedules := func(diff DefinitionDiff) []Change {
+			var changes []Change
+			for _, c := range diff.Changes {
+				if c.Section == SectionSchedules {
+					changes = append(changes, c)
+				}
+			}
+			return changes
+		}
+
+		diff, err := Diff(&local, &deployed, "prod")
+		require.NoError(err)
+		require.Empty(schedules(diff))
+
+		diff, err = Diff(&local, &deployed, "")
+		require.NoError(err)
+		require.Len(schedules(diff), 1)
+	})
+
+	t.Run("reordered parameters", func(t *testing.T) {
+		require := require.New(t)
+		local := remote
+		local.Parameters = []ParameterDefinition_0_3{remote.Parameters[1], remote.Parameters[0]}
+
+		diff, err := Diff(&local, &remote, "")
+		require.NoError(err)
+		require.Equal([]Change{
+			{Section: SectionParameters, Key: "order", Kind: ChangeModified,
+				Old: []string{"name", "count"},
+				New: []string{"count", "name"},
+			},
+		}, diff.Changes)
+	})
+
+	t.Run("kind changed", func(t *testing.T) {
+		require := require.New(t)
+		local := remote
+		local.Node = nil
+		local.Python = &PythonDefinition_0_3{Entrypoint: "my_task.py"}
+
+		diff, err := Diff(&local, &remote, "")
+		require.NoError(err)
+		require.Equal(Change{Section: SectionTask, Key: "kind", Kind: ChangeModified, Old: "node", New: "python"}, diff.Changes[0])
+	})
+}
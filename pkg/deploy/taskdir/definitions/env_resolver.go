@@ -0,0 +1,168 @@
+package definitions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/pkg/errors"
+)
+
+// EnvResolver checks that the config variables, secrets and resources that
+// definitions refer to exist in an environment, e.g. before deploying.
+//
+// Configs, secrets and resources are listed once, the first time they are
+// needed, so a resolver can check many definitions.
+type EnvResolver struct {
+	Client api.IAPIClient
+	// EnvSlug is the environment to check references in. If empty, the
+	// default environment is used.
+	EnvSlug string
+
+	loaded    bool
+	configs   map[string]bool
+	secrets   map[string]bool
+	resources map[string]bool
+}
+
+func (r *EnvResolver) load(ctx context.Context) error {
+	if r.loaded {
+		return nil
+	}
+
+	configs, err := r.Client.ListConfigs(ctx, api.ListConfigsRequest{EnvSlug: r.EnvSlug})
+	if err != nil {
+		return errors.Wrap(err, "listing configs")
+	}
+	r.configs = map[string]bool{}
+	for _, c := range configs.Configs {
+		r.configs[c.NameTag()] = true
+	}
+
+	secrets, err := r.Client.ListSecrets(ctx, api.ListSecretsRequest{EnvSlug: r.EnvSlug})
+	if err != nil {
+		return errors.Wrap(err, "listing secrets")
+	}
+	r.secrets = map[string]bool{}
+	for _, s := range secrets.Secrets {
+		r.secrets[s.Name] = true
+	}
+
+	resources, err := r.Client.ListResources(ctx)
+	if err != nil {
+		return errors.Wrap(err, "listing resources")
+	}
+	r.resources = map[string]bool{}
+	for _, res := range resources.Resources {
+		r.resources[res.Slug] = true
+	}
+
+	r.loaded = true
+	return nil
+}
+
+// CheckEnv returns an error for each env var, found at pointer, that refers
+// to a config, secret or resource that does not exist. Fields of resources
+// are not checked, since resources do not list their secret fields.
+func (r *EnvResolver) CheckEnv(ctx context.Context, pointer string, env map[string]api.EnvVarValue) ([]ValidationError, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []ValidationError
+	for _, name := range names {
+		ev := env[name]
+		ptr := pointer + "/" + escapePointer(name)
+		switch {
+		case ev.Config != nil:
+			if msg := r.checkConfig(*ev.Config); msg != "" {
+				errs = append(errs, ValidationError{Pointer: ptr + "/config", Message: msg})
+			}
+		case ev.Secret != nil:
+			if !r.secrets[*ev.Secret] {
+				errs = append(errs, ValidationError{Pointer: ptr + "/secret", Message: r.missing("secret", *ev.Secret)})
+			}
+		case ev.Resource != nil:
+			slug, _, err := ev.ResourceField()
+			if err != nil {
+				errs = append(errs, ValidationError{Pointer: ptr + "/resource", Message: err.Error()})
+			} else if !r.resources[slug] {
+				errs = append(errs, ValidationError{Pointer: ptr + "/resource", Message: r.missing("resource", slug)})
+			}
+		}
+	}
+	return errs, nil
+}
+
+func (r *EnvResolver) checkConfig(nameTag string) string {
+	if r.configs[nameTag] {
+		return ""
+	}
+	return r.missing("config", nameTag)
+}
+
+func (r *EnvResolver) missing(kind, name string) string {
+	if r.EnvSlug == "" {
+		return fmt.Sprintf("%s %q does not exist", kind, name)
+	}
+	return fmt.Sprintf("%s %q does not exist in environment %q", kind, name, r.EnvSlug)
+}
+
+// CheckDefinition checks the references of the env vars and configs of a
+// task definition, with the overrides for the environment of the resolver
+// applied. It returns an ErrValidation listing every missing config, secret
+// and resource.
+func (r *EnvResolver) CheckDefinition(ctx context.Context, d Definition_0_3) error {
+	d, err := d.ForEnvironment(r.EnvSlug)
+	if err != nil {
+		return err
+	}
+	if err := r.load(ctx); err != nil {
+		return err
+	}
+
+	var errs []ValidationError
+	if env, err := d.GetEnv(); err == nil {
+		envErrs, err := r.CheckEnv(ctx, d.kindPointer()+"/envVars", env)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, envErrs...)
+	}
+	if configs, err := d.GetConfigAttachments(); err == nil {
+		for i, c := range configs {
+			if msg := r.checkConfig(c.NameTag); msg != "" {
+				errs = append(errs, ValidationError{
+					Pointer: fmt.Sprintf("%s/configs/%d", d.kindPointer(), i),
+					Message: msg,
+				})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return ErrValidation{Errors: errs}
+	}
+	return nil
+}
+
+// CheckView checks the references of the env vars of a view definition. It
+// returns an ErrValidation listing every missing config, secret and
+// resource.
+func (r *EnvResolver) CheckView(ctx context.Context, d ViewDefinition) error {
+	errs, err := r.CheckEnv(ctx, "/envVars", d.EnvVars)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return ErrValidation{Errors: errs}
+	}
+	return nil
+}
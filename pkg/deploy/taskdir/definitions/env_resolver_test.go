@@ -0,0 +1,82 @@
+package definitions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/airplanedev/lib/pkg/api/mock"
+	"github.com/airplanedev/lib/pkg/utils/pointers"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvResolver(t *testing.T) {
+	ctx := context.Background()
+	client := &mock.MockClient{
+		Resources: []api.Resource{{ID: "res1", Slug: "db"}},
+		Configs: map[string][]api.Config{
+			"":     {{Name: "db_url"}, {Name: "api_key", Tag: "staging"}},
+			"prod": {{Name: "db_url"}},
+		},
+		Secrets: map[string][]api.Secret{
+			"": {{Name: "stripe_key"}},
+		},
+	}
+	def := Definition_0_3{
+		Name: "My task",
+		Slug: "my_task",
+		Node: &NodeDefinition_0_3{
+			Entrypoint:  "my_task.ts",
+			NodeVersion: "18",
+			EnvVars: api.TaskEnv{
+				"LOG_LEVEL":  {Value: pointers.String("debug")},
+				"DB_URL":     {Config: pointers.String("db_url")},
+				"DB_HOST":    {Resource: pointers.String("db.host")},
+				"STRIPE_KEY": {Secret: pointers.String("stripe_key")},
+			},
+		},
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		r := &EnvResolver{Client: client}
+		require.NoError(t, r.CheckDefinition(ctx, def))
+	})
+
+	t.Run("missing references", func(t *testing.T) {
+		require := require.New(t)
+		r := &EnvResolver{Client: client, EnvSlug: "prod"}
+		def := def
+		def.Environments = map[string]EnvironmentDefinition_0_3{
+			"prod": {EnvVars: api.TaskEnv{
+				"API_KEY": {Config: pointers.String("api_key:staging")},
+				"CACHE":   {Resource: pointers.String("cache.url")},
+			}},
+		}
+
+		err := r.CheckDefinition(ctx, def)
+		var verr ErrValidation
+		require.True(errors.As(err, &verr))
+		require.Equal([]ValidationError{
+			{Pointer: "/node/envVars/API_KEY/config", Message: `config "api_key:staging" does not exist in environment "prod"`},
+			{Pointer: "/node/envVars/CACHE/resource", Message: `resource "cache" does not exist in environment "prod"`},
+			{Pointer: "/node/envVars/STRIPE_KEY/secret", Message: `secret "stripe_key" does not exist in environment "prod"`},
+		}, verr.Errors)
+	})
+
+	t.Run("views", func(t *testing.T) {
+		require := require.New(t)
+		r := &EnvResolver{Client: client}
+		err := r.CheckView(ctx, ViewDefinition{
+			EnvVars: api.EnvVars{
+				"DB_URL": {Config: pointers.String("db_url")},
+				"TOKEN":  {Secret: pointers.String("token")},
+			},
+		})
+		var verr ErrValidation
+		require.True(errors.As(err, &verr))
+		require.Equal([]ValidationError{
+			{Pointer: "/envVars/TOKEN/secret", Message: `secret "token" does not exist`},
+		}, verr.Errors)
+	})
+}
@@ -9,9 +9,37 @@ import (
 
 const taskDefDocURL = "https://docs.airplane.dev/tasks/task-definition"
 
+// FieldError is a problem with a single field of a definition file.
+type FieldError struct {
+	// Field is the path to the offending field, e.g. `parameters.0.type`.
+	Field   string
+	Message string
+	// Line and Column are the 1-based position of the offending field in
+	// the definition file. They are zero if the position is not known.
+	Line   int
+	Column int
+	// File is the fragment that the offending field was read from, if it
+	// was not read from the definition file itself. Line and Column are
+	// positions in File.
+	File string
+}
+
+func (e FieldError) String() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
 type errReadDefinition struct {
 	msg       string
 	errorMsgs []string
+
+	// file and source are the path and contents of the definition file that
+	// fieldErrors refer to.
+	file        string
+	source      []byte
+	fieldErrors []FieldError
+	// fragments maps the paths of fragments that fieldErrors refer to to
+	// their contents.
+	fragments map[string][]byte
 }
 
 func NewErrReadDefinition(msg string, errorMsgs ...string) error {
@@ -21,6 +49,30 @@ func NewErrReadDefinition(msg string, errorMsgs ...string) error {
 	})
 }
 
+// NewErrReadDefinitionWithPositions is like NewErrReadDefinition, but its
+// explanation points at the position of each error in the definition file.
+func NewErrReadDefinitionWithPositions(msg string, file string, source []byte, fieldErrors ...FieldError) error {
+	return errors.WithStack(errReadDefinition{
+		msg:         msg,
+		file:        file,
+		source:      source,
+		fieldErrors: fieldErrors,
+	})
+}
+
+// NewErrReadDefinitionWithFragments is like NewErrReadDefinitionWithPositions,
+// but fieldErrors can also point at positions in the fragments that def
+// extends.
+func NewErrReadDefinitionWithFragments(msg string, file string, def ExtendedDefinition, fieldErrors ...FieldError) error {
+	return errors.WithStack(errReadDefinition{
+		msg:         msg,
+		file:        file,
+		source:      def.source,
+		fieldErrors: fieldErrors,
+		fragments:   def.fragments,
+	})
+}
+
 func (err errReadDefinition) Error() string {
 	return err.msg
 }
@@ -29,9 +81,70 @@ func (err errReadDefinition) Error() string {
 func (err errReadDefinition) ExplainError() string {
 	msgs := []string{}
 	msgs = append(msgs, err.errorMsgs...)
-	if len(err.errorMsgs) > 0 {
+	for _, ferr := range err.fieldErrors {
+		file, source := err.file, err.source
+		if ferr.File != "" {
+			file, source = ferr.File, err.fragments[ferr.File]
+		}
+		switch {
+		case ferr.Line == 0 && ferr.File == "":
+			msgs = append(msgs, ferr.String())
+		case ferr.Line == 0:
+			msgs = append(msgs, fmt.Sprintf("%s: %s", file, ferr.String()))
+		default:
+			msgs = append(msgs, fmt.Sprintf("%s:%d:%d: %s", file, ferr.Line, ferr.Column, ferr.String()))
+			msgs = append(msgs, codeFrame(source, ferr.Line, ferr.Column))
+		}
+	}
+	if len(err.errorMsgs) > 0 || len(err.fieldErrors) > 0 {
 		msgs = append(msgs, "")
 	}
 	msgs = append(msgs, fmt.Sprintf("For more information on the task definition format, see the docs:\n%s", taskDefDocURL))
 	return strings.Join(msgs, "\n")
 }
+
+// codeFrameContext is the number of lines shown above the offending line
+// in a code frame.
+const codeFrameContext = 2
+
+// codeFrame renders the lines of source leading up to line, with a caret
+// under column, e.g.
+//
+//	  6 |   type: integer
+//	> 7 |   default: hello
+//	    |            ^
+func codeFrame(source []byte, line, column int) string {
+	lines := strings.Split(strings.TrimSuffix(string(source), "\n"), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	first := line - codeFrameContext
+	if first < 1 {
+		first = 1
+	}
+	width := len(fmt.Sprint(line))
+
+	var b strings.Builder
+	for i := first; i <= line; i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, i, strings.TrimRight(lines[i-1], "\r"))
+	}
+
+	// Keep tabs so that the caret lines up with the offending line.
+	var indent strings.Builder
+	for i, r := range []rune(lines[line-1]) {
+		if i >= column-1 {
+			break
+		}
+		if r == '\t' {
+			indent.WriteRune('\t')
+		} else {
+			indent.WriteRune(' ')
+		}
+	}
+	fmt.Fprintf(&b, "  %*s | %s^", width, "", indent.String())
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package definitions
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrReadDefinitionWithPositions(t *testing.T) {
+	require := require.New(t)
+
+	source := []byte(`name: Hello World
+slug: hello_world
+parameters:
+- name: Count
+  slug: count
+  type: integer
+  default: hello
+`)
+	err := NewErrReadDefinitionWithPositions("Error reading hello_world.task.yaml", "hello_world.task.yaml", source,
+		FieldError{Field: "parameters.0.default", Message: "default must be an integer", Line: 7, Column: 3},
+		FieldError{Field: "schedules", Message: "unknown field"},
+	)
+	require.Equal("Error reading hello_world.task.yaml", err.Error())
+
+	var explained interface{ ExplainError() string }
+	require.True(errors.As(err, &explained))
+	require.Equal(`hello_world.task.yaml:7:3: parameters.0.default: default must be an integer
+  5 |   slug: count
+  6 |   type: integer
+> 7 |   default: hello
+    |   ^
+schedules: unknown field
+
+For more information on the task definition format, see the docs:
+`+taskDefDocURL, explained.ExplainError())
+}
+
+func TestCodeFrame(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		source   string
+		line     int
+		column   int
+		expected string
+	}{
+		{
+			name:   "first line",
+			source: "name: Hello\nslug: hello\n",
+			line:   1,
+			column: 7,
+			expected: `> 1 | name: Hello
+    |       ^`,
+		},
+		{
+			name:   "tabs",
+			source: "{\n\t\"slug\": 1\n}",
+			line:   2,
+			column: 2,
+			expected: `  1 | {
+> 2 | 	"slug": 1
+    | 	^`,
+		},
+		{
+			name:   "wide line numbers",
+			source: "a\nb\nc\nd\ne\nf\ng\nh\ni\nj: x\n",
+			line:   10,
+			column: 4,
+			expected: `   8 | h
+   9 | i
+> 10 | j: x
+     |    ^`,
+		},
+		{
+			name:   "out of range",
+			source: "a\n",
+			line:   3,
+			column: 1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, codeFrame([]byte(test.source), test.line, test.column))
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package definitions
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// extendsKey is the field of a definition, or of a fragment, that lists the
+// fragments it extends.
+const extendsKey = "extends"
+
+// Fragments can have any YAML or JSON extension, e.g. `shared.yaml`.
+var (
+	yamlFragmentExtensions = []string{".yaml", ".yml"}
+	jsonFragmentExtensions = []string{".json"}
+)
+
+// ExtendedDefinition is a task definition with the fragments that it extends
+// merged in.
+//
+// A definition extends fragments by listing their paths, relative to the
+// definition, in a top-level `extends` field:
+//
+//	extends:
+//	  - ../shared/constraints.yaml
+//	  - ../shared/node.yaml
+//
+// Fragments are YAML or JSON files with any of the fields of a definition,
+// and can extend other fragments in turn. Fields are merged with the
+// following precedence:
+//
+//   - The definition overrides the fragments that it extends.
+//   - Fragments override the fragments listed before them.
+//   - Fragments override the fragments that they extend.
+//
+// Objects, such as `constraints` or `node.envVars`, are merged field by
+// field. All other values, including lists such as `parameters`, are
+// replaced as a whole.
+type ExtendedDefinition struct {
+	// Format and Buf are the merged definition. If the definition does not
+	// extend any fragments, they are the format and contents of the
+	// definition file.
+	Format DefFormat
+	Buf    []byte
+	// Entry identifies the definition in a file that defines several tasks,
+	// e.g. `tasks.1` or `document 2`. It is empty if the file defines a
+	// single task.
+	Entry string
+
+	path   string
+	format DefFormat
+	source []byte
+
+	// doc is the merged document, whose nodes keep their positions in the
+	// file that they were read from. It is nil if the definition does not
+	// extend any fragments.
+	doc *yaml.Node
+	// files maps each node of doc to the file it was read from.
+	files map[*yaml.Node]string
+	// fragments maps the paths of fragments to their contents.
+	fragments map[string][]byte
+}
+
+// ExtendDefinition merges the fragments that the definition at path, with
+// contents buf, extends. Fragments are read relative to the file that
+// extends them.
+func ExtendDefinition(path string, format DefFormat, buf []byte) (ExtendedDefinition, error) {
+	d := newExtendedDefinition(path, format, buf)
+	root, err := parseDefNode(format, buf)
+	if err != nil || !hasExtends(root.Content[0]) {
+		// Leave the definition for UnmarshalDefinition to report on.
+		return d, nil
+	}
+	return d.extend(root.Content[0])
+}
+
+func newExtendedDefinition(path string, format DefFormat, buf []byte) ExtendedDefinition {
+	return ExtendedDefinition{
+		Format:    format,
+		Buf:       buf,
+		path:      path,
+		format:    format,
+		source:    buf,
+		files:     map[*yaml.Node]string{},
+		fragments: map[string][]byte{},
+	}
+}
+
+// extend merges the fragments that the top-level mapping n extends into n,
+// and sets Buf to the result.
+func (d ExtendedDefinition) extend(n *yaml.Node) (ExtendedDefinition, error) {
+	merged, err := d.resolve(d.path, n, nil)
+	if err != nil {
+		return ExtendedDefinition{}, err
+	}
+	d.doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{merged}}
+	out, err := yaml.Marshal(d.doc)
+	if err != nil {
+		return ExtendedDefinition{}, errors.Wrap(err, "marshalling extended definition")
+	}
+	d.Format = DefFormatYAML
+	d.Buf = out
+	return d, nil
+}
+
+func hasExtends(n *yaml.Node) bool {
+	if n.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == extendsKey {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns n, the top-level mapping of the file at path, with the
+// fragments that it extends merged in. stack is the chain of files that
+// extend path, and is used to detect cycles.
+func (d *ExtendedDefinition) resolve(path string, n *yaml.Node, stack []string) (*yaml.Node, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolving %s", path)
+	}
+	for i, p := range stack {
+		if a, err := filepath.Abs(p); err == nil && a == abs {
+			return nil, errors.Errorf("cycle in %s: %s", extendsKey, strings.Join(append(stack[i:], path), " -> "))
+		}
+	}
+	stack = append(stack, path)
+
+	d.setFile(n, path)
+
+	refs, err := popExtends(n)
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, ref := range refs {
+		fragPath := ref
+		if !filepath.IsAbs(fragPath) {
+			fragPath = filepath.Join(filepath.Dir(path), ref)
+		}
+		buf, err := ioutil.ReadFile(fragPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: reading fragment %s", path, ref)
+		}
+		fragDoc, err := parseDefNode(GetDefFormat(fragPath, yamlFragmentExtensions, jsonFragmentExtensions), buf)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: reading fragment %s", path, ref)
+		}
+		d.fragments[fragPath] = buf
+		frag, err := d.resolve(fragPath, fragDoc.Content[0], stack)
+		if err != nil {
+			return nil, err
+		}
+		d.mergeNode(merged, frag)
+	}
+	d.mergeNode(merged, n)
+	return merged, nil
+}
+
+// popExtends removes the extends field from n and returns the fragments
+// that it lists.
+func popExtends(n *yaml.Node) ([]string, error) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value != extendsKey {
+			continue
+		}
+		value := n.Content[i+1]
+		n.Content = append(n.Content[:i:i], n.Content[i+2:]...)
+
+		switch value.Kind {
+		case yaml.ScalarNode:
+			if value.Tag == "!!str" {
+				return []string{value.Value}, nil
+			}
+		case yaml.SequenceNode:
+			refs := make([]string, 0, len(value.Content))
+			for _, item := range value.Content {
+				if item.Kind != yaml.ScalarNode || item.Tag != "!!str" {
+					return nil, errors.Errorf("%s: expected a path to a fragment", extendsKey)
+				}
+				refs = append(refs, item.Value)
+			}
+			return refs, nil
+		}
+		return nil, errors.Errorf("%s: expected a path or a list of paths to fragments", extendsKey)
+	}
+	return nil, nil
+}
+
+// mergeNode merges the mapping src into the mapping dst. Fields of src
+// override those of dst, except that objects are merged field by field.
+func (d *ExtendedDefinition) mergeNode(dst, src *yaml.Node) {
+	// An object is attributed to the last file that sets any of its fields.
+	d.files[dst] = d.files[src]
+	dst.Line, dst.Column = src.Line, src.Column
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		j := mappingIndex(dst, key.Value)
+		switch {
+		case j < 0:
+			dst.Content = append(dst.Content, key, value)
+		case dst.Content[j+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			d.mergeNode(dst.Content[j+1], value)
+		default:
+			dst.Content[j], dst.Content[j+1] = key, value
+		}
+	}
+}
+
+func mappingIndex(n *yaml.Node, key string) int {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d *ExtendedDefinition) setFile(n *yaml.Node, path string) {
+	d.files[n] = path
+	for _, child := range n.Content {
+		d.setFile(child, path)
+	}
+}
+
+// FieldErrors returns the errors of an ErrSchemaValidation or ErrValidation
+// for the merged definition, with the positions of the fields in the files
+// that they were read from. Errors for fields that were read from a fragment
+// name the fragment in their File.
+func (d ExtendedDefinition) FieldErrors(err error) []FieldError {
+	switch err := err.(type) {
+	case ErrSchemaValidation:
+		if d.doc == nil {
+			return err.FieldErrors(d.format, d.source)
+		}
+		errs := make([]FieldError, 0, len(err.Errors))
+		for _, verr := range err.Errors {
+			ferr := FieldError{Field: verr.Field(), Message: verr.Description()}
+			d.locate(&ferr, schemaErrorPointer(verr), verr.Type() != "required")
+			errs = append(errs, ferr)
+		}
+		return errs
+	case ErrValidation:
+		if d.doc == nil {
+			return err.WithPositions(d.format, d.source).FieldErrors()
+		}
+		errs := err.FieldErrors()
+		for i, e := range err.Errors {
+			errs[i].Line, errs[i].Column = 0, 0
+			d.locate(&errs[i], e.Pointer, false)
+		}
+		return errs
+	}
+	return nil
+}
+
+// locate sets the position of ferr to the node at pointer, or to its key if
+// key is true, and its File to the fragment that the node was read from.
+func (d ExtendedDefinition) locate(ferr *FieldError, pointer string, key bool) {
+	var node *yaml.Node
+	if key {
+		node = lookupKey(d.doc, pointer)
+	} else {
+		node = lookupPointer(d.doc, pointer)
+	}
+	if node == nil {
+		return
+	}
+	ferr.Line, ferr.Column = node.Line, node.Column
+	if file := d.files[node]; file != d.path {
+		ferr.File = file
+	}
+}
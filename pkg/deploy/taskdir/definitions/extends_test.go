@@ -0,0 +1,89 @@
+package definitions
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/airplanedev/lib/pkg/utils/pointers"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func readExtended(t *testing.T, path string) (ExtendedDefinition, error) {
+	buf, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return ExtendDefinition(path, GetTaskDefFormat(path), buf)
+}
+
+func TestExtendDefinition(t *testing.T) {
+	require := require.New(t)
+
+	extended, err := readExtended(t, "fixtures/extends/extends.task.yaml")
+	require.NoError(err)
+	require.Equal(DefFormatYAML, extended.Format)
+
+	def, err := UnmarshalDefinition(extended.Format, extended.Buf)
+	require.NoError(err)
+	d := def.(*Definition_0_3)
+	require.Equal("my_task", d.Slug)
+	require.Equal(600, d.Timeout.Value())
+	require.Equal(map[string]string{
+		"aws-region": "us-west-2",
+		"team":       "payments",
+	}, d.Constraints)
+	require.Equal("my_task.ts", d.Node.Entrypoint)
+	require.Equal("16", d.Node.NodeVersion)
+	require.Equal(api.TaskEnv{
+		"LOG_LEVEL":    api.EnvVarValue{Value: pointers.String("debug")},
+		"DATABASE_URL": api.EnvVarValue{Config: pointers.String("database_url")},
+	}, d.Node.EnvVars)
+}
+
+func TestExtendDefinitionWithoutExtends(t *testing.T) {
+	require := require.New(t)
+
+	buf, err := ioutil.ReadFile("fixtures/node.task.yaml")
+	require.NoError(err)
+	extended, err := ExtendDefinition("fixtures/node.task.yaml", DefFormatYAML, buf)
+	require.NoError(err)
+	require.Equal(DefFormatYAML, extended.Format)
+	require.Equal(buf, extended.Buf)
+}
+
+func TestExtendDefinitionCycle(t *testing.T) {
+	_, err := readExtended(t, "fixtures/extends/cycle.task.yaml")
+	require.EqualError(t, err, "cycle in extends: fixtures/extends/shared/cycle_a.yaml -> fixtures/extends/shared/cycle_b.yaml -> fixtures/extends/shared/cycle_a.yaml")
+}
+
+func TestExtendDefinitionMissingFragment(t *testing.T) {
+	_, err := ExtendDefinition("fixtures/extends/missing.task.yaml", DefFormatYAML, []byte("extends: shared/missing.yaml\nslug: my_task\n"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "fixtures/extends/missing.task.yaml: reading fragment shared/missing.yaml")
+}
+
+func TestExtendDefinitionErrors(t *testing.T) {
+	require := require.New(t)
+
+	extended, err := readExtended(t, "fixtures/extends/bad.task.yaml")
+	require.NoError(err)
+
+	_, err = UnmarshalDefinition(extended.Format, extended.Buf)
+	var serr ErrSchemaValidation
+	require.True(errors.As(err, &serr))
+	require.Contains(extended.FieldErrors(serr), FieldError{
+		Field:   "timeout",
+		Message: "Invalid type. Expected: number, given: string",
+		Line:    2,
+		Column:  3,
+		File:    "fixtures/extends/shared/bad.json",
+	})
+
+	err = NewErrReadDefinitionWithFragments("Error reading bad.task.yaml", "fixtures/extends/bad.task.yaml", extended, extended.FieldErrors(serr)...)
+	var explained interface{ ExplainError() string }
+	require.True(errors.As(err, &explained))
+	require.Contains(explained.ExplainError(), `fixtures/extends/shared/bad.json:2:3: timeout: Invalid type. Expected: number, given: string
+  1 | {
+> 2 |   "timeout": "soon"
+    |   ^`)
+}
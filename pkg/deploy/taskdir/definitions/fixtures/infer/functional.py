@@ -0,0 +1,24 @@
+# Linked to https://app.airplane.dev/t/python_keywords [do not edit this line]
+
+from typing import Optional, TypedDict
+
+
+Params = TypedDict(
+    "Params",
+    {
+        "from": str,
+        "2fa_code": Optional[str],
+    },
+)
+
+
+# Put the main logic of the task in the main function.
+def main(params: Params):
+    print("parameters:", params)
+
+    # You can return data to show outputs to users.
+    # Outputs documentation: https://docs.airplane.dev/tasks/outputs
+    return [
+        {"element": "hydrogen", "weight": 1.008},
+        {"element": "helium", "weight": 4.0026}
+    ]
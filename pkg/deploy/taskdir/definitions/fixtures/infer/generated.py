@@ -0,0 +1,32 @@
+# Linked to https://app.airplane.dev/t/generate [do not edit this line]
+
+from typing import Any, Dict, List, Optional, TypedDict
+
+
+class Params(TypedDict):
+    name: str
+    dry_run: bool
+    report: Optional[str]
+    count: int
+    ratio: Optional[float]
+    day: str
+    started_at: str
+    db: Dict[str, str]
+    payload: Any
+    tags: List[str]
+    options: Optional[Dict[str, Any]]
+
+
+# Put the main logic of the task in the main function.
+def main(params: Params):
+    # params["payload"] is already decoded from JSON into a Python value.
+    # params["tags"] is already decoded from JSON into a list of strings.
+    # params["options"] is already decoded from JSON into a dict.
+    print("parameters:", params)
+
+    # You can return data to show outputs to users.
+    # Outputs documentation: https://docs.airplane.dev/tasks/outputs
+    return [
+        {"element": "hydrogen", "weight": 1.008},
+        {"element": "helium", "weight": 4.0026}
+    ]
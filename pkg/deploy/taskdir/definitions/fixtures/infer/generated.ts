@@ -0,0 +1,27 @@
+// Linked to https://app.airplane.dev/t/generate [do not edit this line]
+
+type Params = {
+  name: string
+  dry_run: boolean
+  report?: string
+  count: number
+  ratio?: number
+  day: string
+  started_at: string
+  db: { name: string; value: string }
+  payload: unknown
+  tags: string[]
+  options?: Record<string, unknown>
+}
+
+// Put the main logic of the task in this function.
+export default async function(params: Params) {
+  console.log('parameters:', params);
+
+  // You can return data to show outputs to users.
+  // Outputs documentation: https://docs.airplane.dev/tasks/outputs
+  return [
+    {element: 'hydrogen', weight: 1.008},
+    {element: 'helium', weight: 4.0026},
+  ];
+}
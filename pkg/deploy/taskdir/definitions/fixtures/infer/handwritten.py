@@ -0,0 +1,17 @@
+import datetime
+from typing import List, Literal, Optional, TypedDict
+
+from report import send_report
+
+
+class ReportParams(TypedDict, total=False):
+    """Parameters of the task."""
+
+    # The team to send the report to.
+    team: Literal["sales", "support"]
+    since: datetime.date
+    recipients: List[str]  # e.g. ["a@example.com"]
+
+
+def main(params: ReportParams):
+    return send_report(params)
@@ -0,0 +1,22 @@
+// Linked to https://app.airplane.dev/t/send_report [do not edit this line]
+
+import { sendReport } from "./report";
+
+/**
+ * Parameters of the task.
+ */
+interface ReportParams {
+  // The team to send the report to.
+  team: "sales" | "support"
+  /* Recipients, e.g. "a@example.com". */
+  recipients: Array<string>;
+  "dry_run"?: boolean;
+  limit: number | undefined,
+  filters: {
+    [key: string]: string
+  }
+}
+
+export default async function sendReportTask(params: ReportParams) {
+  return sendReport(params);
+}
@@ -0,0 +1,9 @@
+from typing import TypedDict
+
+
+class Params(TypedDict):
+    size: complex
+
+
+def main(params: Params):
+    pass
@@ -0,0 +1,3 @@
+export default async function(params) {
+  console.log(params);
+}
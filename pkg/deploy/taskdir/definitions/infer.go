@@ -0,0 +1,495 @@
+package definitions
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/airplanedev/lib/pkg/build"
+	"github.com/airplanedev/lib/pkg/runtime"
+	"github.com/pkg/errors"
+)
+
+// NewDefinitionFromCode infers a task definition from the code of a script
+// task, so that tasks linked to scripts can be migrated to task definition
+// files.
+//
+// Parameters are inferred from the type of the params argument of the task:
+//
+//   - for TypeScript, the type or interface of the params argument of the
+//     default exported function
+//   - for Python, the TypedDict of the params argument of `main`
+//
+// The slug is read from the comment that links the script to a task, and
+// falls back to the file name. The entrypoint is the base name of path, so
+// the definition is expected to be written next to the script.
+func NewDefinitionFromCode(path string) (Definition_0_3, error) {
+	code, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Definition_0_3{}, errors.Wrap(err, "reading task code")
+	}
+
+	var kind build.TaskKind
+	var infer func(code string) ([]ParameterDefinition_0_3, error)
+	switch ext := filepath.Ext(path); ext {
+	case ".ts", ".tsx":
+		kind, infer = build.TaskKindNode, inferTypeScriptParameters
+	case ".py":
+		kind, infer = build.TaskKindPython, inferPythonParameters
+	default:
+		return Definition_0_3{}, errors.Errorf("cannot infer task definitions from %s files", ext)
+	}
+
+	slug := runtime.Slug(path)
+	if slug == "" {
+		slug = slugFromFilename(path)
+	}
+	def, err := NewDefinition_0_3(nameFromSlug(slug), slug, kind, filepath.Base(path))
+	if err != nil {
+		return Definition_0_3{}, err
+	}
+
+	def.Parameters, err = infer(string(code))
+	if err != nil {
+		return Definition_0_3{}, errors.Wrapf(err, "inferring parameters of %s", path)
+	}
+	return def, nil
+}
+
+var (
+	// tsParamsArg matches the start of the type of the params argument of
+	// the default exported function.
+	tsParamsArg  = regexp.MustCompile(`export\s+default\s+(?:async\s+)?(?:function\s*[\w$]*\s*)?\(\s*[\w$]+\s*:\s*`)
+	tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][\w$]*`)
+	tsMember     = regexp.MustCompile(`(?s)^(?:readonly\s+)?("(?:[^"\\]|\\.)*"|'[^']*'|[A-Za-z_$][\w$]*)(\?)?\s*:\s*(.+)$`)
+)
+
+// inferTypeScriptParameters infers parameters from TypeScript code.
+func inferTypeScriptParameters(code string) ([]ParameterDefinition_0_3, error) {
+	code = stripComments(code, "//", true)
+
+	loc := tsParamsArg.FindStringIndex(code)
+	if loc == nil {
+		// The task doesn't take parameters, or they're untyped.
+		return nil, nil
+	}
+
+	var body string
+	rest := code[loc[1]:]
+	if strings.HasPrefix(rest, "{") {
+		body = matchBrackets(rest)
+	} else {
+		name := tsIdentifier.FindString(rest)
+		if name == "" {
+			return nil, errors.New("unsupported type of the params argument")
+		}
+		decl := regexp.MustCompile(`(?:type\s+` + regexp.QuoteMeta(name) + `\s*=\s*|interface\s+` + regexp.QuoteMeta(name) + `\s*)\{`)
+		loc := decl.FindStringIndex(code)
+		if loc == nil {
+			return nil, errors.Errorf("could not find the declaration of type %s", name)
+		}
+		body = matchBrackets(code[loc[1]-1:])
+	}
+	if body == "" {
+		return nil, errors.New("unterminated type of the params argument")
+	}
+
+	var params []ParameterDefinition_0_3
+	for _, member := range splitTopLevel(body[1:len(body)-1], ";,\n") {
+		m := tsMember.FindStringSubmatch(member)
+		if m == nil {
+			return nil, errors.Errorf("unsupported member %q of the params type", member)
+		}
+		slug := m[1]
+		if s, err := strconv.Unquote(slug); err == nil {
+			slug = s
+		} else if strings.HasPrefix(slug, "'") {
+			slug = strings.Trim(slug, "'")
+		}
+
+		typ, options, optional, err := typeScriptParamType(m[3])
+		if err != nil {
+			return nil, errors.Wrapf(err, "parameter %s", slug)
+		}
+		params = append(params, newInferredParameter(slug, typ, options, optional || m[2] == "?"))
+	}
+	return params, nil
+}
+
+// typeScriptParamType converts a TypeScript type to a parameter type.
+func typeScriptParamType(t string) (typ string, options []string, optional bool, err error) {
+	var types []string
+	for _, part := range splitTopLevel(t, "|") {
+		switch part {
+		case "undefined", "null":
+			optional = true
+		default:
+			types = append(types, part)
+		}
+	}
+
+	if options, ok := stringLiterals(types); ok {
+		return "shorttext", options, optional, nil
+	}
+	if len(types) == 2 && (types[0] == "true" && types[1] == "false" || types[0] == "false" && types[1] == "true") {
+		return "boolean", nil, optional, nil
+	}
+	if len(types) != 1 {
+		return "", nil, false, errors.Errorf("unsupported type %q", t)
+	}
+
+	t = types[0]
+	compact := strings.Join(strings.Fields(t), "")
+	switch {
+	case t == "string":
+		typ = "shorttext"
+	case t == "number":
+		// Integers and floats can't be told apart.
+		typ = "float"
+	case t == "boolean":
+		typ = "boolean"
+	case compact == "string[]" || compact == "Array<string>":
+		typ = "list"
+	case compact == "{name:string;value:string}" || compact == "{name:string,value:string}":
+		typ = "configvar"
+	case t == "object" || strings.HasPrefix(compact, "Record<string,") || strings.HasPrefix(compact, "{[key:string]:"):
+		typ = "object"
+	case t == "unknown" || t == "any":
+		typ = "json"
+	default:
+		return "", nil, false, errors.Errorf("unsupported type %q", t)
+	}
+	return typ, nil, optional, nil
+}
+
+var (
+	pyMain       = regexp.MustCompile(`(?m)^(?:async\s+)?def\s+main\s*\(\s*\w+\s*:\s*([A-Za-z_][\w.]*)`)
+	pyMember     = regexp.MustCompile(`^\s+([A-Za-z_]\w*)\s*:\s*(.+?)\s*$`)
+	pyTotalFalse = regexp.MustCompile(`total\s*=\s*False`)
+)
+
+// inferPythonParameters infers parameters from Python code.
+func inferPythonParameters(code string) ([]ParameterDefinition_0_3, error) {
+	code = stripComments(code, "#", false)
+
+	m := pyMain.FindStringSubmatch(code)
+	if m == nil {
+		// The task doesn't take parameters, or they're untyped.
+		return nil, nil
+	}
+	name := m[1]
+
+	type member struct {
+		slug, typ string
+	}
+	var members []member
+	var total bool
+
+	class := regexp.MustCompile(`(?m)^class\s+` + regexp.QuoteMeta(name) + `\s*\(([^)]*)\)\s*:[ \t]*\n`)
+	functional := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(name) + `\s*=\s*TypedDict\(\s*["']` + regexp.QuoteMeta(name) + `["']\s*,\s*\{`)
+	if loc := class.FindStringSubmatchIndex(code); loc != nil {
+		bases := code[loc[2]:loc[3]]
+		if !strings.Contains(bases, "TypedDict") {
+			return nil, errors.Errorf("type %s of params must be a TypedDict", name)
+		}
+		total = !pyTotalFalse.MatchString(bases)
+
+		for _, line := range strings.Split(code[loc[1]:], "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if line[0] != ' ' && line[0] != '\t' {
+				// The end of the class body.
+				break
+			}
+			if m := pyMember.FindStringSubmatch(line); m != nil {
+				members = append(members, member{m[1], m[2]})
+			}
+		}
+	} else if loc := functional.FindStringIndex(code); loc != nil {
+		body := matchBrackets(code[loc[1]-1:])
+		if body == "" {
+			return nil, errors.Errorf("unterminated declaration of %s", name)
+		}
+		rest := code[loc[1]-1+len(body):]
+		if end := strings.Index(rest, ")"); end >= 0 {
+			total = !pyTotalFalse.MatchString(rest[:end])
+		}
+
+		for _, entry := range splitTopLevel(body[1:len(body)-1], ",") {
+			key, typ, ok := cutTopLevel(entry, ':')
+			slug, err := strconv.Unquote(strings.Replace(key, "'", `"`, 2))
+			if !ok || err != nil {
+				return nil, errors.Errorf("unsupported entry %q of %s", entry, name)
+			}
+			members = append(members, member{slug, typ})
+		}
+	} else {
+		return nil, errors.Errorf("could not find the declaration of TypedDict %s", name)
+	}
+
+	var params []ParameterDefinition_0_3
+	for _, m := range members {
+		typ, options, optional, err := pythonParamType(m.typ)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parameter %s", m.slug)
+		}
+		params = append(params, newInferredParameter(m.slug, typ, options, optional || !total))
+	}
+	return params, nil
+}
+
+// pythonParamType converts a Python type annotation to a parameter type.
+func pythonParamType(t string) (typ string, options []string, optional bool, err error) {
+	t = strings.ReplaceAll(strings.TrimSpace(t), "typing.", "")
+
+	var types []string
+	for _, part := range splitTopLevel(t, "|") {
+		if part == "None" {
+			optional = true
+		} else {
+			types = append(types, part)
+		}
+	}
+	if len(types) != 1 {
+		return "", nil, false, errors.Errorf("unsupported type %q", t)
+	}
+	t = types[0]
+
+	if inner, ok := pythonGeneric(t, "Optional", "NotRequired"); ok {
+		typ, options, _, err := pythonParamType(inner)
+		return typ, options, true, err
+	}
+	if inner, ok := pythonGeneric(t, "Union"); ok {
+		return pythonParamType(strings.Join(splitTopLevel(inner, ","), " | "))
+	}
+	if inner, ok := pythonGeneric(t, "Literal"); ok {
+		if options, ok := stringLiterals(splitTopLevel(inner, ",")); ok {
+			return "shorttext", options, optional, nil
+		}
+		return "", nil, false, errors.Errorf("unsupported type %q", t)
+	}
+
+	compact := strings.Join(strings.Fields(t), "")
+	switch compact {
+	case "str":
+		typ = "shorttext"
+	case "int":
+		typ = "integer"
+	case "float":
+		typ = "float"
+	case "bool":
+		typ = "boolean"
+	case "date", "datetime.date":
+		typ = "date"
+	case "datetime", "datetime.datetime":
+		typ = "datetime"
+	case "List[str]", "list[str]":
+		typ = "list"
+	case "Dict[str,str]", "dict[str,str]":
+		typ = "configvar"
+	case "Dict[str,Any]", "dict[str,Any]", "Dict", "dict":
+		typ = "object"
+	case "Any":
+		typ = "json"
+	default:
+		return "", nil, false, errors.Errorf("unsupported type %q", t)
+	}
+	return typ, nil, optional, nil
+}
+
+// pythonGeneric returns the type argument of t if it is one of the given
+// generic types, e.g. `int` for `Optional[int]`.
+func pythonGeneric(t string, names ...string) (string, bool) {
+	for _, name := range names {
+		if strings.HasPrefix(t, name+"[") && strings.HasSuffix(t, "]") {
+			return strings.TrimSpace(t[len(name)+1 : len(t)-1]), true
+		}
+	}
+	return "", false
+}
+
+func newInferredParameter(slug, typ string, options []string, optional bool) ParameterDefinition_0_3 {
+	p := ParameterDefinition_0_3{
+		Name: nameFromSlug(slug),
+		Slug: slug,
+		Type: typ,
+	}
+	if optional {
+		p.Required = NewDefaultTrueDefinition(false)
+	}
+	for _, o := range options {
+		p.Options = append(p.Options, OptionDefinition_0_3{Label: o, Value: o})
+	}
+	return p
+}
+
+// stringLiterals returns the values of types if they are all string
+// literals.
+func stringLiterals(types []string) ([]string, bool) {
+	if len(types) == 0 {
+		return nil, false
+	}
+	var values []string
+	for _, t := range types {
+		if len(t) < 2 || !(t[0] == '"' || t[0] == '\'') || t[len(t)-1] != t[0] {
+			return nil, false
+		}
+		values = append(values, t[1:len(t)-1])
+	}
+	return values, true
+}
+
+// stripComments removes comments from code, leaving string literals alone.
+// Block comments are `/* ... */`.
+func stripComments(code string, line string, block bool) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case quote != 0:
+			b.WriteByte(c)
+			if c == '\\' && i+1 < len(code) {
+				i++
+				b.WriteByte(code[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+			b.WriteByte(c)
+		case strings.HasPrefix(code[i:], line):
+			end := strings.IndexByte(code[i:], '\n')
+			if end < 0 {
+				return b.String()
+			}
+			i += end - 1
+		case block && strings.HasPrefix(code[i:], "/*"):
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 3
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// matchBrackets returns the prefix of s up to the bracket that closes the
+// bracket s starts with, or an empty string if it isn't closed.
+func matchBrackets(s string) string {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{' || c == '[' || c == '(' || c == '<':
+			depth++
+		case c == '}' || c == ']' || c == ')' || c == '>':
+			depth--
+			if depth == 0 {
+				return s[:i+1]
+			}
+		}
+	}
+	return ""
+}
+
+// splitTopLevel splits s at any of the separators that aren't nested in
+// brackets or string literals. Parts are trimmed, and empty parts are left
+// out.
+func splitTopLevel(s string, separators string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	add := func(part string) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{' || c == '[' || c == '(' || c == '<':
+			depth++
+		case c == '}' || c == ']' || c == ')' || c == '>':
+			depth--
+		case depth == 0 && strings.IndexByte(separators, c) >= 0:
+			add(s[start:i])
+			start = i + 1
+		}
+	}
+	add(s[start:])
+
+	// Union types may continue on the next line, e.g. `| "b"`.
+	var joined []string
+	for _, part := range parts {
+		if strings.HasPrefix(part, "|") && len(joined) > 0 && !strings.Contains(separators, "|") {
+			joined[len(joined)-1] += " " + part
+			continue
+		}
+		joined = append(joined, part)
+	}
+	return joined
+}
+
+// cutTopLevel is like strings.Cut, but ignores separators in string
+// literals.
+func cutTopLevel(s string, sep byte) (before, after string, found bool) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == sep:
+			return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), true
+		}
+	}
+	return s, "", false
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// slugFromFilename derives a slug from the name of a file, e.g. `my_task`
+// for `My-Task.ts`.
+func slugFromFilename(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// nameFromSlug derives a name from a slug, e.g. `Dry run` for `dry_run`.
+func nameFromSlug(slug string) string {
+	name := strings.TrimSpace(strings.ReplaceAll(slug, "_", " "))
+	if name == "" {
+		return slug
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,144 @@
+package definitions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDefinitionFromCode(t *testing.T) {
+	optional := NewDefaultTrueDefinition(false)
+	generated := []ParameterDefinition_0_3{
+		{Name: "Name", Slug: "name", Type: "shorttext"},
+		{Name: "Dry run", Slug: "dry_run", Type: "boolean"},
+		{Name: "Report", Slug: "report", Type: "shorttext", Required: optional},
+		{Name: "Count", Slug: "count", Type: "float"},
+		{Name: "Ratio", Slug: "ratio", Type: "float", Required: optional},
+		{Name: "Day", Slug: "day", Type: "shorttext"},
+		{Name: "Started at", Slug: "started_at", Type: "shorttext"},
+		{Name: "Db", Slug: "db", Type: "configvar"},
+		{Name: "Payload", Slug: "payload", Type: "json"},
+		{Name: "Tags", Slug: "tags", Type: "list"},
+		{Name: "Options", Slug: "options", Type: "object", Required: optional},
+	}
+
+	for _, test := range []struct {
+		name     string
+		path     string
+		expected Definition_0_3
+	}{
+		{
+			name: "generated typescript",
+			path: "fixtures/infer/generated.ts",
+			expected: Definition_0_3{
+				Name:       "Generate",
+				Slug:       "generate",
+				Node:       &NodeDefinition_0_3{Entrypoint: "generated.ts", NodeVersion: "16"},
+				Parameters: generated,
+			},
+		},
+		{
+			name: "generated python",
+			path: "fixtures/infer/generated.py",
+			expected: Definition_0_3{
+				Name:   "Generate",
+				Slug:   "generate",
+				Python: &PythonDefinition_0_3{Entrypoint: "generated.py"},
+				Parameters: func() []ParameterDefinition_0_3 {
+					params := make([]ParameterDefinition_0_3, len(generated))
+					copy(params, generated)
+					// Python tells integers from floats, but not dates from strings.
+					params[3].Type = "integer"
+					return params
+				}(),
+			},
+		},
+		{
+			name: "functional TypedDict",
+			path: "fixtures/infer/functional.py",
+			expected: Definition_0_3{
+				Name:   "Python keywords",
+				Slug:   "python_keywords",
+				Python: &PythonDefinition_0_3{Entrypoint: "functional.py"},
+				Parameters: []ParameterDefinition_0_3{
+					{Name: "From", Slug: "from", Type: "shorttext"},
+					{Name: "2fa code", Slug: "2fa_code", Type: "shorttext", Required: optional},
+				},
+			},
+		},
+		{
+			name: "handwritten typescript",
+			path: "fixtures/infer/handwritten.ts",
+			expected: Definition_0_3{
+				Name: "Send report",
+				Slug: "send_report",
+				Node: &NodeDefinition_0_3{Entrypoint: "handwritten.ts", NodeVersion: "16"},
+				Parameters: []ParameterDefinition_0_3{
+					{
+						Name: "Team",
+						Slug: "team",
+						Type: "shorttext",
+						Options: []OptionDefinition_0_3{
+							{Label: "sales", Value: "sales"},
+							{Label: "support", Value: "support"},
+						},
+					},
+					{Name: "Recipients", Slug: "recipients", Type: "list"},
+					{Name: "Dry run", Slug: "dry_run", Type: "boolean", Required: optional},
+					{Name: "Limit", Slug: "limit", Type: "float", Required: optional},
+					{Name: "Filters", Slug: "filters", Type: "object"},
+				},
+			},
+		},
+		{
+			name: "handwritten python",
+			path: "fixtures/infer/handwritten.py",
+			expected: Definition_0_3{
+				Name:   "Handwritten",
+				Slug:   "handwritten",
+				Python: &PythonDefinition_0_3{Entrypoint: "handwritten.py"},
+				Parameters: []ParameterDefinition_0_3{
+					{
+						Name:     "Team",
+						Slug:     "team",
+						Type:     "shorttext",
+						Required: optional,
+						Options: []OptionDefinition_0_3{
+							{Label: "sales", Value: "sales"},
+							{Label: "support", Value: "support"},
+						},
+					},
+					{Name: "Since", Slug: "since", Type: "date", Required: optional},
+					{Name: "Recipients", Slug: "recipients", Type: "list", Required: optional},
+				},
+			},
+		},
+		{
+			name: "untyped params",
+			path: "fixtures/infer/untyped.ts",
+			expected: Definition_0_3{
+				Name: "Untyped",
+				Slug: "untyped",
+				Node: &NodeDefinition_0_3{Entrypoint: "untyped.ts", NodeVersion: "16"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			def, err := NewDefinitionFromCode(test.path)
+			require.NoError(err)
+			require.Equal(test.expected, def)
+		})
+	}
+}
+
+func TestNewDefinitionFromCodeErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewDefinitionFromCode("fixtures/infer/unsupported.py")
+	require.EqualError(err, `inferring parameters of fixtures/infer/unsupported.py: parameter size: unsupported type "complex"`)
+
+	_, err = NewDefinitionFromCode("fixtures/my_task.sh")
+	require.EqualError(err, "cannot infer task definitions from .sh files")
+}
@@ -36,10 +36,16 @@ type DefinitionInterface interface {
 	GetName() string
 	GetRuntime() build.TaskRuntime
 	UpgradeJST() error
-	GetUpdateTaskRequest(ctx context.Context, client api.IAPIClient) (api.UpdateTaskRequest, error)
+	// GetUpdateTaskRequest returns the request that deploys this definition to the environment
+	// envSlug, with any overrides for that environment applied.
+	GetUpdateTaskRequest(ctx context.Context, client api.IAPIClient, envSlug string) (api.UpdateTaskRequest, error)
 	SetWorkdir(taskroot, workdir string) error
 
-	GetSchedules() map[string]api.Schedule
+	// GetSchedules returns the schedules that deploying this definition to the environment envSlug
+	// creates, keyed by slug, with any overrides for that environment applied.
+	GetSchedules(envSlug string) (map[string]api.Schedule, error)
+	// GetTriggers returns the webhook and event triggers of the definition, keyed by slug.
+	GetTriggers() map[string]api.TaskTrigger
 
 	// Entrypoint returns ErrNoEntrypoint if the task kind definition requires no entrypoint. May be
 	// empty. May be absolute or relative; if relative, it is relative to the defn file.
@@ -51,6 +57,13 @@ type DefinitionInterface interface {
 
 	// Marshal returns a serialized version of the definition in the given format.
 	Marshal(format DefFormat) ([]byte, error)
+
+	// Validate checks the definition for problems that the JSON schema does not catch. Returns
+	// an ErrValidation listing every problem.
+	Validate() error
+	// ValidateFiles checks the files that the definition refers to, once its path is set with
+	// SetDefnFilePath. Returns an ErrValidation listing every problem.
+	ValidateFiles() error
 }
 
 var ErrNoEntrypoint = errors.New("No entrypoint")
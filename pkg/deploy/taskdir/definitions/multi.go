@@ -0,0 +1,129 @@
+package definitions
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// tasksKey is the field of a definition file that lists several tasks.
+const tasksKey = "tasks"
+
+// defEntry is one of the task definitions in a file.
+type defEntry struct {
+	name string
+	node *yaml.Node
+}
+
+// splitDefNodes returns the top-level mappings of the task definitions in a
+// file that defines several tasks, either as YAML documents separated by
+// `---`:
+//
+//	slug: first_task
+//	...
+//	---
+//	slug: second_task
+//	...
+//
+// or as a list under a `tasks` field:
+//
+//	tasks:
+//	  - slug: first_task
+//	    ...
+//	  - slug: second_task
+//	    ...
+//
+// It returns nil if the file defines a single task.
+func splitDefNodes(format DefFormat, buf []byte) ([]defEntry, error) {
+	if format != DefFormatYAML && format != DefFormatJSON {
+		return nil, nil
+	}
+
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(buf))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "parsing task definition")
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+		docs = append(docs, &doc)
+	}
+
+	if len(docs) > 1 {
+		entries := make([]defEntry, len(docs))
+		for i, doc := range docs {
+			name := fmt.Sprintf("document %d", i+1)
+			if doc.Content[0].Kind != yaml.MappingNode {
+				return nil, errors.Errorf("%s: task definition must be an object", name)
+			}
+			entries[i] = defEntry{name: name, node: doc.Content[0]}
+		}
+		return entries, nil
+	}
+
+	if len(docs) == 0 || docs[0].Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	root := docs[0].Content[0]
+	i := mappingIndex(root, tasksKey)
+	if i < 0 {
+		return nil, nil
+	}
+	for j := 0; j+1 < len(root.Content); j += 2 {
+		if key := root.Content[j].Value; key != tasksKey {
+			return nil, errors.Errorf("%s: files that list tasks can't set other fields", key)
+		}
+	}
+	tasks := root.Content[i+1]
+	if tasks.Kind != yaml.SequenceNode || len(tasks.Content) == 0 {
+		return nil, errors.Errorf("%s: expected a list of task definitions", tasksKey)
+	}
+	entries := make([]defEntry, len(tasks.Content))
+	for j, task := range tasks.Content {
+		name := fmt.Sprintf("%s.%d", tasksKey, j)
+		if task.Kind != yaml.MappingNode {
+			return nil, errors.Errorf("%s: task definition must be an object", name)
+		}
+		entries[j] = defEntry{name: name, node: task}
+	}
+	return entries, nil
+}
+
+// SplitDefinitions returns the task definitions in the file at path, with
+// the fragments that they extend merged in. Files usually define a single
+// task, but can define several as YAML documents separated by `---` or as a
+// list under a `tasks` field.
+//
+// The Buf of each definition is ready for UnmarshalDefinition, and the
+// FieldErrors of each definition point at positions in the file.
+func SplitDefinitions(path string, format DefFormat, buf []byte) ([]ExtendedDefinition, error) {
+	entries, err := splitDefNodes(format, buf)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		d, err := ExtendDefinition(path, format, buf)
+		if err != nil {
+			return nil, err
+		}
+		return []ExtendedDefinition{d}, nil
+	}
+
+	defs := make([]ExtendedDefinition, len(entries))
+	for i, entry := range entries {
+		d := newExtendedDefinition(path, format, buf)
+		d.Entry = entry.name
+		if defs[i], err = d.extend(entry.node); err != nil {
+			return nil, errors.Wrap(err, entry.name)
+		}
+	}
+	return defs, nil
+}
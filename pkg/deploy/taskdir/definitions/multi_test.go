@@ -0,0 +1,92 @@
+package definitions
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitDefinitions(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		def     string
+		entries []string
+		slugs   []string
+		err     string
+	}{
+		{
+			name:    "single task",
+			def:     "slug: my_task\nname: My Task\nrest:\n  resource: api\n  method: GET\n  path: /\n  bodyType: json\n",
+			entries: []string{""},
+			slugs:   []string{"my_task"},
+		},
+		{
+			name:    "documents",
+			def:     "slug: first\nname: First\nrest:\n  resource: api\n  method: GET\n  path: /\n  bodyType: json\n---\nslug: second\nname: Second\nrest:\n  resource: api\n  method: POST\n  path: /\n  bodyType: json\n",
+			entries: []string{"document 1", "document 2"},
+			slugs:   []string{"first", "second"},
+		},
+		{
+			name:    "tasks list",
+			def:     "tasks:\n  - slug: first\n    name: First\n    rest:\n      resource: api\n      method: GET\n      path: /\n      bodyType: json\n  - slug: second\n    name: Second\n    rest:\n      resource: api\n      method: POST\n      path: /\n      bodyType: json\n",
+			entries: []string{"tasks.0", "tasks.1"},
+			slugs:   []string{"first", "second"},
+		},
+		{
+			name: "tasks list with other fields",
+			def:  "timeout: 60\ntasks:\n  - slug: first\n",
+			err:  "timeout: files that list tasks can't set other fields",
+		},
+		{
+			name: "empty tasks list",
+			def:  "tasks: []\n",
+			err:  "tasks: expected a list of task definitions",
+		},
+		{
+			name: "document that is not an object",
+			def:  "slug: first\n---\n- slug: second\n",
+			err:  "document 2: task definition must be an object",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			defs, err := SplitDefinitions("my_task.task.yaml", DefFormatYAML, []byte(test.def))
+			if test.err != "" {
+				require.EqualError(err, test.err)
+				return
+			}
+			require.NoError(err)
+
+			var entries, slugs []string
+			for _, d := range defs {
+				entries = append(entries, d.Entry)
+				def, err := UnmarshalDefinition(d.Format, d.Buf)
+				require.NoError(err)
+				slugs = append(slugs, def.GetSlug())
+			}
+			require.Equal(test.entries, entries)
+			require.Equal(test.slugs, slugs)
+		})
+	}
+}
+
+func TestSplitDefinitionsErrors(t *testing.T) {
+	require := require.New(t)
+
+	def := "tasks:\n  - slug: first\n    name: First\n    timeout: soon\n    rest:\n      resource: api\n      method: GET\n      path: /\n      bodyType: json\n"
+	defs, err := SplitDefinitions("my_task.task.yaml", DefFormatYAML, []byte(def))
+	require.NoError(err)
+	require.Len(defs, 1)
+
+	_, err = UnmarshalDefinition(defs[0].Format, defs[0].Buf)
+	var serr ErrSchemaValidation
+	require.True(errors.As(err, &serr))
+	require.Contains(defs[0].FieldErrors(serr), FieldError{
+		Field:   "timeout",
+		Message: "Invalid type. Expected: number, given: string",
+		Line:    4,
+		Column:  5,
+	})
+}
@@ -0,0 +1,30 @@
+package definitions
+
+import (
+	"github.com/airplanedev/lib/pkg/params"
+	"github.com/pkg/errors"
+)
+
+// ValidateParamValues checks values against the parameters of a task, e.g.
+// before running it locally. It returns an ErrValidation whose pointers refer
+// to values, e.g. `/count`.
+//
+// Parameters hidden by their visibleIf condition are not checked. Use
+// params.Coerce to convert values typed on the command line first.
+func ValidateParamValues(parameters []ParameterDefinition_0_3, values map[string]interface{}) error {
+	apiParams, err := ParametersToAPI(parameters)
+	if err != nil {
+		return err
+	}
+
+	err = params.Validate(apiParams, values)
+	var perr params.ErrInvalidValues
+	if !errors.As(err, &perr) {
+		return err
+	}
+	errs := make([]ValidationError, 0, len(perr.Errors))
+	for _, e := range perr.Errors {
+		errs = append(errs, ValidationError{Pointer: "/" + escapePointer(e.Param), Message: e.Message})
+	}
+	return ErrValidation{Errors: errs}
+}
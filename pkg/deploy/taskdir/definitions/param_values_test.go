@@ -0,0 +1,97 @@
+package definitions
+
+import (
+	"testing"
+
+	"github.com/airplanedev/lib/pkg/utils/pointers"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateParamValues(t *testing.T) {
+	params := []ParameterDefinition_0_3{
+		{Slug: "name", Type: "shorttext", MinLength: pointers.Int(2), MaxLength: pointers.Int(5), Regex: "^[a-z]+$"},
+		{Slug: "count", Type: "integer", Min: pointers.Float64(1), Max: pointers.Float64(10)},
+		{Slug: "day", Type: "date", MinDate: "2022-01-01", MaxDate: "2022-12-31", Required: NewDefaultTrueDefinition(false)},
+		{Slug: "mode", Type: "shorttext", Options: []OptionDefinition_0_3{{Value: "basic"}, {Value: "advanced"}}},
+		{Slug: "limit", Type: "integer", VisibleIf: &ParamConditionDefinition_0_3{Param: "mode", Equals: "advanced"}},
+		{Slug: "reason", Type: "longtext", RequiredIf: &ParamConditionDefinition_0_3{Param: "day"}},
+		{Slug: "colors", Type: "multiselect", Required: NewDefaultTrueDefinition(false), Options: []OptionDefinition_0_3{
+			{Value: "red"},
+			{Value: "blue"},
+		}},
+	}
+
+	for _, test := range []struct {
+		name     string
+		values   map[string]interface{}
+		expected []ValidationError
+	}{
+		{
+			name: "valid",
+			values: map[string]interface{}{
+				"name":   "abc",
+				"count":  float64(3),
+				"mode":   "basic",
+				"colors": []interface{}{"red", "blue"},
+			},
+		},
+		{
+			name: "conditions hold",
+			values: map[string]interface{}{
+				"name":  "abc",
+				"count": 3,
+				"mode":  "advanced",
+				"day":   "2022-06-01",
+			},
+			expected: []ValidationError{
+				{Pointer: "/limit", Message: "value is required"},
+				{Pointer: "/reason", Message: "value is required"},
+			},
+		},
+		{
+			name: "invalid",
+			values: map[string]interface{}{
+				"name":   "a",
+				"count":  float64(11),
+				"day":    "2021-12-31",
+				"mode":   "expert",
+				"reason": "because",
+				"colors": []interface{}{"green"},
+				"extra":  true,
+			},
+			expected: []ValidationError{
+				{Pointer: "/name", Message: "value must have at least 2 characters"},
+				{Pointer: "/count", Message: "value must be at most 10"},
+				{Pointer: "/day", Message: "value must not be before 2022-01-01"},
+				{Pointer: "/mode", Message: "value must be one of the options"},
+				{Pointer: "/colors", Message: "value must be one of the options"},
+				{Pointer: "/extra", Message: "unknown parameter"},
+			},
+		},
+		{
+			name: "missing",
+			values: map[string]interface{}{
+				"name":  "",
+				"count": "3",
+			},
+			expected: []ValidationError{
+				{Pointer: "/name", Message: "value is required"},
+				{Pointer: "/count", Message: "value must be an integer"},
+				{Pointer: "/mode", Message: "value is required"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+			err := ValidateParamValues(params, test.values)
+			if test.expected == nil {
+				require.NoError(err)
+				return
+			}
+			var verr ErrValidation
+			require.True(errors.As(err, &verr))
+			require.Equal(test.expected, verr.Errors)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package definitions
+
+import (
+	"sort"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/airplanedev/lib/pkg/utils/pointers"
+)
+
+// PermissionsDefinition_0_3 configures who can access a task. If a task
+// definition has no permissions, they are managed in the Airplane UI
+// instead.
+type PermissionsDefinition_0_3 struct {
+	// Roles grants roles on the task, e.g. task_viewer, to users and groups.
+	// If empty, everyone on the team can access the task according to their
+	// team role. If omitted, roles are managed in the Airplane UI.
+	Roles map[api.RoleID]PrincipalsDefinition_0_3 `json:"roles,omitempty"`
+	// Approvals configures who approves requests to run the task.
+	Approvals *ApprovalsDefinition_0_3 `json:"approvals,omitempty"`
+}
+
+// PrincipalsDefinition_0_3 lists users and groups by ID.
+type PrincipalsDefinition_0_3 struct {
+	Users  []string `json:"users,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+type ApprovalsDefinition_0_3 struct {
+	// Required is the number of approvals that requests need, default 1.
+	Required int `json:"required,omitempty"`
+	// Groups restricts approvals to members of these groups.
+	Groups []string `json:"groups,omitempty"`
+}
+
+// isTaskRole returns true if role can be granted on a task.
+func isTaskRole(role api.RoleID) bool {
+	for _, r := range api.TaskRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// addPermissionsToUpdateTaskRequest sets the permissions and approval rules
+// of req. Requests leave them unchanged if the definition has no
+// permissions, and leave the permissions unchanged if it has no roles.
+func (d *Definition_0_3) addPermissionsToUpdateTaskRequest(req *api.UpdateTaskRequest) {
+	if d.Permissions == nil {
+		return
+	}
+
+	if d.Permissions.Roles != nil {
+		d.addRolesToUpdateTaskRequest(req)
+	}
+
+	required, groups := 0, []string{}
+	if approvals := d.Permissions.Approvals; approvals != nil {
+		required = approvals.Required
+		if approvals.Groups != nil {
+			groups = approvals.Groups
+		}
+	}
+	req.ExecuteRules.RequiredApprovals = &required
+	req.ExecuteRules.ApproverGroupIDs = &groups
+}
+
+// addRolesToUpdateTaskRequest sets the permissions of req to the roles of
+// the definition. Tasks without roles are accessible to the whole team.
+func (d *Definition_0_3) addRolesToUpdateTaskRequest(req *api.UpdateTaskRequest) {
+	permissions := api.Permissions{}
+	roles := make([]string, 0, len(d.Permissions.Roles))
+	for role := range d.Permissions.Roles {
+		roles = append(roles, string(role))
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		principals := d.Permissions.Roles[api.RoleID(role)]
+		for _, user := range principals.Users {
+			permissions = append(permissions, api.Permission{
+				RoleID:    api.RoleID(role),
+				SubUserID: pointers.String(user),
+			})
+		}
+		for _, group := range principals.Groups {
+			permissions = append(permissions, api.Permission{
+				RoleID:     api.RoleID(role),
+				SubGroupID: pointers.String(group),
+			})
+		}
+	}
+	req.RequireExplicitPermissions = pointers.Bool(len(permissions) > 0)
+	req.Permissions = &permissions
+}
+
+// convertPermissionsFromTask sets the permissions of the definition if the
+// task has explicit permissions or approval rules. Permissions that grant
+// individual actions, rather than roles, can't be represented and are
+// skipped.
+func (d *Definition_0_3) convertPermissionsFromTask(t *api.Task) {
+	var perms PermissionsDefinition_0_3
+	if t.RequireExplicitPermissions {
+		for _, p := range t.Permissions {
+			if p.RoleID == "" {
+				continue
+			}
+			if perms.Roles == nil {
+				perms.Roles = map[api.RoleID]PrincipalsDefinition_0_3{}
+			}
+			principals := perms.Roles[p.RoleID]
+			if p.SubUserID != nil {
+				principals.Users = append(principals.Users, *p.SubUserID)
+			}
+			if p.SubGroupID != nil {
+				principals.Groups = append(principals.Groups, *p.SubGroupID)
+			}
+			perms.Roles[p.RoleID] = principals
+		}
+	}
+	if t.ExecuteRules.RequiredApprovals > 0 || len(t.ExecuteRules.ApproverGroupIDs) > 0 {
+		perms.Approvals = &ApprovalsDefinition_0_3{
+			Required: t.ExecuteRules.RequiredApprovals,
+			Groups:   t.ExecuteRules.ApproverGroupIDs,
+		}
+	}
+	if perms.Roles != nil || perms.Approvals != nil {
+		d.Permissions = &perms
+	}
+}
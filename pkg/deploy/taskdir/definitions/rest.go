@@ -0,0 +1,172 @@
+package definitions
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RESTBodyType is the encoding of the body of a REST request.
+type RESTBodyType string
+
+const (
+	// RESTBodyTypeJSON sends Body as JSON.
+	RESTBodyTypeJSON RESTBodyType = "json"
+	// RESTBodyTypeRaw sends Body, which must be a string, as is.
+	RESTBodyTypeRaw RESTBodyType = "raw"
+	// RESTBodyTypeFormData sends FormData as multipart/form-data.
+	RESTBodyTypeFormData RESTBodyType = "form-data"
+	// RESTBodyTypeFormURLEncoded sends FormData as
+	// application/x-www-form-urlencoded.
+	RESTBodyTypeFormURLEncoded RESTBodyType = "x-www-form-urlencoded"
+)
+
+// RESTBodyTypes are the supported body types.
+var RESTBodyTypes = []RESTBodyType{
+	RESTBodyTypeJSON,
+	RESTBodyTypeRaw,
+	RESTBodyTypeFormData,
+	RESTBodyTypeFormURLEncoded,
+}
+
+// IsForm returns true if requests with this body type send FormData rather
+// than Body.
+func (t RESTBodyType) IsForm() bool {
+	return t == RESTBodyTypeFormData || t == RESTBodyTypeFormURLEncoded
+}
+
+// GetURLParams returns the URL parameters of the request. Numbers and
+// booleans are formatted as JSON.
+func (d *RESTDefinition_0_3) GetURLParams() (map[string]string, error) {
+	return stringValues("urlParams", d.URLParams)
+}
+
+// GetHeaders returns the headers of the request. Numbers and booleans are
+// formatted as JSON.
+func (d *RESTDefinition_0_3) GetHeaders() (map[string]string, error) {
+	return stringValues("headers", d.Headers)
+}
+
+// GetFormData returns the form values of the request. Numbers and booleans
+// are formatted as JSON.
+func (d *RESTDefinition_0_3) GetFormData() (map[string]string, error) {
+	return stringValues("formData", d.FormData)
+}
+
+func stringValues(field string, m map[string]interface{}) (map[string]string, error) {
+	values := make(map[string]string, len(m))
+	for k, v := range m {
+		switch v := v.(type) {
+		case string:
+			values[k] = v
+		case bool, int, int64, uint64, float64, json.Number:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s.%s", field, k)
+			}
+			values[k] = string(b)
+		default:
+			return nil, errors.Errorf("%s.%s: expected a string, number or boolean, got %T instead", field, k, v)
+		}
+	}
+	return values, nil
+}
+
+// GetBody returns the body of the request, reading it from BodyFile if it
+// is set.
+func (d *RESTDefinition_0_3) GetBody() (interface{}, error) {
+	if d.BodyFile == "" {
+		return d.Body, nil
+	}
+	if !d.bodyFileLoaded {
+		if d.absoluteBodyFile == "" {
+			return nil, errors.Errorf("reading REST body file %s: definition path is unknown", d.BodyFile)
+		}
+		body, err := ioutil.ReadFile(d.absoluteBodyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading REST body file %s", d.BodyFile)
+		}
+		d.bodyFileContents = string(body)
+		d.bodyFileLoaded = true
+	}
+	return d.bodyFileContents, nil
+}
+
+// setDefnFilePath resolves BodyFile relative to the definition file.
+func (d *RESTDefinition_0_3) setDefnFilePath(filePath string) {
+	if d.BodyFile == "" {
+		return
+	}
+	if filepath.IsAbs(d.BodyFile) {
+		d.absoluteBodyFile = d.BodyFile
+	} else {
+		d.absoluteBodyFile = filepath.Join(filepath.Dir(filePath), d.BodyFile)
+	}
+	d.bodyFileContents = ""
+	d.bodyFileLoaded = false
+}
+
+// templateExprs returns the expressions of the JavaScript templates in s,
+// e.g. `params.id` for `/users/{{params.id}}`.
+func templateExprs(s string) ([]string, error) {
+	var exprs []string
+	for {
+		i := strings.Index(s, "{{")
+		if i < 0 {
+			return exprs, nil
+		}
+		s = s[i+2:]
+		j := strings.Index(s, "}}")
+		if j < 0 {
+			return nil, errors.New("template is missing a closing }}")
+		}
+		expr := strings.TrimSpace(s[:j])
+		if expr == "" {
+			return nil, errors.New("template is empty")
+		}
+		exprs = append(exprs, expr)
+		s = s[j+2:]
+	}
+}
+
+var templateParamRegex = regexp.MustCompile(`(?:^|[^\w$.])params\s*(?:\.\s*([A-Za-z_$][\w$]*)|\[\s*["']([^"']*)["']\s*\])`)
+
+// templateParams returns the slugs of the parameters that the templates in s
+// refer to, sorted and deduplicated.
+func templateParams(s string) ([]string, error) {
+	exprs, err := templateExprs(s)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var slugs []string
+	for _, expr := range exprs {
+		for _, m := range templateParamRegex.FindAllStringSubmatch(expr, -1) {
+			slug := m[1] + m[2]
+			if !seen[slug] {
+				seen[slug] = true
+				slugs = append(slugs, slug)
+			}
+		}
+	}
+	sort.Strings(slugs)
+	return slugs, nil
+}
+
+var templateRegex = regexp.MustCompile(`{{[\s\S]*?}}`)
+
+// checkJSONTemplate returns a message if s is not valid JSON once its
+// templates are evaluated. Templates are assumed to evaluate to valid JSON
+// values, e.g. `{"id": {{params.id}}}`.
+func checkJSONTemplate(s string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(templateRegex.ReplaceAllString(s, "0")), &v); err != nil {
+		return "must be valid JSON: " + err.Error()
+	}
+	return ""
+}
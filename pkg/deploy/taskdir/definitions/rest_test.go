@@ -0,0 +1,99 @@
+package definitions
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/airplanedev/lib/pkg/build"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateParams(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		s        string
+		expected []string
+		err      string
+	}{
+		{name: "no templates", s: "/users"},
+		{name: "dot access", s: "/users/{{params.id}}/{{ params.name }}", expected: []string{"id", "name"}},
+		{name: "index access", s: `{{params["first-name"]}} {{params['id']}}`, expected: []string{"first-name", "id"}},
+		{name: "expressions", s: "{{params.a + params.b}} {{params.a}}", expected: []string{"a", "b"}},
+		{name: "other globals", s: "{{session.params.id}} {{env.TOKEN}}"},
+		{name: "unterminated", s: "{{params.id", err: "template is missing a closing }}"},
+		{name: "empty", s: "{{ }}", err: "template is empty"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			slugs, err := templateParams(test.s)
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, slugs)
+		})
+	}
+}
+
+func TestRESTGetters(t *testing.T) {
+	require := require.New(t)
+
+	d := RESTDefinition_0_3{
+		URLParams: map[string]interface{}{"page": float64(3), "all": true},
+		Headers:   map[string]interface{}{"X-Api-Key": "{{params.key}}"},
+		FormData:  map[string]interface{}{"nested": map[string]interface{}{}},
+	}
+
+	urlParams, err := d.GetURLParams()
+	require.NoError(err)
+	require.Equal(map[string]string{"page": "3", "all": "true"}, urlParams)
+
+	headers, err := d.GetHeaders()
+	require.NoError(err)
+	require.Equal(map[string]string{"X-Api-Key": "{{params.key}}"}, headers)
+
+	_, err = d.GetFormData()
+	require.EqualError(err, "formData.nested: expected a string, number or boolean, got map[string]interface {} instead")
+}
+
+func TestRESTBodyFile(t *testing.T) {
+	require := require.New(t)
+
+	buf, err := ioutil.ReadFile("fixtures/rest_body/create_user.task.yaml")
+	require.NoError(err)
+	def, err := UnmarshalDefinition(DefFormatYAML, buf)
+	require.NoError(err)
+
+	_, _, err = def.GetKindAndOptions()
+	require.EqualError(err, "reading REST body file body.json: definition path is unknown")
+
+	def.SetDefnFilePath("fixtures/rest_body/create_user.task.yaml")
+	kind, options, err := def.GetKindAndOptions()
+	require.NoError(err)
+	require.Equal(build.TaskKindREST, kind)
+	require.Equal("{\"id\": {{params.id}}}\n", options["body"])
+	require.Equal("json", options["bodyType"])
+}
+
+func TestRESTValidateBodyFile(t *testing.T) {
+	require := require.New(t)
+
+	buf, err := ioutil.ReadFile("fixtures/rest_body/bad_body.task.yaml")
+	require.NoError(err)
+	def, err := UnmarshalDefinition(DefFormatYAML, buf)
+	require.NoError(err)
+
+	def.SetDefnFilePath("fixtures/rest_body/bad_body.task.yaml")
+	err = def.ValidateFiles()
+	require.Equal(ErrValidation{Errors: []ValidationError{
+		{Pointer: "/rest/bodyFile", Message: "body file must be valid JSON: invalid character '}' looking for beginning of object key string"},
+		{Pointer: "/rest/bodyFile", Message: `template refers to unknown parameter "name"`},
+	}}, err)
+
+	buf, err = ioutil.ReadFile("fixtures/rest_body/create_user.task.yaml")
+	require.NoError(err)
+	def, err = UnmarshalDefinition(DefFormatYAML, buf)
+	require.NoError(err)
+	def.SetDefnFilePath("fixtures/rest_body/create_user.task.yaml")
+	require.NoError(def.ValidateFiles())
+}
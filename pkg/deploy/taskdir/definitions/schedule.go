@@ -0,0 +1,165 @@
+package definitions
+
+import (
+	"time"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/airplanedev/lib/pkg/cron"
+	"github.com/pkg/errors"
+)
+
+// NewScheduleDefinitionFromTrigger_0_3 converts a schedule trigger to a
+// schedule definition. Disabled triggers are paused.
+func NewScheduleDefinitionFromTrigger_0_3(t api.Trigger) ScheduleDefinition_0_3 {
+	s := ScheduleDefinition_0_3{
+		Name:        t.Name,
+		Description: t.Description,
+		Paused:      t.DisabledAt != nil,
+	}
+	config := t.KindConfig.Schedule
+	if config == nil {
+		return s
+	}
+	s.CronExpr = config.CronExpr.String()
+	s.Timezone = config.Timezone
+	s.ParamValues = config.ParamValues
+
+	loc, err := s.Location()
+	if err != nil {
+		loc = time.UTC
+	}
+	if config.StartAt != nil {
+		s.StartDate = config.StartAt.In(loc).Format(time.RFC3339)
+	}
+	if config.EndAt != nil {
+		s.EndDate = config.EndAt.In(loc).Format(time.RFC3339)
+	}
+	return s
+}
+
+// TriggerKindConfig converts the schedule to the config of a schedule
+// trigger.
+func (s ScheduleDefinition_0_3) TriggerKindConfig() (api.TriggerKindConfigSchedule, error) {
+	sched, err := cron.Parse(s.CronExpr)
+	if err != nil {
+		return api.TriggerKindConfigSchedule{}, errors.Wrap(err, "parsing cron")
+	}
+	start, end, err := s.Bounds()
+	if err != nil {
+		return api.TriggerKindConfigSchedule{}, err
+	}
+	return api.TriggerKindConfigSchedule{
+		ParamValues: s.ParamValues,
+		CronExpr:    sched.CronExpr(),
+		Timezone:    s.Timezone,
+		StartAt:     start,
+		EndAt:       end,
+	}, nil
+}
+
+// apiSchedule converts the schedule to the format used to deploy it.
+// Schedules are validated before they are deployed, so invalid fields are
+// passed on as is, or left out.
+func (s ScheduleDefinition_0_3) apiSchedule() api.Schedule {
+	schedule := api.Schedule{
+		Name:        s.Name,
+		Description: s.Description,
+		CronExpr:    s.CronExpr,
+		Timezone:    s.Timezone,
+		Paused:      s.Paused,
+		ParamValues: s.ParamValues,
+	}
+	// Descriptors are expanded, since the API only accepts five fields.
+	if sched, err := cron.Parse(s.CronExpr); err == nil {
+		schedule.CronExpr = sched.String()
+	}
+	if start, end, err := s.Bounds(); err == nil {
+		schedule.StartAt, schedule.EndAt = start, end
+	}
+	return schedule
+}
+
+// Location returns the time zone of the schedule.
+func (s ScheduleDefinition_0_3) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, errors.Errorf("unknown timezone %q", s.Timezone)
+	}
+	return loc, nil
+}
+
+// Bounds returns the start and end dates of the schedule, if set. Dates
+// without a time are at midnight in the time zone of the schedule.
+func (s ScheduleDefinition_0_3) Bounds() (start, end *time.Time, err error) {
+	loc, err := s.Location()
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.StartDate != "" {
+		t, err := parseScheduleDate(s.StartDate, loc)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "invalid startDate")
+		}
+		start = &t
+	}
+	if s.EndDate != "" {
+		t, err := parseScheduleDate(s.EndDate, loc)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "invalid endDate")
+		}
+		end = &t
+	}
+	if start != nil && end != nil && !end.After(*start) {
+		return nil, nil, errors.New("endDate must be after startDate")
+	}
+	return start, end, nil
+}
+
+func parseScheduleDate(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.Errorf("%q is not a date, e.g. 2006-01-02, or an RFC 3339 datetime", s)
+	}
+	return t, nil
+}
+
+// NextRuns returns the next n times after the given time that the schedule
+// runs, in the time zone of the schedule. Paused schedules don't run.
+func (s ScheduleDefinition_0_3) NextRuns(after time.Time, n int) ([]time.Time, error) {
+	sched, err := cron.Parse(s.CronExpr)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing cron")
+	}
+	loc, err := s.Location()
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := s.Bounds()
+	if err != nil {
+		return nil, err
+	}
+	if s.Paused {
+		return nil, nil
+	}
+
+	t := after.In(loc)
+	if start != nil && t.Before(*start) {
+		// Include runs at the start date.
+		t = start.In(loc).Add(-time.Nanosecond)
+	}
+	var runs []time.Time
+	for len(runs) < n {
+		t = sched.Next(t)
+		if t.IsZero() || (end != nil && t.After(*end)) {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs, nil
+}
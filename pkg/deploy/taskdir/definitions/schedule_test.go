@@ -0,0 +1,108 @@
+package definitions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleNextRuns(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	after := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		name     string
+		schedule ScheduleDefinition_0_3
+		expected []time.Time
+	}{
+		{
+			name:     "utc",
+			schedule: ScheduleDefinition_0_3{CronExpr: "@daily"},
+			expected: []time.Time{
+				time.Date(2022, 3, 2, 0, 0, 0, 0, time.UTC),
+				time.Date(2022, 3, 3, 0, 0, 0, 0, time.UTC),
+				time.Date(2022, 3, 4, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "timezone",
+			schedule: ScheduleDefinition_0_3{CronExpr: "0 9 * * MON-FRI", Timezone: "America/New_York"},
+			expected: []time.Time{
+				time.Date(2022, 3, 1, 9, 0, 0, 0, ny),
+				time.Date(2022, 3, 2, 9, 0, 0, 0, ny),
+				time.Date(2022, 3, 3, 9, 0, 0, 0, ny),
+			},
+		},
+		{
+			name: "bounds",
+			schedule: ScheduleDefinition_0_3{
+				CronExpr:  "0 0 * * *",
+				Timezone:  "America/New_York",
+				StartDate: "2022-03-10",
+				EndDate:   "2022-03-11T00:00:00-05:00",
+			},
+			expected: []time.Time{
+				time.Date(2022, 3, 10, 0, 0, 0, 0, ny),
+				time.Date(2022, 3, 11, 0, 0, 0, 0, ny),
+			},
+		},
+		{
+			name:     "paused",
+			schedule: ScheduleDefinition_0_3{CronExpr: "@hourly", Paused: true},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+			runs, err := test.schedule.NextRuns(after, 3)
+			require.NoError(err)
+			require.Len(runs, len(test.expected))
+			for i := range runs {
+				require.True(test.expected[i].Equal(runs[i]), "expected %s, got %s", test.expected[i], runs[i])
+				require.Equal(test.expected[i].Location(), runs[i].Location())
+			}
+		})
+	}
+
+	_, err = ScheduleDefinition_0_3{CronExpr: "@often"}.NextRuns(after, 1)
+	require.EqualError(t, err, "parsing cron: unknown descriptor @often")
+}
+
+func TestScheduleTriggerRoundTrip(t *testing.T) {
+	require := require.New(t)
+
+	schedule := ScheduleDefinition_0_3{
+		Name:        "Weekly",
+		Description: "Runs weekly",
+		CronExpr:    "@weekly",
+		Timezone:    "Europe/Paris",
+		StartDate:   "2022-01-01",
+		ParamValues: map[string]interface{}{"count": float64(1)},
+	}
+	config, err := schedule.TriggerKindConfig()
+	require.NoError(err)
+	require.Equal(api.CronExpr{Minute: "0", Hour: "0", DayOfMonth: "*", Month: "*", DayOfWeek: "0"}, config.CronExpr)
+	require.Equal("Europe/Paris", config.Timezone)
+	require.Equal("2021-12-31T23:00:00Z", config.StartAt.UTC().Format(time.RFC3339))
+	require.Nil(config.EndAt)
+
+	now := time.Now()
+	actual := NewScheduleDefinitionFromTrigger_0_3(api.Trigger{
+		Name:        schedule.Name,
+		Description: schedule.Description,
+		Kind:        api.TriggerKindSchedule,
+		KindConfig:  api.TriggerKindConfig{Schedule: &config},
+		DisabledAt:  &now,
+	})
+	require.Equal(ScheduleDefinition_0_3{
+		Name:        "Weekly",
+		Description: "Runs weekly",
+		CronExpr:    "0 0 * * 0",
+		Timezone:    "Europe/Paris",
+		StartDate:   "2022-01-01T00:00:00+01:00",
+		Paused:      true,
+		ParamValues: map[string]interface{}{"count": float64(1)},
+	}, actual)
+}
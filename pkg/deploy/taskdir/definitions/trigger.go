@@ -0,0 +1,179 @@
+package definitions
+
+import (
+	"strings"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/airplanedev/path"
+	"github.com/pkg/errors"
+)
+
+// TriggerDefinition_0_3 is a trigger, other than a schedule, that is
+// deployed with a task. Exactly one of Webhook and Event is set.
+type TriggerDefinition_0_3 struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Paused triggers are deployed, but do not run the task.
+	Paused bool `json:"paused,omitempty"`
+
+	Webhook *WebhookTriggerDefinition_0_3 `json:"webhook,omitempty"`
+	Event   *EventTriggerDefinition_0_3   `json:"event,omitempty"`
+}
+
+// WebhookTriggerDefinition_0_3 runs a task when a request is sent to the URL
+// of the trigger.
+type WebhookTriggerDefinition_0_3 struct {
+	// ParamMappings maps parameter slugs to JSON paths into the body of the
+	// request, e.g. `$.pull_request.number`.
+	ParamMappings map[string]string `json:"paramMappings,omitempty"`
+}
+
+// EventTriggerDefinition_0_3 runs a task when an event is emitted.
+type EventTriggerDefinition_0_3 struct {
+	// Source is the integration that emits the event, e.g. `github`.
+	Source string `json:"source,omitempty"`
+	// Type is the type of event, e.g. `pull_request.opened`.
+	Type string `json:"type"`
+	// ParamMappings maps parameter slugs to JSON paths into the payload of
+	// the event.
+	ParamMappings map[string]string `json:"paramMappings,omitempty"`
+}
+
+// NewTriggerDefinitionFromTrigger_0_3 converts a webhook or event trigger to
+// a trigger definition. It returns false for other kinds of triggers.
+func NewTriggerDefinitionFromTrigger_0_3(t api.Trigger) (TriggerDefinition_0_3, bool) {
+	def := TriggerDefinition_0_3{
+		Name:        t.Name,
+		Description: t.Description,
+		Paused:      t.DisabledAt != nil,
+	}
+	if !def.setKindConfig(t.Kind, t.KindConfig) {
+		return TriggerDefinition_0_3{}, false
+	}
+	return def, true
+}
+
+func (t *TriggerDefinition_0_3) setKindConfig(kind api.TriggerKind, config api.TriggerKindConfig) bool {
+	switch {
+	case kind == api.TriggerKindWebhook && config.Webhook != nil:
+		t.Webhook = &WebhookTriggerDefinition_0_3{
+			ParamMappings: config.Webhook.ParamMappings,
+		}
+	case kind == api.TriggerKindEvent && config.Event != nil:
+		t.Event = &EventTriggerDefinition_0_3{
+			Source:        config.Event.Source,
+			Type:          config.Event.EventType,
+			ParamMappings: config.Event.ParamMappings,
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// Kind returns the kind of the trigger.
+func (t TriggerDefinition_0_3) Kind() api.TriggerKind {
+	switch {
+	case t.Webhook != nil:
+		return api.TriggerKindWebhook
+	case t.Event != nil:
+		return api.TriggerKindEvent
+	default:
+		return api.TriggerKindUnknown
+	}
+}
+
+// ParamMappings returns the JSON paths that parameters are read from.
+func (t TriggerDefinition_0_3) ParamMappings() map[string]string {
+	switch {
+	case t.Webhook != nil:
+		return t.Webhook.ParamMappings
+	case t.Event != nil:
+		return t.Event.ParamMappings
+	default:
+		return nil
+	}
+}
+
+// TriggerKindConfig converts the trigger to the config of an API trigger.
+func (t TriggerDefinition_0_3) TriggerKindConfig() api.TriggerKindConfig {
+	var config api.TriggerKindConfig
+	switch {
+	case t.Webhook != nil:
+		config.Webhook = &api.TriggerKindConfigWebhook{
+			ParamMappings: t.Webhook.ParamMappings,
+		}
+	case t.Event != nil:
+		config.Event = &api.TriggerKindConfigEvent{
+			Source:        t.Event.Source,
+			EventType:     t.Event.Type,
+			ParamMappings: t.Event.ParamMappings,
+		}
+	}
+	return config
+}
+
+func (t TriggerDefinition_0_3) apiTrigger() api.TaskTrigger {
+	return api.TaskTrigger{
+		Name:        t.Name,
+		Description: t.Description,
+		Kind:        t.Kind(),
+		KindConfig:  t.TriggerKindConfig(),
+		Paused:      t.Paused,
+	}
+}
+
+// ParamValues reads the values of parameters from the body of a webhook
+// request, or the payload of an event, using the param mappings of the
+// trigger. Paths that are missing from the payload are skipped.
+func (t TriggerDefinition_0_3) ParamValues(payload interface{}) (map[string]interface{}, error) {
+	mappings := t.ParamMappings()
+	values := make(map[string]interface{}, len(mappings))
+	for slug, jsonPath := range mappings {
+		p, err := parseJSONPath(jsonPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "param %s", slug)
+		}
+		if v, ok := lookupPath(payload, p); ok {
+			values[slug] = v
+		}
+	}
+	return values, nil
+}
+
+// parseJSONPath parses a JSON path such as `$.items[0]["first name"]`. The
+// leading `$` is optional.
+func parseJSONPath(s string) (path.P, error) {
+	js := strings.TrimPrefix(s, "$")
+	if js != s {
+		js = strings.TrimPrefix(js, ".")
+	}
+	p, err := path.FromJS(js)
+	if err != nil {
+		return path.P{}, errors.Errorf("invalid JSON path %q", s)
+	}
+	return p, nil
+}
+
+// lookupPath returns the value at p in v, which is decoded JSON.
+func lookupPath(v interface{}, p path.P) (interface{}, bool) {
+	for _, c := range p.Components() {
+		switch key := c.(type) {
+		case string:
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			if v, ok = obj[key]; !ok {
+				return nil, false
+			}
+		case int:
+			arr, ok := v.([]interface{})
+			if !ok || key >= len(arr) {
+				return nil, false
+			}
+			v = arr[key]
+		}
+	}
+	return v, true
+}
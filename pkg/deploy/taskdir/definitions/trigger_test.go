@@ -0,0 +1,46 @@
+package definitions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerParamValues(t *testing.T) {
+	require := require.New(t)
+
+	trigger := TriggerDefinition_0_3{
+		Webhook: &WebhookTriggerDefinition_0_3{
+			ParamMappings: map[string]string{
+				"branch":  "$.ref",
+				"author":  "$.commits[0].author.name",
+				"label":   `$["pull request"].labels[1]`,
+				"payload": "$",
+				"missing": "$.repository.owner",
+			},
+		},
+	}
+	payload := map[string]interface{}{
+		"ref": "main",
+		"commits": []interface{}{
+			map[string]interface{}{"author": map[string]interface{}{"name": "Ada"}},
+		},
+		"pull request": map[string]interface{}{
+			"labels": []interface{}{"bug", "urgent"},
+		},
+		"repository": "lib",
+	}
+
+	values, err := trigger.ParamValues(payload)
+	require.NoError(err)
+	require.Equal(map[string]interface{}{
+		"branch":  "main",
+		"author":  "Ada",
+		"label":   "urgent",
+		"payload": payload,
+	}, values)
+
+	trigger.Webhook.ParamMappings = map[string]string{"branch": "$..ref"}
+	_, err = trigger.ParamValues(payload)
+	require.EqualError(err, `param branch: invalid JSON path "$..ref"`)
+}
@@ -2,8 +2,18 @@ package definitions
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/airplanedev/lib/pkg/cron"
+	"github.com/airplanedev/lib/pkg/params"
+	"github.com/airplanedev/lib/pkg/utils/quantity"
 	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
 )
 
 type ErrSchemaValidation struct {
@@ -13,3 +23,773 @@ type ErrSchemaValidation struct {
 func (err ErrSchemaValidation) Error() string {
 	return fmt.Sprintf("invalid format: %v", err.Errors)
 }
+
+// FieldErrors returns the schema errors along with their positions in the
+// definition file that was validated. Errors point at the offending key, or
+// at the enclosing object if a required field is missing.
+func (err ErrSchemaValidation) FieldErrors(format DefFormat, buf []byte) []FieldError {
+	var doc *yaml.Node
+	var n yaml.Node
+	if format != DefFormatUnknown && yaml.Unmarshal(buf, &n) == nil {
+		doc = &n
+	}
+
+	errs := make([]FieldError, 0, len(err.Errors))
+	for _, verr := range err.Errors {
+		ferr := FieldError{Field: verr.Field(), Message: verr.Description()}
+		if doc != nil {
+			pointer := schemaErrorPointer(verr)
+			var node *yaml.Node
+			if verr.Type() == "required" {
+				node = lookupPointer(doc, pointer)
+			} else {
+				node = lookupKey(doc, pointer)
+			}
+			if node != nil {
+				ferr.Line, ferr.Column = node.Line, node.Column
+			}
+		}
+		errs = append(errs, ferr)
+	}
+	return errs
+}
+
+// schemaErrorPointer returns a JSON pointer to the value that verr refers
+// to. Unknown properties are pointed at directly, rather than at the object
+// that contains them.
+func schemaErrorPointer(verr gojsonschema.ResultError) string {
+	var tokens []string
+	if ctx := verr.Context(); ctx != nil {
+		// The context is a path of unescaped tokens, starting with `(root)`.
+		tokens = strings.Split(ctx.String("\x00"), "\x00")[1:]
+	}
+	if verr.Type() == "additional_property_not_allowed" {
+		if property, ok := verr.Details()["property"].(string); ok {
+			tokens = append(tokens, property)
+		}
+	}
+
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/" + escapePointer(token))
+	}
+	return b.String()
+}
+
+// ValidationError is a single problem found by Validate.
+type ValidationError struct {
+	// Pointer is a JSON pointer to the offending value, e.g.
+	// `/parameters/0/default`.
+	Pointer string
+	Message string
+	// Line and Column are the 1-based position of the offending value in
+	// the definition file. They are zero if the position is not known.
+	Line   int
+	Column int
+}
+
+func (e ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s: %s (line %d, column %d)", e.Pointer, e.Message, e.Line, e.Column)
+	}
+	return fmt.Sprintf("%s: %s", e.Pointer, e.Message)
+}
+
+// ErrValidation is returned when a definition matches the schema, but is
+// not valid otherwise.
+type ErrValidation struct {
+	Errors []ValidationError
+}
+
+func (err ErrValidation) Error() string {
+	msgs := make([]string, 0, len(err.Errors))
+	for _, e := range err.Errors {
+		msgs = append(msgs, e.Error())
+	}
+	return fmt.Sprintf("invalid definition: %s", strings.Join(msgs, "; "))
+}
+
+// WithPositions returns a copy of err with the line and column of each
+// error filled in from the definition file that it was read from.
+func (err ErrValidation) WithPositions(format DefFormat, buf []byte) ErrValidation {
+	var doc yaml.Node
+	if format == DefFormatUnknown || yaml.Unmarshal(buf, &doc) != nil {
+		return err
+	}
+	errs := make([]ValidationError, len(err.Errors))
+	for i, e := range err.Errors {
+		if n := lookupPointer(&doc, e.Pointer); n != nil {
+			e.Line, e.Column = n.Line, n.Column
+		}
+		errs[i] = e
+	}
+	return ErrValidation{Errors: errs}
+}
+
+// FieldErrors returns the validation errors as FieldErrors.
+func (err ErrValidation) FieldErrors() []FieldError {
+	errs := make([]FieldError, 0, len(err.Errors))
+	for _, e := range err.Errors {
+		errs = append(errs, FieldError{
+			Field:   pointerToField(e.Pointer),
+			Message: e.Message,
+			Line:    e.Line,
+			Column:  e.Column,
+		})
+	}
+	return errs
+}
+
+// Validate checks the parts of a definition that the JSON schema cannot,
+// returning an ErrValidation with every problem that was found.
+func (d Definition_0_3) Validate() error {
+	var errs []ValidationError
+	add := func(pointer, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+
+	params := map[string]ParameterDefinition_0_3{}
+	for i, p := range d.Parameters {
+		ptr := fmt.Sprintf("/parameters/%d", i)
+		if _, ok := params[p.Slug]; ok {
+			add(ptr+"/slug", "duplicate parameter slug %q", p.Slug)
+		}
+		params[p.Slug] = p
+
+		validateParamConstraints(ptr, p, add)
+		if p.Default != nil {
+			if msg := checkParamDefault(p, p.Default); msg != "" {
+				add(ptr+"/default", "default %s", msg)
+			}
+		}
+		if p.Type == "multiselect" && len(p.Options) == 0 {
+			add(ptr+"/type", "multiselect parameters must have options")
+		}
+		// Each option of a list is a single value of the list.
+		optType := p.Type
+		if optType == "list" || optType == "multiselect" {
+			optType = "shorttext"
+		}
+		for j, o := range p.Options {
+			optPtr := fmt.Sprintf("%s/options/%d", ptr, j)
+			switch {
+			case o.Config != nil && p.Type != "configvar":
+				add(optPtr+"/config", "config options are only supported by configvar parameters")
+			case o.Config == nil && o.Value == nil:
+				add(optPtr, "option must have a value")
+			case o.Config == nil:
+				if msg := checkParamValue(optType, o.Value); msg != "" {
+					// Options written as plain strings have no value field.
+					if _, isString := o.Value.(string); isString && o.Label == "" {
+						add(optPtr, "option %s", msg)
+					} else {
+						add(optPtr+"/value", "option %s", msg)
+					}
+				}
+			}
+		}
+		if p.Regex != "" {
+			if _, err := regexp.Compile(p.Regex); err != nil {
+				add(ptr+"/regex", "invalid regex: %s", strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+			}
+		}
+	}
+
+	// Conditions can refer to any parameter, including later ones.
+	for i, p := range d.Parameters {
+		ptr := fmt.Sprintf("/parameters/%d", i)
+		validateParamCondition(ptr+"/visibleIf", p.Slug, p.VisibleIf, params, add)
+		validateParamCondition(ptr+"/requiredIf", p.Slug, p.RequiredIf, params, add)
+		if p.RequiredIf != nil && p.Required.value != nil && *p.Required.value {
+			add(ptr+"/required", "required must not be true if requiredIf is set")
+		}
+	}
+
+	if env, err := d.GetEnv(); err == nil {
+		validateEnvVars(d.kindPointer()+"/envVars", env, add)
+	}
+	if d.Compute != nil && (d.SQL != nil || d.REST != nil) {
+		kind, _ := d.Kind()
+		add("/compute", "compute is not supported by %s tasks", kind)
+	}
+	validateCompute("/compute", d.Compute, add)
+	validateREST("/rest", d.REST, params, add)
+	validatePermissions("/permissions", d.Permissions, d.RequireRequests, add)
+	validateSchedules("/schedules", d.Schedules, params, add)
+	validateTriggers("/triggers", d.Triggers, d.Schedules, params, add)
+
+	envSlugs := make([]string, 0, len(d.Environments))
+	for slug := range d.Environments {
+		envSlugs = append(envSlugs, slug)
+	}
+	sort.Strings(envSlugs)
+	for _, slug := range envSlugs {
+		env := d.Environments[slug]
+		ptr := "/environments/" + escapePointer(slug)
+		if len(env.EnvVars) > 0 && (d.SQL != nil || d.REST != nil) {
+			kind, _ := d.Kind()
+			add(ptr+"/envVars", "env vars are not supported by %s tasks", kind)
+		}
+		validateEnvVars(ptr+"/envVars", env.EnvVars, add)
+		validateSchedules(ptr+"/schedules", env.Schedules, params, add)
+	}
+
+	if len(errs) > 0 {
+		return ErrValidation{Errors: errs}
+	}
+	return nil
+}
+
+// validateParamConstraints checks that the constraints of p, found at
+// pointer, apply to its type and are consistent with each other.
+func validateParamConstraints(pointer string, p ParameterDefinition_0_3, add func(pointer, format string, args ...interface{})) {
+	isNumber := p.Type == "integer" || p.Type == "float"
+	isLength := isTextParamType(p.Type) || p.Type == "list" || p.Type == "multiselect"
+	isDate := p.Type == "date" || p.Type == "datetime"
+
+	if p.Min != nil && !isNumber {
+		add(pointer+"/min", "min is only supported by integer and float parameters")
+	}
+	if p.Max != nil && !isNumber {
+		add(pointer+"/max", "max is only supported by integer and float parameters")
+	}
+	if p.Min != nil && p.Max != nil && *p.Max < *p.Min {
+		add(pointer+"/max", "max must not be less than min")
+	}
+
+	if p.MinLength != nil && !isLength {
+		add(pointer+"/minLength", "minLength is only supported by text and list parameters")
+	}
+	if p.MaxLength != nil && !isLength {
+		add(pointer+"/maxLength", "maxLength is only supported by text and list parameters")
+	}
+	if p.MinLength != nil && p.MaxLength != nil && *p.MaxLength < *p.MinLength {
+		add(pointer+"/maxLength", "maxLength must not be less than minLength")
+	}
+
+	var minDate, maxDate time.Time
+	for _, bound := range []struct {
+		field string
+		value string
+		t     *time.Time
+	}{
+		{"minDate", p.MinDate, &minDate},
+		{"maxDate", p.MaxDate, &maxDate},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		if !isDate {
+			add(pointer+"/"+bound.field, "%s is only supported by date and datetime parameters", bound.field)
+			continue
+		}
+		if msg := checkParamValue(p.Type, bound.value); msg != "" {
+			add(pointer+"/"+bound.field, "%s %s", bound.field, msg)
+			continue
+		}
+		typ, _, _ := paramTypeToAPI(p.Type)
+		*bound.t, _ = params.ParseTime(typ, bound.value)
+	}
+	if !minDate.IsZero() && !maxDate.IsZero() && maxDate.Before(minDate) {
+		add(pointer+"/maxDate", "maxDate must not be before minDate")
+	}
+}
+
+// validateParamCondition checks that cond, found at pointer, refers to
+// another parameter and that the value it compares against is valid.
+func validateParamCondition(
+	pointer string,
+	slug string,
+	cond *ParamConditionDefinition_0_3,
+	params map[string]ParameterDefinition_0_3,
+	add func(pointer, format string, args ...interface{}),
+) {
+	if cond == nil {
+		return
+	}
+	if cond.Param == slug {
+		add(pointer+"/param", "condition must refer to another parameter")
+		return
+	}
+	p, ok := params[cond.Param]
+	if !ok {
+		add(pointer+"/param", "unknown parameter %q", cond.Param)
+		return
+	}
+	if cond.Equals != nil {
+		if msg := checkParamValue(p.Type, cond.Equals); msg != "" {
+			add(pointer+"/equals", "equals %s", msg)
+		}
+	}
+}
+
+// validatePermissions checks that permissions, found at pointer, only grant
+// roles that apply to tasks, and that approvals are only configured for
+// tasks that require requests.
+func validatePermissions(
+	pointer string,
+	perms *PermissionsDefinition_0_3,
+	requireRequests bool,
+	add func(pointer, format string, args ...interface{}),
+) {
+	if perms == nil {
+		return
+	}
+
+	roles := make([]string, 0, len(perms.Roles))
+	for role := range perms.Roles {
+		roles = append(roles, string(role))
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		ptr := pointer + "/roles/" + escapePointer(role)
+		if !isTaskRole(api.RoleID(role)) {
+			add(ptr, "unknown role %q, expected one of %s", role, strings.Join(taskRoleNames(), ", "))
+			continue
+		}
+		principals := perms.Roles[api.RoleID(role)]
+		if len(principals.Users) == 0 && len(principals.Groups) == 0 {
+			add(ptr, "role must be granted to at least one user or group")
+		}
+	}
+
+	if perms.Approvals != nil && !requireRequests {
+		add(pointer+"/approvals", "approvals require requireRequests to be true")
+	}
+}
+
+func taskRoleNames() []string {
+	names := make([]string, 0, len(api.TaskRoles))
+	for _, r := range api.TaskRoles {
+		names = append(names, string(r))
+	}
+	return names
+}
+
+// validateEnvVars checks that each env var, found at pointer, sets exactly
+// one value or reference.
+func validateEnvVars(
+	pointer string,
+	env map[string]api.EnvVarValue,
+	add func(pointer, format string, args ...interface{}),
+) {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := env[name].Validate(); err != nil {
+			add(pointer+"/"+escapePointer(name), "%s", err)
+		}
+	}
+}
+
+// kindPointer returns the pointer to the options of the task kind, e.g.
+// `/node`.
+func (d Definition_0_3) kindPointer() string {
+	switch {
+	case d.Image != nil:
+		return "/docker"
+	case d.Node != nil:
+		return "/node"
+	case d.Python != nil:
+		return "/python"
+	case d.Shell != nil:
+		return "/shell"
+	case d.SQL != nil:
+		return "/sql"
+	case d.REST != nil:
+		return "/rest"
+	default:
+		return ""
+	}
+}
+
+// minCPU is the smallest amount of CPU that a task can request.
+var minCPU = quantity.MustParse("1m")
+
+// validateCompute checks that the quantities of compute, found at pointer,
+// are valid, and that limits are not less than requests.
+func validateCompute(
+	pointer string,
+	compute *ComputeDefinition_0_3,
+	add func(pointer, format string, args ...interface{}),
+) {
+	if compute == nil {
+		return
+	}
+
+	parse := func(ptr string, resources *ComputeResourcesDefinition_0_3) (cpu, memory *quantity.Quantity) {
+		if resources == nil {
+			return nil, nil
+		}
+		if resources.CPU != "" {
+			if q, err := quantity.Parse(resources.CPU); err != nil {
+				add(ptr+"/cpu", "%s", err)
+			} else if q.Cmp(minCPU) < 0 {
+				add(ptr+"/cpu", "cpu must be at least 1m")
+			} else {
+				cpu = &q
+			}
+		}
+		if resources.Memory != "" {
+			if q, err := quantity.Parse(resources.Memory); err != nil {
+				add(ptr+"/memory", "%s", err)
+			} else if q.IsZero() {
+				add(ptr+"/memory", "memory must be greater than 0")
+			} else if !q.IsInteger() {
+				add(ptr+"/memory", "memory must be a whole number of bytes")
+			} else {
+				memory = &q
+			}
+		}
+		return cpu, memory
+	}
+	reqCPU, reqMemory := parse(pointer+"/requests", compute.Requests)
+	limCPU, limMemory := parse(pointer+"/limits", compute.Limits)
+
+	if reqCPU != nil && limCPU != nil && limCPU.Cmp(*reqCPU) < 0 {
+		add(pointer+"/limits/cpu", "limit must not be less than the request of %s", reqCPU)
+	}
+	if reqMemory != nil && limMemory != nil && limMemory.Cmp(*reqMemory) < 0 {
+		add(pointer+"/limits/memory", "limit must not be less than the request of %s", reqMemory)
+	}
+}
+
+func validateREST(
+	pointer string,
+	rest *RESTDefinition_0_3,
+	params map[string]ParameterDefinition_0_3,
+	add func(pointer, format string, args ...interface{}),
+) {
+	if rest == nil {
+		return
+	}
+
+	if rest.Body != nil && rest.BodyFile != "" {
+		add(pointer+"/bodyFile", "bodyFile must not be set with body")
+	}
+	switch {
+	case rest.BodyType.IsForm():
+		if rest.Body != nil {
+			add(pointer+"/body", "%s requests send formData rather than a body", rest.BodyType)
+		}
+		if rest.BodyFile != "" {
+			add(pointer+"/bodyFile", "%s requests send formData rather than a body", rest.BodyType)
+		}
+	case len(rest.FormData) > 0:
+		add(pointer+"/formData", "formData is only sent by form-data and x-www-form-urlencoded requests")
+	}
+	switch body := rest.Body.(type) {
+	case string:
+		if rest.BodyType == RESTBodyTypeJSON {
+			if msg := checkJSONTemplate(body); msg != "" {
+				add(pointer+"/body", "body %s", msg)
+			}
+		}
+	case nil:
+	default:
+		if rest.BodyType == RESTBodyTypeRaw {
+			add(pointer+"/body", "raw bodies must be a string")
+		}
+	}
+
+	var checkValue func(ptr string, v interface{})
+	checkValue = func(ptr string, v interface{}) {
+		switch v := v.(type) {
+		case string:
+			checkTemplates(ptr, v, params, add)
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				checkValue(ptr+"/"+escapePointer(k), v[k])
+			}
+		case []interface{}:
+			for i, item := range v {
+				checkValue(fmt.Sprintf("%s/%d", ptr, i), item)
+			}
+		}
+	}
+	checkTemplates(pointer+"/path", rest.Path, params, add)
+	checkValue(pointer+"/urlParams", rest.URLParams)
+	checkValue(pointer+"/headers", rest.Headers)
+	checkValue(pointer+"/body", rest.Body)
+	checkValue(pointer+"/formData", rest.FormData)
+}
+
+// checkTemplates checks that the templates in s, found at pointer, only
+// refer to the parameters of the task.
+func checkTemplates(
+	pointer string,
+	s string,
+	params map[string]ParameterDefinition_0_3,
+	add func(pointer, format string, args ...interface{}),
+) {
+	slugs, err := templateParams(s)
+	if err != nil {
+		add(pointer, "%s", err)
+		return
+	}
+	for _, slug := range slugs {
+		if _, ok := params[slug]; !ok {
+			add(pointer, "template refers to unknown parameter %q", slug)
+		}
+	}
+}
+
+// ValidateFiles checks the files that the definition refers to, such as the
+// body file of a REST task, returning an ErrValidation with every problem
+// that was found. Files are read relative to the definition, so its path
+// must be set with SetDefnFilePath.
+func (d *Definition_0_3) ValidateFiles() error {
+	var errs []ValidationError
+	add := func(pointer, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Body files of form requests are already reported by Validate.
+	if rest := d.REST; rest != nil && rest.BodyFile != "" && !rest.BodyType.IsForm() {
+		if body, err := rest.GetBody(); err != nil {
+			add("/rest/bodyFile", "%s", err)
+		} else if s, ok := body.(string); ok {
+			if rest.BodyType == RESTBodyTypeJSON {
+				if msg := checkJSONTemplate(s); msg != "" {
+					add("/rest/bodyFile", "body file %s", msg)
+				}
+			}
+			params := map[string]ParameterDefinition_0_3{}
+			for _, p := range d.Parameters {
+				params[p.Slug] = p
+			}
+			checkTemplates("/rest/bodyFile", s, params, add)
+		}
+	}
+
+	if len(errs) > 0 {
+		return ErrValidation{Errors: errs}
+	}
+	return nil
+}
+
+// validateSchedules checks the cron strings, time zones and bounds of
+// schedules, found at pointer, and that their param values reference
+// parameters of the task.
+func validateSchedules(
+	pointer string,
+	schedules map[string]ScheduleDefinition_0_3,
+	params map[string]ParameterDefinition_0_3,
+	add func(pointer, format string, args ...interface{}),
+) {
+	scheduleSlugs := make([]string, 0, len(schedules))
+	for slug := range schedules {
+		scheduleSlugs = append(scheduleSlugs, slug)
+	}
+	sort.Strings(scheduleSlugs)
+	for _, slug := range scheduleSlugs {
+		schedule := schedules[slug]
+		ptr := pointer + "/" + escapePointer(slug)
+		if _, err := cron.Parse(schedule.CronExpr); err != nil {
+			add(ptr+"/cron", "%s", err)
+		}
+		loc, err := schedule.Location()
+		if err != nil {
+			add(ptr+"/timezone", "%s", err)
+			loc = time.UTC
+		}
+		var start, end time.Time
+		if schedule.StartDate != "" {
+			if start, err = parseScheduleDate(schedule.StartDate, loc); err != nil {
+				add(ptr+"/startDate", "%s", err)
+			}
+		}
+		if schedule.EndDate != "" {
+			if end, err = parseScheduleDate(schedule.EndDate, loc); err != nil {
+				add(ptr+"/endDate", "%s", err)
+			} else if !start.IsZero() && !end.After(start) {
+				add(ptr+"/endDate", "must be after startDate")
+			}
+		}
+
+		paramValues := schedule.ParamValues
+		paramSlugs := make([]string, 0, len(paramValues))
+		for param := range paramValues {
+			paramSlugs = append(paramSlugs, param)
+		}
+		sort.Strings(paramSlugs)
+		for _, param := range paramSlugs {
+			ptr := pointer + "/" + escapePointer(slug) + "/paramValues/" + escapePointer(param)
+			p, ok := params[param]
+			if !ok {
+				add(ptr, "unknown parameter %q", param)
+				continue
+			}
+			if msg := checkParamValue(p.Type, paramValues[param]); msg != "" {
+				add(ptr, "value %s", msg)
+			}
+		}
+	}
+}
+
+// validateTriggers checks that the param mappings of triggers, found at
+// pointer, are JSON paths for parameters of the task. Triggers and schedules
+// are deployed as triggers of the task, so their slugs must not overlap.
+func validateTriggers(
+	pointer string,
+	triggers map[string]TriggerDefinition_0_3,
+	schedules map[string]ScheduleDefinition_0_3,
+	params map[string]ParameterDefinition_0_3,
+	add func(pointer, format string, args ...interface{}),
+) {
+	triggerSlugs := make([]string, 0, len(triggers))
+	for slug := range triggers {
+		triggerSlugs = append(triggerSlugs, slug)
+	}
+	sort.Strings(triggerSlugs)
+	for _, slug := range triggerSlugs {
+		trigger := triggers[slug]
+		ptr := pointer + "/" + escapePointer(slug)
+		if _, ok := schedules[slug]; ok {
+			add(ptr, "slug %q is already used by a schedule", slug)
+		}
+
+		mappingsPtr := ptr + "/" + string(trigger.Kind()) + "/paramMappings"
+		mappings := trigger.ParamMappings()
+		paramSlugs := make([]string, 0, len(mappings))
+		for param := range mappings {
+			paramSlugs = append(paramSlugs, param)
+		}
+		sort.Strings(paramSlugs)
+		for _, param := range paramSlugs {
+			ptr := mappingsPtr + "/" + escapePointer(param)
+			if _, ok := params[param]; !ok {
+				add(ptr, "unknown parameter %q", param)
+				continue
+			}
+			if _, err := parseJSONPath(mappings[param]); err != nil {
+				add(ptr, "%s", err)
+			}
+		}
+	}
+}
+
+// checkParamValue checks that v is a valid value of the type typ,
+// returning a description of the problem if it is not.
+func checkParamValue(typ string, v interface{}) string {
+	t, _, err := paramTypeToAPI(typ)
+	if err != nil {
+		// Unknown types are reported by the schema.
+		return ""
+	}
+	return params.CheckValue(api.Parameter{Type: t}, v)
+}
+
+// checkParamDefault checks that v is a valid value for p that satisfies its
+// constraints, returning a description of the problem if it is not. It does
+// not check that v is one of the options of p.
+func checkParamDefault(p ParameterDefinition_0_3, v interface{}) string {
+	t, _, err := paramTypeToAPI(p.Type)
+	if err != nil {
+		return ""
+	}
+	return params.CheckValue(api.Parameter{
+		Type: t,
+		Constraints: api.Constraints{
+			Regex:     p.Regex,
+			Min:       p.Min,
+			Max:       p.Max,
+			MinLength: p.MinLength,
+			MaxLength: p.MaxLength,
+			MinDate:   p.MinDate,
+			MaxDate:   p.MaxDate,
+		},
+	}, v)
+}
+
+func isTextParamType(typ string) bool {
+	return typ == "shorttext" || typ == "longtext" || typ == "sql"
+}
+
+// escapePointer escapes a JSON pointer reference token.
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+// pointerToField converts a JSON pointer to the dotted form used by schema
+// errors, e.g. `/parameters/0/default` becomes `parameters.0.default`.
+func pointerToField(pointer string) string {
+	if pointer == "" {
+		return "(root)"
+	}
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, token := range tokens {
+		tokens[i] = unescapePointer(token)
+	}
+	return strings.Join(tokens, ".")
+}
+
+func unescapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+// lookupKey is like lookupPointer, but returns the key node if pointer
+// refers to a field of an object.
+func lookupKey(doc *yaml.Node, pointer string) *yaml.Node {
+	value := lookupPointer(doc, pointer)
+	i := strings.LastIndex(pointer, "/")
+	if i < 0 {
+		return value
+	}
+	parent := lookupPointer(doc, pointer[:i])
+	if parent == nil || parent == value || parent.Kind != yaml.MappingNode {
+		return value
+	}
+	for j := 0; j+1 < len(parent.Content); j += 2 {
+		if parent.Content[j+1] == value {
+			return parent.Content[j]
+		}
+	}
+	return value
+}
+
+// lookupPointer returns the node that pointer refers to. If pointer refers
+// to a value that does not exist, the closest existing parent is returned.
+func lookupPointer(doc *yaml.Node, pointer string) *yaml.Node {
+	n := doc
+	if n.Kind == yaml.DocumentNode {
+		if len(n.Content) == 0 {
+			return nil
+		}
+		n = n.Content[0]
+	}
+	if pointer == "" {
+		return n
+	}
+
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = unescapePointer(token)
+		var next *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == token {
+					next = n.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(token); err == nil && i >= 0 && i < len(n.Content) {
+				next = n.Content[i]
+			}
+		}
+		if next == nil {
+			return n
+		}
+		n = next
+	}
+	return n
+}
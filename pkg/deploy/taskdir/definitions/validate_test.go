@@ -0,0 +1,375 @@
+package definitions
+
+import (
+	"testing"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/airplanedev/lib/pkg/utils/pointers"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		def      Definition_0_3
+		expected []ValidationError
+	}{
+		{
+			name: "valid",
+			def: Definition_0_3{
+				Parameters: []ParameterDefinition_0_3{
+					{Slug: "text", Type: "shorttext", Default: "hello", Regex: "^h"},
+					{Slug: "count", Type: "integer", Default: float64(3)},
+					{Slug: "ratio", Type: "float", Default: 0.5},
+					{Slug: "day", Type: "date", Default: "2022-01-02"},
+					{Slug: "at", Type: "datetime", Default: "2022-01-02T03:04:05Z"},
+					{Slug: "on", Type: "boolean", Default: true},
+					{Slug: "key", Type: "configvar", Default: "api_key", Options: []OptionDefinition_0_3{
+						{Value: "api_key"},
+						{Label: "Other", Config: pointers.String("other_key")},
+					}},
+				},
+				Schedules: map[string]ScheduleDefinition_0_3{
+					"daily": {CronExpr: "0 0 * * *", ParamValues: map[string]interface{}{"count": float64(5)}},
+				},
+			},
+		},
+		{
+			name: "invalid",
+			def: Definition_0_3{
+				Parameters: []ParameterDefinition_0_3{
+					{Slug: "count", Type: "integer", Default: 1.5},
+					{Slug: "count", Type: "shorttext", Regex: "("},
+					{Slug: "day", Type: "date", Default: "tomorrow", Options: []OptionDefinition_0_3{
+						{Value: "2022-01-02"},
+						{Label: "Bad", Value: float64(1)},
+						{Label: "Config", Config: pointers.String("x")},
+					}},
+				},
+				Schedules: map[string]ScheduleDefinition_0_3{
+					"daily": {CronExpr: "0 0 * * *", ParamValues: map[string]interface{}{
+						"day":     true,
+						"missing": "x",
+					}},
+				},
+			},
+			expected: []ValidationError{
+				{Pointer: "/parameters/0/default", Message: "default must be an integer"},
+				{Pointer: "/parameters/1/slug", Message: `duplicate parameter slug "count"`},
+				{Pointer: "/parameters/1/regex", Message: "invalid regex: missing closing ): `(`"},
+				{Pointer: "/parameters/2/default", Message: "default must be a date, e.g. 2006-01-02"},
+				{Pointer: "/parameters/2/options/1/value", Message: "option must be a date, e.g. 2006-01-02"},
+				{Pointer: "/parameters/2/options/2/config", Message: "config options are only supported by configvar parameters"},
+				{Pointer: "/schedules/daily/paramValues/day", Message: "value must be a date, e.g. 2006-01-02"},
+				{Pointer: "/schedules/daily/paramValues/missing", Message: `unknown parameter "missing"`},
+			},
+		},
+		{
+			name: "invalid schedules",
+			def: Definition_0_3{
+				Schedules: map[string]ScheduleDefinition_0_3{
+					"bounds": {CronExpr: "@daily", StartDate: "2022-02-01", EndDate: "2022-01-01"},
+					"cron":   {CronExpr: "0 25 * * *", StartDate: "soon"},
+					"zone":   {CronExpr: "@hourly", Timezone: "Mars/Olympus_Mons"},
+				},
+			},
+			expected: []ValidationError{
+				{Pointer: "/schedules/bounds/endDate", Message: "must be after startDate"},
+				{Pointer: "/schedules/cron/cron", Message: `invalid hour "25": value 25 is out of range 0-23`},
+				{Pointer: "/schedules/cron/startDate", Message: `"soon" is not a date, e.g. 2006-01-02, or an RFC 3339 datetime`},
+				{Pointer: "/schedules/zone/timezone", Message: `unknown timezone "Mars/Olympus_Mons"`},
+			},
+		},
+		{
+			name: "invalid triggers",
+			def: Definition_0_3{
+				Parameters: []ParameterDefinition_0_3{
+					{Slug: "email", Type: "shorttext"},
+				},
+				Schedules: map[string]ScheduleDefinition_0_3{
+					"daily": {CronExpr: "@daily"},
+				},
+				Triggers: map[string]TriggerDefinition_0_3{
+					"daily": {Webhook: &WebhookTriggerDefinition_0_3{}},
+					"signup": {Event: &EventTriggerDefinition_0_3{
+						Type: "user.created",
+						ParamMappings: map[string]string{
+							"email":   "$.user[email]",
+							"missing": "$.missing",
+						},
+					}},
+				},
+			},
+			expected: []ValidationError{
+				{Pointer: "/triggers/daily", Message: `slug "daily" is already used by a schedule`},
+				{Pointer: "/triggers/signup/event/paramMappings/email", Message: `invalid JSON path "$.user[email]"`},
+				{Pointer: "/triggers/signup/event/paramMappings/missing", Message: `unknown parameter "missing"`},
+			},
+		},
+		{
+			name: "invalid env vars",
+			def: Definition_0_3{
+				Node: &NodeDefinition_0_3{
+					Entrypoint: "main.ts",
+					EnvVars: api.TaskEnv{
+						"BOTH":  {Value: pointers.String("x"), Secret: pointers.String("y")},
+						"EMPTY": {},
+						"HOST":  {Resource: pointers.String("db")},
+					},
+				},
+				Environments: map[string]EnvironmentDefinition_0_3{
+					"prod": {EnvVars: api.TaskEnv{"HOST": {Resource: pointers.String(".host")}}},
+				},
+			},
+			expected: []ValidationError{
+				{Pointer: "/node/envVars/BOTH", Message: "expected only one of value, secret"},
+				{Pointer: "/node/envVars/EMPTY", Message: "expected one of value, config, secret or resource"},
+				{Pointer: "/node/envVars/HOST", Message: `invalid resource reference "db", expected <resource slug>.<field>`},
+				{Pointer: "/environments/prod/envVars/HOST", Message: `invalid resource reference ".host", expected <resource slug>.<field>`},
+			},
+		},
+		{
+			name: "invalid compute",
+			def: Definition_0_3{
+				SQL: &SQLDefinition_0_3{Resource: "db", Entrypoint: "query.sql"},
+				Compute: &ComputeDefinition_0_3{
+					Requests: &ComputeResourcesDefinition_0_3{CPU: "2", Memory: "1Gi"},
+					Limits:   &ComputeResourcesDefinition_0_3{CPU: "500m", Memory: "1.5"},
+				},
+			},
+			expected: []ValidationError{
+				{Pointer: "/compute", Message: "compute is not supported by sql tasks"},
+				{Pointer: "/compute/limits/memory", Message: "memory must be a whole number of bytes"},
+				{Pointer: "/compute/limits/cpu", Message: "limit must not be less than the request of 2"},
+			},
+		},
+		{
+			name: "sub-millicore cpu",
+			def: Definition_0_3{
+				Python: &PythonDefinition_0_3{Entrypoint: "main.py"},
+				Compute: &ComputeDefinition_0_3{
+					Requests: &ComputeResourcesDefinition_0_3{CPU: "0.0001"},
+					Limits:   &ComputeResourcesDefinition_0_3{CPU: "1m"},
+				},
+			},
+			expected: []ValidationError{
+				{Pointer: "/compute/requests/cpu", Message: "cpu must be at least 1m"},
+			},
+		},
+		{
+			name: "invalid REST bodies",
+			def: Definition_0_3{
+				Parameters: []ParameterDefinition_0_3{
+					{Slug: "id", Type: "integer"},
+				},
+				REST: &RESTDefinition_0_3{
+					Resource: "api",
+					Method:   "POST",
+					Path:     "/users/{{params.id}}/{{params.name}}",
+					Headers:  map[string]interface{}{"X-Trace": "{{params.id"},
+					BodyType: RESTBodyTypeJSON,
+					Body:     `{"id": {{params.id}},}`,
+					FormData: map[string]interface{}{"id": "{{params.id}}"},
+				},
+			},
+			expected: []ValidationError{
+				{Pointer: "/rest/formData", Message: "formData is only sent by form-data and x-www-form-urlencoded requests"},
+				{Pointer: "/rest/body", Message: "body must be valid JSON: invalid character '}' looking for beginning of object key string"},
+				{Pointer: "/rest/path", Message: `template refers to unknown parameter "name"`},
+				{Pointer: "/rest/headers/X-Trace", Message: "template is missing a closing }}"},
+			},
+		},
+		{
+			name: "REST form with a body",
+			def: Definition_0_3{
+				REST: &RESTDefinition_0_3{
+					Resource: "api",
+					Method:   "POST",
+					Path:     "/",
+					BodyType: RESTBodyTypeFormURLEncoded,
+					Body:     "{}",
+					BodyFile: "body.json",
+					FormData: map[string]interface{}{"name": `{{params["name"]}}`},
+				},
+			},
+			expected: []ValidationError{
+				{Pointer: "/rest/bodyFile", Message: "bodyFile must not be set with body"},
+				{Pointer: "/rest/body", Message: "x-www-form-urlencoded requests send formData rather than a body"},
+				{Pointer: "/rest/bodyFile", Message: "x-www-form-urlencoded requests send formData rather than a body"},
+				{Pointer: "/rest/formData/name", Message: `template refers to unknown parameter "name"`},
+			},
+		},
+		{
+			name: "invalid permissions",
+			def: Definition_0_3{
+				Permissions: &PermissionsDefinition_0_3{
+					Roles: map[api.RoleID]PrincipalsDefinition_0_3{
+						api.RoleTaskViewer:   {},
+						api.RoleTeamAdmin:    {Users: []string{"usr1"}},
+						api.RoleTaskExecuter: {Groups: []string{"grp1"}},
+					},
+					Approvals: &ApprovalsDefinition_0_3{Required: 2},
+				},
+			},
+			expected: []ValidationError{
+				{Pointer: "/permissions/roles/task_viewer", Message: "role must be granted to at least one user or group"},
+				{Pointer: "/permissions/roles/team_admin", Message: `unknown role "team_admin", expected one of task_viewer, task_requester, task_executer, task_admin`},
+				{Pointer: "/permissions/approvals", Message: "approvals require requireRequests to be true"},
+			},
+		},
+		{
+			name: "structured types",
+			def: Definition_0_3{
+				Parameters: []ParameterDefinition_0_3{
+					{Slug: "tags", Type: "list", Default: []interface{}{"a", float64(1)}},
+					{Slug: "colors", Type: "multiselect", Default: []interface{}{"red"}},
+					{Slug: "payload", Type: "object", Default: "{}"},
+					{Slug: "blob", Type: "json", Default: []interface{}{true}},
+				},
+			},
+			expected: []ValidationError{
+				{Pointer: "/parameters/0/default", Message: "default must be a list of strings"},
+				{Pointer: "/parameters/1/type", Message: "multiselect parameters must have options"},
+				{Pointer: "/parameters/2/default", Message: "default must be an object"},
+			},
+		},
+		{
+			name: "constraints",
+			def: Definition_0_3{
+				Parameters: []ParameterDefinition_0_3{
+					{Slug: "count", Type: "integer", Min: pointers.Float64(5), Max: pointers.Float64(1), Default: float64(0)},
+					{Slug: "name", Type: "shorttext", Min: pointers.Float64(1), MinLength: pointers.Int(3), Default: "ab"},
+					{Slug: "day", Type: "date", MinDate: "2022-02-01", MaxDate: "2022-01-01"},
+					{Slug: "on", Type: "boolean", MaxDate: "2022-01-01", VisibleIf: &ParamConditionDefinition_0_3{Param: "on"}},
+					{Slug: "reason", Type: "longtext", Required: NewDefaultTrueDefinition(true), RequiredIf: &ParamConditionDefinition_0_3{
+						Param:  "count",
+						Equals: "five",
+					}},
+					{Slug: "note", Type: "longtext", VisibleIf: &ParamConditionDefinition_0_3{Param: "missing"}},
+				},
+			},
+			expected: []ValidationError{
+				{Pointer: "/parameters/0/max", Message: "max must not be less than min"},
+				{Pointer: "/parameters/0/default", Message: "default must be at least 5"},
+				{Pointer: "/parameters/1/min", Message: "min is only supported by integer and float parameters"},
+				{Pointer: "/parameters/1/default", Message: "default must have at least 3 characters"},
+				{Pointer: "/parameters/2/maxDate", Message: "maxDate must not be before minDate"},
+				{Pointer: "/parameters/3/maxDate", Message: "maxDate is only supported by date and datetime parameters"},
+				{Pointer: "/parameters/3/visibleIf/param", Message: "condition must refer to another parameter"},
+				{Pointer: "/parameters/4/requiredIf/equals", Message: "equals must be an integer"},
+				{Pointer: "/parameters/4/required", Message: "required must not be true if requiredIf is set"},
+				{Pointer: "/parameters/5/visibleIf/param", Message: `unknown parameter "missing"`},
+			},
+		},
+		{
+			name: "environments",
+			def: Definition_0_3{
+				Parameters: []ParameterDefinition_0_3{
+					{Slug: "count", Type: "integer"},
+				},
+				SQL: &SQLDefinition_0_3{Resource: "db", Entrypoint: "query.sql"},
+				Environments: map[string]EnvironmentDefinition_0_3{
+					"prod": {
+						EnvVars: api.TaskEnv{"DEBUG": {Value: pointers.String("false")}},
+						Schedules: map[string]ScheduleDefinition_0_3{
+							"daily": {CronExpr: "0 0 * * *", ParamValues: map[string]interface{}{"count": "x"}},
+						},
+					},
+				},
+			},
+			expected: []ValidationError{
+				{Pointer: "/environments/prod/envVars", Message: "env vars are not supported by sql tasks"},
+				{Pointer: "/environments/prod/schedules/daily/paramValues/count", Message: "value must be an integer"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+			err := test.def.Validate()
+			if test.expected == nil {
+				require.NoError(err)
+				return
+			}
+			var verr ErrValidation
+			require.True(errors.As(err, &verr))
+			require.Equal(test.expected, verr.Errors)
+		})
+	}
+}
+
+func TestValidatePositions(t *testing.T) {
+	require := require.New(t)
+
+	_, err := UnmarshalDefinition(DefFormatYAML, []byte(`name: Hello World
+slug: hello_world
+parameters:
+- name: Count
+  slug: count
+  type: integer
+  default: hello
+python:
+  entrypoint: hello_world.py
+schedules:
+  daily:
+    cron: 0 0 * * *
+    paramValues:
+      other: 1
+`))
+	var verr ErrValidation
+	require.True(errors.As(err, &verr))
+	require.Equal([]ValidationError{
+		{Pointer: "/parameters/0/default", Message: "default must be an integer", Line: 7, Column: 12},
+		{Pointer: "/schedules/daily/paramValues/other", Message: `unknown parameter "other"`, Line: 14, Column: 14},
+	}, verr.Errors)
+	require.Equal("/parameters/0/default: default must be an integer (line 7, column 12)", verr.Errors[0].Error())
+
+	_, err = UnmarshalDefinition(DefFormatJSON, []byte(`{
+	"name": "Hello World",
+	"slug": "hello_world",
+	"parameters": [{"name": "On", "slug": "on", "type": "boolean", "default": "yes"}],
+	"python": {"entrypoint": "hello_world.py"}
+}`))
+	require.True(errors.As(err, &verr))
+	require.Equal([]ValidationError{
+		{Pointer: "/parameters/0/default", Message: "default must be a boolean", Line: 4, Column: 76},
+	}, verr.Errors)
+}
+
+func TestSchemaFieldErrors(t *testing.T) {
+	require := require.New(t)
+
+	buf := []byte(`name: Hello World
+slug: hello_world
+parameters:
+- name: Count
+  slug: count
+  type: number
+python:
+  entrypoint: hello_world.py
+  extra: true
+`)
+	_, err := UnmarshalDefinition(DefFormatYAML, buf)
+	var serr ErrSchemaValidation
+	require.True(errors.As(err, &serr))
+	require.ElementsMatch([]FieldError{
+		{Field: "parameters.0.type", Message: `parameters.0.type must be one of the following: "shorttext", "longtext", "sql", "boolean", "upload", "integer", "float", "date", "datetime", "configvar", "json", "list", "multiselect", "object"`, Line: 6, Column: 3},
+		{Field: "python", Message: "Additional property extra is not allowed", Line: 9, Column: 3},
+		{Field: "(root)", Message: "Must validate one and only one schema (oneOf)", Line: 1, Column: 1},
+		{Field: "(root)", Message: "Must validate all the schemas (allOf)", Line: 1, Column: 1},
+	}, serr.FieldErrors(DefFormatYAML, buf))
+
+	buf = []byte(`{
+	"name": "Hello World",
+	"parameters": [{"name": "Count", "type": "integer"}],
+	"python": {"entrypoint": "hello_world.py"}
+}`)
+	_, err = UnmarshalDefinition(DefFormatJSON, buf)
+	require.True(errors.As(err, &serr))
+	require.ElementsMatch([]FieldError{
+		{Field: "(root)", Message: "slug is required", Line: 1, Column: 1},
+		{Field: "parameters.0", Message: "slug is required", Line: 3, Column: 17},
+		{Field: "(root)", Message: "Must validate one and only one schema (oneOf)", Line: 1, Column: 1},
+		{Field: "(root)", Message: "Must validate all the schemas (allOf)", Line: 1, Column: 1},
+	}, serr.FieldErrors(DefFormatJSON, buf))
+}
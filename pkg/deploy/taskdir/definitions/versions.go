@@ -0,0 +1,346 @@
+package definitions
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	yamlutil "github.com/airplanedev/lib/pkg/utils/yaml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefVersion is a version of the task definition format.
+type DefVersion string
+
+const (
+	DefVersion_0_3 DefVersion = "0.3"
+)
+
+// versionField is the top-level field that declares the format version of
+// a task definition.
+const versionField = "version"
+
+// implicitDefVersion is the version of definitions that do not declare a
+// version. It must never change: these files were written before versions
+// were declared.
+const implicitDefVersion = DefVersion_0_3
+
+// defVersion is a single version of the task definition format.
+type defVersion struct {
+	version DefVersion
+	// upgrade migrates a definition from this version to the next one, in
+	// place. JSON definitions are edited as YAML. It is nil for the latest
+	// version.
+	upgrade func(e *yamlutil.Editor) error
+}
+
+// defVersions are the supported versions of the task definition format,
+// oldest first. Every definition is upgraded to the last version before it
+// is unmarshalled.
+//
+// To introduce a new version, add an upgrade to the current latest version
+// that migrates definitions to the new version, append the new version and
+// update UnmarshalDefinition to unmarshal it.
+var defVersions = []defVersion{
+	{version: DefVersion_0_3},
+}
+
+// LatestDefVersion returns the version that definitions are upgraded to.
+func LatestDefVersion() DefVersion {
+	return defVersions[len(defVersions)-1].version
+}
+
+// ErrUnknownDefVersion is returned when a definition declares a version
+// that is not supported.
+type ErrUnknownDefVersion struct {
+	Version DefVersion
+}
+
+// Error implementation.
+func (err ErrUnknownDefVersion) Error() string {
+	return fmt.Sprintf("unknown task definition version %q", err.Version)
+}
+
+// ExplainError implementation.
+func (err ErrUnknownDefVersion) ExplainError() string {
+	versions := make([]string, 0, len(defVersions))
+	for _, v := range defVersions {
+		versions = append(versions, string(v.version))
+	}
+	return fmt.Sprintf(
+		"Supported versions are: %s.\nIf the definition was written by a newer version of the CLI, upgrade the CLI.",
+		strings.Join(versions, ", "),
+	)
+}
+
+// GetDefVersion returns the format version of the definition in buf.
+// Definitions that do not declare a version are version 0.3.
+func GetDefVersion(format DefFormat, buf []byte) (DefVersion, error) {
+	doc, err := parseDefNode(format, buf)
+	if err != nil {
+		return "", err
+	}
+	v, _, err := defNodeVersion(doc)
+	return v, err
+}
+
+// UpgradeDefinition upgrades the definition in buf to the latest version,
+// returning the upgraded definition in the same format along with the
+// version it was upgraded from. If the definition is already at the latest
+// version, buf is returned unchanged.
+//
+// Comments and formatting of YAML definitions are preserved.
+func UpgradeDefinition(format DefFormat, buf []byte) ([]byte, DefVersion, error) {
+	doc, err := parseDefNode(format, buf)
+	if err != nil {
+		return nil, "", err
+	}
+	from, i, err := defNodeVersion(doc)
+	if err != nil {
+		return nil, "", err
+	}
+	if i == len(defVersions)-1 {
+		return buf, from, nil
+	}
+
+	src := buf
+	if format == DefFormatJSON {
+		// Edit JSON definitions as block-style YAML, then convert back.
+		if src, err = toBlockYAML(doc); err != nil {
+			return nil, "", err
+		}
+	}
+	e, err := yamlutil.NewEditor(src)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "parsing task definition")
+	}
+	for ; i < len(defVersions)-1; i++ {
+		if err := defVersions[i].upgrade(e); err != nil {
+			return nil, "", errors.Wrapf(err, "upgrading task definition from version %s", defVersions[i].version)
+		}
+	}
+	if err := e.InsertKey("", 0, versionField, string(LatestDefVersion())); err != nil {
+		return nil, "", errors.Wrap(err, "setting task definition version")
+	}
+
+	out := e.Bytes()
+	if format == DefFormatJSON {
+		if out, err = toJSON(out); err != nil {
+			return nil, "", err
+		}
+	}
+	if !bytes.HasSuffix(buf, []byte("\n")) {
+		out = bytes.TrimSuffix(out, []byte("\n"))
+	}
+	return out, from, nil
+}
+
+// UpgradeDefinitionFile rewrites the definition at path to the latest
+// version, preserving comments. It returns the version the file was
+// upgraded from; if the file was already at the latest version, it is left
+// untouched.
+func UpgradeDefinitionFile(path string) (DefVersion, error) {
+	format := GetTaskDefFormat(path)
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(err, "reading task definition")
+	}
+	if entries, err := splitDefNodes(format, buf); err != nil {
+		return "", err
+	} else if entries != nil {
+		return "", errors.New("upgrading files that define several tasks is not supported")
+	}
+
+	out, from, err := UpgradeDefinition(format, buf)
+	if err != nil {
+		return "", err
+	}
+	if from == LatestDefVersion() {
+		return from, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", errors.Wrap(err, "reading task definition")
+	}
+	if err := ioutil.WriteFile(path, out, info.Mode()); err != nil {
+		return "", errors.Wrap(err, "writing task definition")
+	}
+	return from, nil
+}
+
+// UnmarshalDefinition unmarshals a task definition of any supported version,
+// upgrading it to the latest version first.
+//
+// The definition is validated with Validate. Validation errors are
+// returned as an ErrValidation with the positions of the errors in buf.
+func UnmarshalDefinition(format DefFormat, buf []byte) (DefinitionInterface, error) {
+	upgraded, _, err := UpgradeDefinition(format, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	def := Definition_0_3{}
+	if err := def.Unmarshal(format, upgraded); err != nil {
+		return nil, err
+	}
+	if err := def.Validate(); err != nil {
+		if verr, ok := err.(ErrValidation); ok {
+			return nil, errors.WithStack(verr.WithPositions(format, buf))
+		}
+		return nil, err
+	}
+	return &def, nil
+}
+
+// parseDefNode parses a definition into a document node whose only child is
+// the top-level mapping.
+func parseDefNode(format DefFormat, buf []byte) (*yaml.Node, error) {
+	switch format {
+	case DefFormatYAML, DefFormatJSON:
+		// JSON is a subset of YAML.
+	default:
+		return nil, errors.Errorf("unknown format: %s", format)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(buf, &doc); err != nil {
+		return nil, errors.Wrap(err, "parsing task definition")
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, errors.New("task definition is empty")
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("task definition must be an object")
+	}
+	return &doc, nil
+}
+
+// defNodeVersion returns the version declared by doc along with its index
+// in defVersions.
+func defNodeVersion(doc *yaml.Node) (DefVersion, int, error) {
+	version := implicitDefVersion
+	node, err := yamlutil.GetYAMLNode(doc.Content[0], versionField)
+	if err != nil {
+		return "", 0, err
+	}
+	if node != nil {
+		if node.Kind != yaml.ScalarNode {
+			return "", 0, errors.Errorf("%s must be a string, e.g. %q", versionField, LatestDefVersion())
+		}
+		version = DefVersion(node.Value)
+	}
+
+	for i, v := range defVersions {
+		if v.version == version {
+			return version, i, nil
+		}
+	}
+	return "", 0, errors.WithStack(ErrUnknownDefVersion{Version: version})
+}
+
+// toBlockYAML encodes doc as YAML, in block style.
+func toBlockYAML(doc *yaml.Node) ([]byte, error) {
+	var clearStyle func(n *yaml.Node)
+	clearStyle = func(n *yaml.Node) {
+		if n.Kind != yaml.ScalarNode {
+			n.Style = 0
+		}
+		for _, c := range n.Content {
+			clearStyle(c)
+		}
+	}
+	clearStyle(doc)
+
+	buf, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling task definition")
+	}
+	return buf, nil
+}
+
+// toJSON converts a YAML definition to JSON, preserving the order of keys.
+func toJSON(buf []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(buf, &doc); err != nil {
+		return nil, errors.Wrap(err, "parsing task definition")
+	}
+	buf, err := json.Marshal(jsonNode{&doc})
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling task definition")
+	}
+	// Match the indentation used by Marshal.
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf, "", "\t"); err != nil {
+		return nil, errors.Wrap(err, "marshalling task definition")
+	}
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+// jsonNode marshals a YAML node as JSON, preserving the order of keys.
+type jsonNode struct {
+	*yaml.Node
+}
+
+var _ json.Marshaler = jsonNode{}
+
+func (n jsonNode) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return []byte("null"), nil
+		}
+		return json.Marshal(jsonNode{n.Content[0]})
+
+	case yaml.AliasNode:
+		return json.Marshal(jsonNode{n.Alias})
+
+	case yaml.MappingNode:
+		buf.WriteString("{")
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			k, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return nil, err
+			}
+			v, err := json.Marshal(jsonNode{n.Content[i+1]})
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(k)
+			buf.WriteString(":")
+			buf.Write(v)
+		}
+		buf.WriteString("}")
+
+	case yaml.SequenceNode:
+		buf.WriteString("[")
+		for i, c := range n.Content {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			v, err := json.Marshal(jsonNode{c})
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(v)
+		}
+		buf.WriteString("]")
+
+	default:
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,185 @@
+package definitions
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	yamlutil "github.com/airplanedev/lib/pkg/utils/yaml"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// withTestVersions registers a fake 0.4 version that renames `timeout` to
+// `timeoutSeconds`, for the duration of a test.
+func withTestVersions(t *testing.T) {
+	prev := defVersions
+	t.Cleanup(func() { defVersions = prev })
+
+	defVersions = []defVersion{
+		{
+			version: DefVersion_0_3,
+			upgrade: func(e *yamlutil.Editor) error {
+				n, err := e.Get("timeout")
+				if err != nil || n == nil {
+					return err
+				}
+				return e.Rename("timeout", "timeoutSeconds")
+			},
+		},
+		{version: "0.4"},
+	}
+}
+
+func TestGetDefVersion(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		format   DefFormat
+		def      string
+		expected DefVersion
+		err      bool
+	}{
+		{
+			name:     "implicit",
+			format:   DefFormatYAML,
+			def:      "slug: my_task\n",
+			expected: DefVersion_0_3,
+		},
+		{
+			name:     "explicit",
+			format:   DefFormatYAML,
+			def:      "version: \"0.3\"\nslug: my_task\n",
+			expected: DefVersion_0_3,
+		},
+		{
+			name:     "unquoted",
+			format:   DefFormatYAML,
+			def:      "version: 0.3\nslug: my_task\n",
+			expected: DefVersion_0_3,
+		},
+		{
+			name:     "json",
+			format:   DefFormatJSON,
+			def:      `{"version": "0.3", "slug": "my_task"}`,
+			expected: DefVersion_0_3,
+		},
+		{
+			name:   "unknown",
+			format: DefFormatYAML,
+			def:    "version: \"9.9\"\nslug: my_task\n",
+			err:    true,
+		},
+		{
+			name:   "not an object",
+			format: DefFormatYAML,
+			def:    "- slug: my_task\n",
+			err:    true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+			v, err := GetDefVersion(test.format, []byte(test.def))
+			if test.err {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+			require.Equal(test.expected, v)
+		})
+	}
+
+	_, err := GetDefVersion(DefFormatYAML, []byte("version: \"9.9\"\n"))
+	var verr ErrUnknownDefVersion
+	require.True(t, errors.As(err, &verr))
+	require.Equal(t, DefVersion("9.9"), verr.Version)
+}
+
+func TestUpgradeDefinition(t *testing.T) {
+	withTestVersions(t)
+
+	t.Run("yaml", func(t *testing.T) {
+		require := require.New(t)
+		in := `# My task.
+slug: my_task
+name: My task
+# Five minutes.
+timeout: 300 # seconds
+`
+		out, from, err := UpgradeDefinition(DefFormatYAML, []byte(in))
+		require.NoError(err)
+		require.Equal(DefVersion_0_3, from)
+		require.Equal(`version: "0.4"
+# My task.
+slug: my_task
+name: My task
+# Five minutes.
+timeoutSeconds: 300 # seconds
+`, string(out))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		require := require.New(t)
+		in := `{"slug": "my_task", "timeout": 300, "name": "My task", "parameters": [{"slug": "a", "default": 1.5}]}`
+		out, from, err := UpgradeDefinition(DefFormatJSON, []byte(in))
+		require.NoError(err)
+		require.Equal(DefVersion_0_3, from)
+		require.Equal(`{
+	"version": "0.4",
+	"slug": "my_task",
+	"timeoutSeconds": 300,
+	"name": "My task",
+	"parameters": [
+		{
+			"slug": "a",
+			"default": 1.5
+		}
+	]
+}`, string(out))
+	})
+
+	t.Run("latest", func(t *testing.T) {
+		require := require.New(t)
+		in := "version: \"0.4\"\ntimeout: 300\n"
+		out, from, err := UpgradeDefinition(DefFormatYAML, []byte(in))
+		require.NoError(err)
+		require.Equal(DefVersion("0.4"), from)
+		require.Equal(in, string(out))
+	})
+}
+
+func TestUpgradeDefinitionFile(t *testing.T) {
+	withTestVersions(t)
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "my_task.task.yaml")
+	require.NoError(ioutil.WriteFile(path, []byte("slug: my_task\ntimeout: 300\n"), 0644))
+
+	from, err := UpgradeDefinitionFile(path)
+	require.NoError(err)
+	require.Equal(DefVersion_0_3, from)
+	buf, err := ioutil.ReadFile(path)
+	require.NoError(err)
+	require.Equal("version: \"0.4\"\nslug: my_task\ntimeoutSeconds: 300\n", string(buf))
+
+	// Upgrading again is a no-op.
+	from, err = UpgradeDefinitionFile(path)
+	require.NoError(err)
+	require.Equal(DefVersion("0.4"), from)
+}
+
+func TestUnmarshalDefinition(t *testing.T) {
+	require := require.New(t)
+
+	def, err := UnmarshalDefinition(DefFormatYAML, []byte(`version: "0.3"
+name: Hello World
+slug: hello_world
+node:
+  entrypoint: hello_world.ts
+  nodeVersion: "14"
+`))
+	require.NoError(err)
+	require.Equal("hello_world", def.GetSlug())
+
+	_, err = UnmarshalDefinition(DefFormatYAML, []byte("version: \"9.9\"\nname: Hello World\n"))
+	require.Error(err)
+}
@@ -20,7 +20,8 @@ name: {{.name}}
 #   # A human-readable name for the parameter.
 #   name: Name
 #   # The type of parameter. Valid values: shorttext, longtext, sql, boolean,
-#   # upload, integer, float, date, datetime, configvar.
+#   # upload, integer, float, date, datetime, configvar, json, list,
+#   # multiselect, object.
 #   type: shorttext
 #   # A human-readable description of the parameter.
 #   description: The user's name.
@@ -206,6 +207,10 @@ rest:
   # (https://docs.airplane.dev/runbooks/javascript-templates).
   body: "{{.Body}}"
 
+  # The path to a file containing the body of the request, instead of body.
+  # This can be absolute or relative to the location of the definition file.
+  # bodyFile: body.json
+
   # A map of form values. Supports JavaScript templates
   # (https://docs.airplane.dev/runbooks/javascript-templates).
   # formData:
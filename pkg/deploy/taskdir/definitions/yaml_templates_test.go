@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/airplanedev/lib/pkg/api"
 	"github.com/airplanedev/lib/pkg/build"
 	"github.com/stretchr/testify/require"
 )
@@ -93,3 +94,66 @@ func TestYAMLComments(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateCommentedFileKeepsFields(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		update func(d *Definition_0_3)
+		field  string
+	}{
+		{
+			name: "permissions",
+			update: func(d *Definition_0_3) {
+				d.Permissions = &PermissionsDefinition_0_3{
+					Roles: map[api.RoleID]PrincipalsDefinition_0_3{
+						api.RoleTaskAdmin: {Users: []string{"usr1"}},
+					},
+				}
+			},
+			field: "permissions:",
+		},
+		{
+			name: "compute",
+			update: func(d *Definition_0_3) {
+				d.Compute = &ComputeDefinition_0_3{
+					Requests: &ComputeResourcesDefinition_0_3{CPU: "500m", Memory: "1Gi"},
+					Limits:   &ComputeResourcesDefinition_0_3{CPU: "2"},
+				}
+			},
+			field: "compute:",
+		},
+		{
+			name: "schedules",
+			update: func(d *Definition_0_3) {
+				d.Schedules = map[string]ScheduleDefinition_0_3{
+					"daily": {CronExpr: "0 12 * * *"},
+				}
+			},
+			field: "schedules:",
+		},
+		{
+			name: "triggers",
+			update: func(d *Definition_0_3) {
+				d.Triggers = map[string]TriggerDefinition_0_3{
+					"on_push": {Webhook: &WebhookTriggerDefinition_0_3{}},
+				}
+			},
+			field: "triggers:",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+			def, err := NewDefinition_0_3("My Task", "my_task", build.TaskKindPython, "my_task.py")
+			require.NoError(err)
+			test.update(&def)
+
+			got, err := def.GenerateCommentedFile(DefFormatYAML)
+			require.NoError(err)
+			require.Contains(string(got), test.field)
+
+			unmarshalled := Definition_0_3{}
+			require.NoError(unmarshalled.Unmarshal(DefFormatYAML, got))
+			require.Equal(def, unmarshalled)
+		})
+	}
+}
@@ -10,7 +10,24 @@ import (
 	"github.com/pkg/errors"
 )
 
+// ReadDefinition reads the task definition of the directory. It returns an
+// error if the definition file defines several tasks; use ReadDefinitions to
+// read those.
 func (td TaskDirectory) ReadDefinition() (definitions.DefinitionInterface, error) {
+	defs, err := td.ReadDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	if len(defs) != 1 {
+		return nil, errors.Errorf("%s defines %d tasks, expected one", td.defPath, len(defs))
+	}
+	return defs[0], nil
+}
+
+// ReadDefinitions reads the task definitions of the directory. Definition
+// files usually define a single task, but can define several; see
+// definitions.SplitDefinitions.
+func (td TaskDirectory) ReadDefinitions() ([]definitions.DefinitionInterface, error) {
 	buf, err := ioutil.ReadFile(td.defPath)
 	if err != nil {
 		return nil, errors.Wrap(err, "reading task definition")
@@ -24,20 +41,55 @@ func (td TaskDirectory) ReadDefinition() (definitions.DefinitionInterface, error
 		defPath = path
 	}
 
-	def := definitions.Definition_0_3{}
-	if err := def.Unmarshal(definitions.GetTaskDefFormat(defPath), buf); err != nil {
+	format := definitions.GetTaskDefFormat(defPath)
+	// Split the file into its tasks and merge in any fragments that they
+	// extend before they are validated, so that errors can point at the
+	// entry or fragment of a bad field.
+	entries, err := definitions.SplitDefinitions(defPath, format, buf)
+	if err != nil {
+		return nil, definitions.NewErrReadDefinition(fmt.Sprintf("Error reading %s", defPath), err.Error())
+	}
+
+	defs := make([]definitions.DefinitionInterface, 0, len(entries))
+	entriesBySlug := map[string]string{}
+	for _, entry := range entries {
+		name := defPath
+		if entry.Entry != "" {
+			name = fmt.Sprintf("%s (%s)", defPath, entry.Entry)
+		}
+		def, err := td.readEntry(name, defPath, entry)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := entriesBySlug[def.GetSlug()]; ok {
+			return nil, definitions.NewErrReadDefinition(fmt.Sprintf("Error reading %s", name),
+				fmt.Sprintf("slug %q is already used by %s", def.GetSlug(), other))
+		}
+		entriesBySlug[def.GetSlug()] = entry.Entry
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+func (td TaskDirectory) readEntry(name, defPath string, entry definitions.ExtendedDefinition) (definitions.DefinitionInterface, error) {
+	def, err := definitions.UnmarshalDefinition(entry.Format, entry.Buf)
+	if err != nil {
 		switch err := errors.Cause(err).(type) {
-		case definitions.ErrSchemaValidation:
-			errorMsgs := []string{}
-			for _, verr := range err.Errors {
-				errorMsgs = append(errorMsgs, fmt.Sprintf("%s: %s", verr.Field(), verr.Description()))
-			}
-			return nil, definitions.NewErrReadDefinition(fmt.Sprintf("Error reading %s", defPath), errorMsgs...)
+		case definitions.ErrSchemaValidation, definitions.ErrValidation:
+			return nil, definitions.NewErrReadDefinitionWithFragments(fmt.Sprintf("Error reading %s", name), defPath, entry, entry.FieldErrors(err)...)
+		case definitions.ErrUnknownDefVersion:
+			return nil, definitions.NewErrReadDefinition(fmt.Sprintf("Error reading %s", name), err.Error(), err.ExplainError())
 		default:
 			return nil, errors.Wrap(err, "unmarshalling task definition")
 		}
 	}
 	def.SetDefnFilePath(td.defPath)
+	if err := def.ValidateFiles(); err != nil {
+		if verr, ok := errors.Cause(err).(definitions.ErrValidation); ok {
+			return nil, definitions.NewErrReadDefinitionWithFragments(fmt.Sprintf("Error reading %s", name), defPath, entry, entry.FieldErrors(verr)...)
+		}
+		return nil, err
+	}
 	entrypoint, err := def.Entrypoint()
 	if err == definitions.ErrNoEntrypoint {
 		// nothing
@@ -59,5 +111,5 @@ func (td TaskDirectory) ReadDefinition() (definitions.DefinitionInterface, error
 			}
 		}
 	}
-	return &def, nil
+	return def, nil
 }
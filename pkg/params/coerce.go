@@ -0,0 +1,253 @@
+package params
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/pkg/errors"
+)
+
+const dateLayout = "2006-01-02"
+
+// coerce converts input to a value of the type of p:
+//
+//   - integers are ints, floats are float64s and booleans are bools
+//   - dates are strings formatted as 2006-01-02, and datetimes are strings
+//     formatted as RFC 3339
+//   - config vars are objects that reference the config var by name
+//   - json, list and object values are decoded from JSON
+func coerce(p api.Parameter, input interface{}) (interface{}, error) {
+	s, isString := input.(string)
+
+	switch p.Type {
+	case api.TypeString, api.TypeUpload:
+		if !isString {
+			return nil, errors.New("value must be a string")
+		}
+		return s, nil
+
+	case api.TypeBoolean:
+		if b, ok := input.(bool); ok {
+			return b, nil
+		}
+		if isString {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b, nil
+			}
+		}
+		return nil, errors.Errorf("value must be a boolean, got %s", describe(input))
+
+	case api.TypeInteger:
+		if isString {
+			if i, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+				return i, nil
+			}
+		} else if f, ok := toFloat(input); ok && f == math.Trunc(f) {
+			return int(f), nil
+		}
+		return nil, errors.Errorf("value must be an integer, got %s", describe(input))
+
+	case api.TypeFloat:
+		if isString {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return f, nil
+			}
+		} else if f, ok := toFloat(input); ok {
+			return f, nil
+		}
+		return nil, errors.Errorf("value must be a number, got %s", describe(input))
+
+	case api.TypeDate:
+		if isString {
+			s = strings.TrimSpace(s)
+			if t, err := time.Parse(dateLayout, s); err == nil {
+				return t.Format(dateLayout), nil
+			}
+			// Accept datetimes, keeping only the date.
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t.Format(dateLayout), nil
+			}
+		}
+		return nil, errors.Errorf("value must be a date, e.g. 2006-01-02, got %s", describe(input))
+
+	case api.TypeDatetime:
+		if isString {
+			s = strings.TrimSpace(s)
+			for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", dateLayout} {
+				// Datetimes without a time zone are in UTC.
+				if t, err := time.Parse(layout, s); err == nil {
+					return t.Format(time.RFC3339), nil
+				}
+			}
+		}
+		return nil, errors.Errorf("value must be a datetime, e.g. 2006-01-02T15:04:05Z, got %s", describe(input))
+
+	case api.TypeConfigVar:
+		if name := configVarName(input); name != "" {
+			return map[string]interface{}{
+				"__airplaneType": "configvar",
+				"name":           name,
+			}, nil
+		}
+		return nil, errors.Errorf("value must be the name of a config var, got %s", describe(input))
+
+	case api.TypeJSON:
+		if isString {
+			var v interface{}
+			if err := json.Unmarshal([]byte(s), &v); err != nil {
+				return nil, errors.Errorf("value must be JSON: %s", err)
+			}
+			return v, nil
+		}
+		return input, nil
+
+	case api.TypeList:
+		if isString {
+			s = strings.TrimSpace(s)
+			if strings.HasPrefix(s, "[") {
+				var list []interface{}
+				if err := json.Unmarshal([]byte(s), &list); err != nil {
+					return nil, errors.Errorf("value must be a JSON list: %s", err)
+				}
+				input = list
+			} else {
+				// Comma-separated values.
+				var list []interface{}
+				for _, item := range strings.Split(s, ",") {
+					list = append(list, strings.TrimSpace(item))
+				}
+				return list, nil
+			}
+		}
+		if list, ok := toList(input); ok {
+			return list, nil
+		}
+		return nil, errors.Errorf("value must be a list of strings, got %s", describe(input))
+
+	case api.TypeObject:
+		if isString {
+			var m map[string]interface{}
+			if err := json.Unmarshal([]byte(s), &m); err != nil {
+				return nil, errors.Errorf("value must be a JSON object: %s", err)
+			}
+			return m, nil
+		}
+		if m, ok := input.(map[string]interface{}); ok {
+			return m, nil
+		}
+		return nil, errors.Errorf("value must be an object, got %s", describe(input))
+
+	default:
+		return nil, errors.Errorf("unknown parameter type: %s", p.Type)
+	}
+}
+
+// checkType checks that v is a value of the type of p, as returned by
+// coerce, returning a description of the problem if it is not.
+func checkType(p api.Parameter, v interface{}) string {
+	switch p.Type {
+	case api.TypeString, api.TypeUpload:
+		if _, ok := v.(string); !ok {
+			return "must be a string"
+		}
+	case api.TypeBoolean:
+		if _, ok := v.(bool); !ok {
+			return "must be a boolean"
+		}
+	case api.TypeInteger:
+		if f, ok := toFloat(v); !ok || f != math.Trunc(f) {
+			return "must be an integer"
+		}
+	case api.TypeFloat:
+		if _, ok := toFloat(v); !ok {
+			return "must be a number"
+		}
+	case api.TypeDate, api.TypeDatetime:
+		if s, ok := v.(string); !ok || !isTime(p.Type, s) {
+			if p.Type == api.TypeDate {
+				return "must be a date, e.g. 2006-01-02"
+			}
+			return "must be an RFC 3339 datetime, e.g. 2006-01-02T15:04:05Z"
+		}
+	case api.TypeConfigVar:
+		if configVarName(v) == "" {
+			return "must be the name of a config var"
+		}
+	case api.TypeList:
+		if _, ok := toList(v); !ok {
+			return "must be a list of strings"
+		}
+	case api.TypeObject:
+		if _, ok := v.(map[string]interface{}); !ok {
+			return "must be an object"
+		}
+	}
+	return ""
+}
+
+// configVarName returns the name of the config var that v refers to. Config
+// vars are referenced by name, as `{"config": name}`, or in the format used
+// by the API.
+func configVarName(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]interface{}:
+		if name, ok := v["config"].(string); ok {
+			return name
+		}
+		if v["__airplaneType"] == "configvar" {
+			name, _ := v["name"].(string)
+			return name
+		}
+	}
+	return ""
+}
+
+// toList converts a list of strings to the type used for lists.
+func toList(v interface{}) ([]interface{}, bool) {
+	switch v := v.(type) {
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+		return list, true
+	case []interface{}:
+		for _, item := range v {
+			if _, ok := item.(string); !ok {
+				return nil, false
+			}
+		}
+		return v, true
+	}
+	return nil, false
+}
+
+func isTime(typ api.Type, s string) bool {
+	_, err := ParseTime(typ, s)
+	return err == nil
+}
+
+// ParseTime parses the value of a date or datetime parameter.
+func ParseTime(typ api.Type, s string) (time.Time, error) {
+	if typ == api.TypeDatetime {
+		return time.Parse(time.RFC3339, s)
+	}
+	return time.Parse(dateLayout, s)
+}
+
+func describe(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return "an invalid value"
+	}
+	return string(buf)
+}
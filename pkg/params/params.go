@@ -0,0 +1,348 @@
+// Package params parses and validates parameter values before a task is run.
+//
+// Values are usually typed on the command line as strings, or read from a
+// JSON file. Coerce converts them to the values that tasks receive, applying
+// defaults and checking constraints, so that invalid values are reported
+// before the task runs rather than deep inside task code.
+//
+// Parameters of task definitions can be converted with
+// definitions.ParametersToAPI.
+package params
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/airplanedev/lib/pkg/api"
+)
+
+// Values are parameter values, keyed by parameter slug.
+type Values = map[string]interface{}
+
+// ValueError is a problem with the value of a single parameter.
+type ValueError struct {
+	// Param is the slug of the parameter.
+	Param   string
+	Message string
+}
+
+func (e ValueError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Param, e.Message)
+}
+
+// ErrInvalidValues is returned when one or more parameter values are
+// invalid.
+type ErrInvalidValues struct {
+	Errors []ValueError
+}
+
+// Error implementation.
+func (err ErrInvalidValues) Error() string {
+	msgs := make([]string, 0, len(err.Errors))
+	for _, e := range err.Errors {
+		msgs = append(msgs, e.Error())
+	}
+	return fmt.Sprintf("invalid parameter values: %s", strings.Join(msgs, "; "))
+}
+
+// ExplainError implementation.
+func (err ErrInvalidValues) ExplainError() string {
+	msgs := make([]string, 0, len(err.Errors))
+	for _, e := range err.Errors {
+		msgs = append(msgs, "- "+e.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Coerce converts inputs to values of the types of params, applies defaults
+// and checks constraints.
+//
+// Each input is either a string, as typed on the command line, or a value
+// that was already decoded from JSON. Empty strings are treated as missing.
+// Parameters hidden by their visibleIf condition are left out of the
+// returned values.
+//
+// Every problem is returned in an ErrInvalidValues.
+func Coerce(params api.Parameters, inputs Values) (Values, error) {
+	var errs []ValueError
+	values := Values{}
+	for _, p := range params {
+		input, ok := inputs[p.Slug]
+		if !ok || isEmpty(input) {
+			continue
+		}
+		v, err := coerce(p, input)
+		if err != nil {
+			errs = append(errs, ValueError{Param: p.Slug, Message: err.Error()})
+			continue
+		}
+		values[p.Slug] = v
+	}
+
+	for _, p := range params {
+		if _, ok := values[p.Slug]; ok || p.Default == nil {
+			continue
+		}
+		// Defaults are in the same format as decoded JSON inputs.
+		if v, err := coerce(p, p.Default); err == nil {
+			values[p.Slug] = v
+		}
+	}
+
+	errs = append(errs, check(params, values, inputs)...)
+	if len(errs) > 0 {
+		return nil, ErrInvalidValues{Errors: sortErrors(params, errs)}
+	}
+
+	for _, p := range params {
+		if !visible(p, values) {
+			delete(values, p.Slug)
+		}
+	}
+	return values, nil
+}
+
+// Validate checks values, which must already be of the types of params,
+// without coercing them or applying defaults.
+func Validate(params api.Parameters, values Values) error {
+	var errs []ValueError
+	typed := Values{}
+	for _, p := range params {
+		v, ok := values[p.Slug]
+		if !ok || isEmpty(v) {
+			continue
+		}
+		if msg := checkType(p, v); msg != "" {
+			errs = append(errs, ValueError{Param: p.Slug, Message: "value " + msg})
+			continue
+		}
+		if list, ok := toList(v); ok && p.Type == api.TypeList {
+			v = list
+		}
+		typed[p.Slug] = v
+	}
+
+	errs = append(errs, check(params, typed, values)...)
+	if len(errs) > 0 {
+		return ErrInvalidValues{Errors: sortErrors(params, errs)}
+	}
+	return nil
+}
+
+// CheckValue checks that v is a value of the type of p that satisfies its
+// regex, range and length constraints, returning a description of the
+// problem if it does not. Options and conditions are not checked.
+func CheckValue(p api.Parameter, v interface{}) string {
+	if msg := checkType(p, v); msg != "" {
+		return msg
+	}
+	if list, ok := toList(v); ok && p.Type == api.TypeList {
+		v = list
+	}
+	return checkConstraints(p, v)
+}
+
+// check checks the constraints of params against values, which are of the
+// right types. Inputs are the values as given, and are used to report
+// missing and unknown parameters.
+func check(params api.Parameters, values Values, inputs Values) []ValueError {
+	var errs []ValueError
+	add := func(slug, msg string) {
+		errs = append(errs, ValueError{Param: slug, Message: msg})
+	}
+
+	known := map[string]bool{}
+	for _, p := range params {
+		known[p.Slug] = true
+	}
+
+	for _, p := range params {
+		if !visible(p, values) {
+			continue
+		}
+		v, ok := values[p.Slug]
+		if !ok {
+			if input, given := inputs[p.Slug]; given && !isEmpty(input) {
+				// The value could not be coerced, which is already reported.
+				continue
+			}
+			if required(p, values) {
+				add(p.Slug, "value is required")
+			}
+			continue
+		}
+		if msg := checkOptions(p, v); msg != "" {
+			add(p.Slug, "value "+msg)
+		} else if msg := checkConstraints(p, v); msg != "" {
+			add(p.Slug, "value "+msg)
+		}
+	}
+
+	var unknown []string
+	for slug := range inputs {
+		if !known[slug] {
+			unknown = append(unknown, slug)
+		}
+	}
+	sort.Strings(unknown)
+	for _, slug := range unknown {
+		add(slug, "unknown parameter")
+	}
+	return errs
+}
+
+// sortErrors sorts errs in the order of params. Errors of unknown parameters
+// come last.
+func sortErrors(params api.Parameters, errs []ValueError) []ValueError {
+	index := map[string]int{}
+	for i, p := range params {
+		index[p.Slug] = i
+	}
+	position := func(slug string) int {
+		if i, ok := index[slug]; ok {
+			return i
+		}
+		return len(params)
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return position(errs[i].Param) < position(errs[j].Param)
+	})
+	return errs
+}
+
+func visible(p api.Parameter, values Values) bool {
+	return p.Constraints.VisibleIf == nil || holds(*p.Constraints.VisibleIf, values)
+}
+
+func required(p api.Parameter, values Values) bool {
+	if p.Constraints.RequiredIf != nil {
+		return holds(*p.Constraints.RequiredIf, values)
+	}
+	return !p.Constraints.Optional
+}
+
+// holds returns true if the condition is satisfied by values.
+func holds(c api.ParamCondition, values Values) bool {
+	v, ok := values[c.Param]
+	if !ok || isEmpty(v) {
+		return false
+	}
+	if c.Equals == nil {
+		return true
+	}
+	return equal(v, c.Equals)
+}
+
+// checkOptions checks that v is one of the options of p. Every item of list
+// values must be an option.
+func checkOptions(p api.Parameter, v interface{}) string {
+	// Config var options are resolved by the API.
+	if len(p.Constraints.Options) == 0 || p.Type == api.TypeConfigVar {
+		return ""
+	}
+
+	items := []interface{}{v}
+	if list, ok := v.([]interface{}); ok {
+		items = list
+	}
+	for _, item := range items {
+		found := false
+		for _, o := range p.Constraints.Options {
+			if equal(item, o.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "must be one of the options"
+		}
+	}
+	return ""
+}
+
+// checkConstraints checks that v satisfies the regex, range and length
+// constraints of p.
+func checkConstraints(p api.Parameter, v interface{}) string {
+	c := p.Constraints
+	if s, ok := v.(string); ok && p.Type == api.TypeString && c.Regex != "" {
+		if re, err := regexp.Compile(c.Regex); err == nil && !re.MatchString(s) {
+			return fmt.Sprintf("must match regex %q", c.Regex)
+		}
+	}
+
+	if f, ok := toFloat(v); ok {
+		if c.Min != nil && f < *c.Min {
+			return fmt.Sprintf("must be at least %s", strconv.FormatFloat(*c.Min, 'f', -1, 64))
+		}
+		if c.Max != nil && f > *c.Max {
+			return fmt.Sprintf("must be at most %s", strconv.FormatFloat(*c.Max, 'f', -1, 64))
+		}
+	}
+
+	length, unit := -1, ""
+	switch v := v.(type) {
+	case string:
+		if p.Type == api.TypeString {
+			length, unit = utf8.RuneCountInString(v), "characters"
+		}
+	case []interface{}:
+		length, unit = len(v), "items"
+	}
+	if length >= 0 {
+		if c.MinLength != nil && length < *c.MinLength {
+			return fmt.Sprintf("must have at least %d %s", *c.MinLength, unit)
+		}
+		if c.MaxLength != nil && length > *c.MaxLength {
+			return fmt.Sprintf("must have at most %d %s", *c.MaxLength, unit)
+		}
+	}
+
+	if s, ok := v.(string); ok && (c.MinDate != "" || c.MaxDate != "") {
+		t, err := ParseTime(p.Type, s)
+		if err != nil {
+			return ""
+		}
+		if min, err := ParseTime(p.Type, c.MinDate); err == nil && t.Before(min) {
+			return fmt.Sprintf("must not be before %s", c.MinDate)
+		}
+		if max, err := ParseTime(p.Type, c.MaxDate); err == nil && t.After(max) {
+			return fmt.Sprintf("must not be after %s", c.MaxDate)
+		}
+	}
+	return ""
+}
+
+func isEmpty(v interface{}) bool {
+	return v == nil || v == ""
+}
+
+// equal compares parameter values, treating numbers of different types as
+// equal if they have the same value.
+func equal(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
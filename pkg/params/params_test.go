@@ -0,0 +1,217 @@
+package params
+
+import (
+	"testing"
+
+	"github.com/airplanedev/lib/pkg/api"
+	"github.com/airplanedev/lib/pkg/utils/pointers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoerce(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		params   api.Parameters
+		inputs   Values
+		expected Values
+		errs     []ValueError
+	}{
+		{
+			name: "strings",
+			params: api.Parameters{
+				{Slug: "count", Type: api.TypeInteger},
+				{Slug: "ratio", Type: api.TypeFloat},
+				{Slug: "dry", Type: api.TypeBoolean},
+				{Slug: "day", Type: api.TypeDate},
+				{Slug: "at", Type: api.TypeDatetime},
+				{Slug: "db", Type: api.TypeConfigVar},
+				{Slug: "tags", Type: api.TypeList},
+				{Slug: "opts", Type: api.TypeObject},
+				{Slug: "raw", Type: api.TypeJSON},
+			},
+			inputs: Values{
+				"count": " 3",
+				"ratio": "0.5",
+				"dry":   "true",
+				"day":   "2022-03-04T10:00:00Z",
+				"at":    "2022-03-04 10:30:00",
+				"db":    "DB_URL",
+				"tags":  "a, b",
+				"opts":  `{"a": 1}`,
+				"raw":   `[1, "x"]`,
+			},
+			expected: Values{
+				"count": 3,
+				"ratio": 0.5,
+				"dry":   true,
+				"day":   "2022-03-04",
+				"at":    "2022-03-04T10:30:00Z",
+				"db":    map[string]interface{}{"__airplaneType": "configvar", "name": "DB_URL"},
+				"tags":  []interface{}{"a", "b"},
+				"opts":  map[string]interface{}{"a": float64(1)},
+				"raw":   []interface{}{float64(1), "x"},
+			},
+		},
+		{
+			name: "decoded JSON",
+			params: api.Parameters{
+				{Slug: "count", Type: api.TypeInteger},
+				{Slug: "dry", Type: api.TypeBoolean},
+				{Slug: "db", Type: api.TypeConfigVar},
+				{Slug: "tags", Type: api.TypeList},
+			},
+			inputs: Values{
+				"count": float64(3),
+				"dry":   false,
+				"db":    map[string]interface{}{"config": "DB_URL"},
+				"tags":  []interface{}{"a"},
+			},
+			expected: Values{
+				"count": 3,
+				"dry":   false,
+				"db":    map[string]interface{}{"__airplaneType": "configvar", "name": "DB_URL"},
+				"tags":  []interface{}{"a"},
+			},
+		},
+		{
+			name: "defaults",
+			params: api.Parameters{
+				{Slug: "name", Type: api.TypeString, Default: "world"},
+				{Slug: "count", Type: api.TypeInteger, Default: float64(1)},
+				{Slug: "day", Type: api.TypeDate, Constraints: api.Constraints{Optional: true}},
+			},
+			inputs: Values{
+				"name": "",
+			},
+			expected: Values{
+				"name":  "world",
+				"count": 1,
+			},
+		},
+		{
+			name: "conditions",
+			params: api.Parameters{
+				{Slug: "mode", Type: api.TypeString},
+				{
+					Slug: "query",
+					Type: api.TypeString,
+					Constraints: api.Constraints{
+						VisibleIf: &api.ParamCondition{Param: "mode", Equals: "custom"},
+					},
+				},
+				{
+					Slug: "reason",
+					Type: api.TypeString,
+					Constraints: api.Constraints{
+						RequiredIf: &api.ParamCondition{Param: "mode", Equals: "custom"},
+					},
+				},
+			},
+			inputs: Values{
+				"mode":  "all",
+				"query": "select 1",
+			},
+			expected: Values{
+				"mode": "all",
+			},
+		},
+		{
+			name: "errors",
+			params: api.Parameters{
+				{Slug: "name", Type: api.TypeString},
+				{Slug: "count", Type: api.TypeInteger, Constraints: api.Constraints{Max: pointers.Float64(10)}},
+				{Slug: "ratio", Type: api.TypeFloat},
+				{Slug: "day", Type: api.TypeDate},
+				{
+					Slug: "mode",
+					Type: api.TypeString,
+					Constraints: api.Constraints{
+						Options: []api.ConstraintOption{{Value: "a"}, {Value: "b"}},
+					},
+				},
+				{Slug: "tags", Type: api.TypeList, Constraints: api.Constraints{MaxLength: pointers.Int(1)}},
+			},
+			inputs: Values{
+				"extra": "1",
+				"tags":  "a,b",
+				"ratio": "x",
+				"count": "11",
+				"day":   "yesterday",
+				"mode":  "c",
+			},
+			errs: []ValueError{
+				{Param: "name", Message: "value is required"},
+				{Param: "count", Message: "value must be at most 10"},
+				{Param: "ratio", Message: `value must be a number, got "x"`},
+				{Param: "day", Message: `value must be a date, e.g. 2006-01-02, got "yesterday"`},
+				{Param: "mode", Message: "value must be one of the options"},
+				{Param: "tags", Message: "value must have at most 1 items"},
+				{Param: "extra", Message: "unknown parameter"},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			values, err := Coerce(test.params, test.inputs)
+			if test.errs != nil {
+				require.Error(err)
+				require.Equal(ErrInvalidValues{Errors: test.errs}, err)
+				return
+			}
+			require.NoError(err)
+			require.Equal(test.expected, values)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	require := require.New(t)
+
+	params := api.Parameters{
+		{Slug: "count", Type: api.TypeInteger, Constraints: api.Constraints{Min: pointers.Float64(1)}},
+		{Slug: "day", Type: api.TypeDate, Constraints: api.Constraints{Optional: true}},
+		{
+			Slug: "tags",
+			Type: api.TypeList,
+			Constraints: api.Constraints{
+				Optional: true,
+				Options:  []api.ConstraintOption{{Value: "a"}, {Value: "b"}},
+			},
+		},
+	}
+
+	require.NoError(Validate(params, Values{
+		"count": 2,
+		"day":   "2022-03-04",
+		"tags":  []string{"a", "b"},
+	}))
+
+	err := Validate(params, Values{
+		"count": "2",
+		"day":   "2022-03-04T00:00:00Z",
+		"tags":  []string{"c"},
+	})
+	require.Equal(ErrInvalidValues{Errors: []ValueError{
+		{Param: "count", Message: "value must be an integer"},
+		{Param: "day", Message: "value must be a date, e.g. 2006-01-02"},
+		{Param: "tags", Message: "value must be one of the options"},
+	}}, err)
+
+	err = Validate(params, Values{"count": 0})
+	require.Equal(ErrInvalidValues{Errors: []ValueError{
+		{Param: "count", Message: "value must be at least 1"},
+	}}, err)
+}
+
+func TestCheckValue(t *testing.T) {
+	require := require.New(t)
+
+	min := 1.0
+	maxLength := 2
+	require.Equal("", CheckValue(api.Parameter{Type: api.TypeInteger, Constraints: api.Constraints{Min: &min}}, 3))
+	require.Equal("must be at least 1", CheckValue(api.Parameter{Type: api.TypeInteger, Constraints: api.Constraints{Min: &min}}, 0))
+	require.Equal("must be an integer", CheckValue(api.Parameter{Type: api.TypeInteger}, 1.5))
+	require.Equal("must be a date, e.g. 2006-01-02", CheckValue(api.Parameter{Type: api.TypeDate}, 20220304))
+	require.Equal("must have at most 2 items", CheckValue(api.Parameter{Type: api.TypeList, Constraints: api.Constraints{MaxLength: &maxLength}}, []string{"a", "b", "c"}))
+}
@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -27,6 +28,36 @@ func Comment(r Interface, taskURL string) string {
 	return r.FormatComment("Linked to " + taskURL + " [do not edit this line]")
 }
 
+// Unlink removes the comment generated by Comment from code, along with the
+// blank line that follows it. Code is returned as is if it is not linked.
+func Unlink(r Interface, code []byte) []byte {
+	m := commentRegex.FindSubmatch(code)
+	if m == nil {
+		return code
+	}
+	comment := []byte(Comment(r, string(m[1])))
+	start := bytes.Index(code, comment)
+	if start < 0 || (start > 0 && code[start-1] != '\n') {
+		return code
+	}
+
+	end := start + len(comment)
+	for i := 0; i < 2; i++ {
+		// Remove the line ending, and the next line if it is blank.
+		if bytes.HasPrefix(code[end:], []byte("\r\n")) {
+			end += 2
+		} else if bytes.HasPrefix(code[end:], []byte("\n")) {
+			end++
+		} else {
+			break
+		}
+	}
+
+	unlinked := make([]byte, 0, len(code)-(end-start))
+	unlinked = append(unlinked, code[:start]...)
+	return append(unlinked, code[end:]...)
+}
+
 // Slug returns the slug from the given file. An empty string is returned if a slug was not found.
 func Slug(filePath string) string {
 	file, err := os.Open(filePath)
@@ -51,3 +51,50 @@ console.log('ship it')`,
 		})
 	}
 }
+
+// hashComments is a runtime with `#` comments.
+type hashComments struct {
+	Interface
+}
+
+func (hashComments) FormatComment(s string) string {
+	return "# " + s
+}
+
+func TestUnlink(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{
+			name: "not linked",
+			in:   "echo hi\n",
+			out:  "echo hi\n",
+		},
+		{
+			name: "comment and blank line",
+			in:   "# Linked to https://app.airplane.dev/t/myslug [do not edit this line]\n\necho hi\n",
+			out:  "echo hi\n",
+		},
+		{
+			name: "after shebang",
+			in:   "#!/bin/bash\n# Linked to https://app.airplane.dev/t/myslug [do not edit this line]\n\n# Params\necho hi\n",
+			out:  "#!/bin/bash\n# Params\necho hi\n",
+		},
+		{
+			name: "crlf line endings",
+			in:   "# Linked to https://app.airplane.dev/t/myslug [do not edit this line]\r\n\r\necho hi\r\n",
+			out:  "echo hi\r\n",
+		},
+		{
+			name: "other comment style",
+			in:   "// Linked to https://app.airplane.dev/t/myslug [do not edit this line]\n\necho hi\n",
+			out:  "// Linked to https://app.airplane.dev/t/myslug [do not edit this line]\n\necho hi\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.out, string(Unlink(hashComments{}, []byte(test.in))))
+		})
+	}
+}
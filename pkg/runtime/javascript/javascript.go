@@ -32,9 +32,24 @@ func init() {
 var code = template.Must(template.New("js").Parse(`{{with .Comment -}}
 {{.}}
 
+{{end -}}
+{{with .Params -}}
+/**
+ * @typedef {Object} Params
+{{- range . }}
+ * @property {{ printf "{%s}" .Type }} {{ if .Optional }}[{{ .Slug }}]{{ else }}{{ .Slug }}{{ end }} {{ .Name }}
+{{- end }}
+ */
+
 {{end -}}
 // Put the main logic of the task in this function.
+{{- if .Params }}
+/** @param {Params} params */
+{{- end }}
 export default async function(params) {
+  {{- range .JSONParams }}
+  // params.{{ .Slug }} is already decoded from JSON into {{ .Description }}.
+  {{- end }}
   console.log('parameters:', params);
 
   // You can return data to show outputs to users.
@@ -48,7 +63,23 @@ export default async function(params) {
 
 // Data represents the data template.
 type data struct {
-	Comment string
+	Comment    string
+	Params     []param
+	JSONParams []jsonParam
+}
+
+// Param represents a parameter in the JSDoc type of params.
+type param struct {
+	Slug     string
+	Name     string
+	Type     string
+	Optional bool
+}
+
+// jsonParam is a parameter whose value is decoded from JSON.
+type jsonParam struct {
+	Slug        string
+	Description string
 }
 
 // Runtime implementaton.
@@ -59,6 +90,20 @@ func (r Runtime) Generate(t *runtime.Task) ([]byte, fs.FileMode, error) {
 	d := data{}
 	if t != nil {
 		d.Comment = runtime.Comment(r, t.URL)
+		for _, p := range t.Parameters {
+			d.Params = append(d.Params, param{
+				Slug:     p.Slug,
+				Name:     p.Name,
+				Type:     TypeOf(p.Type),
+				Optional: p.Optional,
+			})
+			if p.Type.IsJSON() {
+				d.JSONParams = append(d.JSONParams, jsonParam{
+					Slug:        p.Slug,
+					Description: describeJSON(p.Type),
+				})
+			}
+		}
 	}
 
 	var buf bytes.Buffer
@@ -69,6 +114,43 @@ func (r Runtime) Generate(t *runtime.Task) ([]byte, fs.FileMode, error) {
 	return buf.Bytes(), 0644, nil
 }
 
+// TypeOf translates the given type to a TypeScript type, which is also
+// understood by JSDoc.
+func TypeOf(t runtime.Type) string {
+	switch t {
+	case runtime.TypeInteger, runtime.TypeFloat:
+		return "number"
+	case runtime.TypeDate, runtime.TypeDatetime:
+		return "string"
+	case runtime.TypeBoolean:
+		return "boolean"
+	case runtime.TypeString:
+		return "string"
+	case runtime.TypeUpload:
+		return "string"
+	case runtime.TypeList:
+		return "string[]"
+	case runtime.TypeObject:
+		return "Record<string, unknown>"
+	case runtime.TypeConfigVar:
+		return "{ name: string; value: string }"
+	default:
+		return "unknown"
+	}
+}
+
+// describeJSON describes the JavaScript value of a JSON parameter.
+func describeJSON(t runtime.Type) string {
+	switch t {
+	case runtime.TypeList:
+		return "an array of strings"
+	case runtime.TypeObject:
+		return "an object"
+	default:
+		return "a JavaScript value"
+	}
+}
+
 // Workdir picks the working directory for commands to be executed from.
 //
 // For JS, that is the nearest parent directory containing a `package.json`.
@@ -72,3 +72,8 @@ func TestDev(tt *testing.T) {
 
 	runtimetest.Run(tt, ctx, tests)
 }
+
+func TestGenerate(t *testing.T) {
+	runtimetest.Golden(t, Runtime{}, &runtime.Task{URL: "https://app.airplane.dev/t/javascript_simple"}, "fixtures/generate/empty.js.golden")
+	runtimetest.Golden(t, Runtime{}, &runtimetest.GenerateTask, "fixtures/generate/params.js.golden")
+}
@@ -5,13 +5,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"html/template"
 	"io"
 	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"text/template"
 
 	"github.com/MakeNowJust/heredoc"
 	"github.com/airplanedev/lib/pkg/build"
@@ -30,9 +32,36 @@ func init() {
 var code = template.Must(template.New("py").Parse(`{{with .Comment -}}
 {{.}}
 
+{{end -}}
+{{with .Params -}}
+{{with $.Imports -}}
+from typing import {{.}}
+
+
+{{end -}}
+{{if $.Functional -}}
+Params = TypedDict(
+    "Params",
+    {
+    {{- range . }}
+        "{{ .Slug }}": {{ .Type }},
+    {{- end }}
+    },
+)
+{{- else -}}
+class Params(TypedDict):
+{{- range . }}
+    {{ .Slug }}: {{ .Type }}
+{{- end }}
+{{- end }}
+
+
 {{end -}}
 # Put the main logic of the task in the main function.
-def main(params):
+def main(params{{ if .Params }}: Params{{ end }}):
+    {{- range .JSONParams }}
+    # params["{{ .Slug }}"] is already decoded from JSON into {{ .Description }}.
+    {{- end }}
     print("parameters:", params)
 
     # You can return data to show outputs to users.
@@ -46,6 +75,25 @@ def main(params):
 // Data represents the data template.
 type data struct {
 	Comment string
+	// Imports are the names imported from the typing module.
+	Imports string
+	Params  []param
+	// Functional is true if Params must be declared with the functional
+	// TypedDict syntax, because not every slug is a Python identifier.
+	Functional bool
+	JSONParams []jsonParam
+}
+
+// Param represents a key of the Params TypedDict.
+type param struct {
+	Slug string
+	Type string
+}
+
+// jsonParam is a parameter whose value is decoded from JSON.
+type jsonParam struct {
+	Slug        string
+	Description string
 }
 
 // Runtime implementation.
@@ -152,6 +200,37 @@ func (r Runtime) Generate(t *runtime.Task) ([]byte, fs.FileMode, error) {
 	d := data{}
 	if t != nil {
 		d.Comment = runtime.Comment(r, t.URL)
+		imports := map[string]bool{}
+		for _, p := range t.Parameters {
+			typ := typeof(p.Type)
+			if p.Optional {
+				typ = "Optional[" + typ + "]"
+			}
+			d.Params = append(d.Params, param{
+				Slug: p.Slug,
+				Type: typ,
+			})
+			for _, name := range typingName.FindAllString(typ, -1) {
+				imports[name] = true
+			}
+			if !identifier.MatchString(p.Slug) || pythonKeywords[p.Slug] {
+				d.Functional = true
+			}
+			if p.Type.IsJSON() {
+				d.JSONParams = append(d.JSONParams, jsonParam{
+					Slug:        p.Slug,
+					Description: describeJSON(p.Type),
+				})
+			}
+		}
+		if len(d.Params) > 0 {
+			names := []string{"TypedDict"}
+			for name := range imports {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			d.Imports = strings.Join(names, ", ")
+		}
 	}
 
 	var buf bytes.Buffer
@@ -162,6 +241,59 @@ func (r Runtime) Generate(t *runtime.Task) ([]byte, fs.FileMode, error) {
 	return buf.Bytes(), 0644, nil
 }
 
+// typingName matches the names that type annotations import from the
+// typing module.
+var typingName = regexp.MustCompile(`[A-Z][A-Za-z]*`)
+
+// identifier matches Python identifiers.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// pythonKeywords are the keywords that can't be used as identifiers.
+var pythonKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true,
+	"assert": true, "async": true, "await": true, "break": true,
+	"class": true, "continue": true, "def": true, "del": true, "elif": true,
+	"else": true, "except": true, "finally": true, "for": true, "from": true,
+	"global": true, "if": true, "import": true, "in": true, "is": true,
+	"lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true,
+	"raise": true, "return": true, "try": true, "while": true, "with": true,
+	"yield": true,
+}
+
+// typeof translates the given type to a Python type annotation.
+func typeof(t runtime.Type) string {
+	switch t {
+	case runtime.TypeInteger:
+		return "int"
+	case runtime.TypeFloat:
+		return "float"
+	case runtime.TypeBoolean:
+		return "bool"
+	case runtime.TypeString, runtime.TypeUpload, runtime.TypeDate, runtime.TypeDatetime:
+		return "str"
+	case runtime.TypeConfigVar:
+		return "Dict[str, str]"
+	case runtime.TypeList:
+		return "List[str]"
+	case runtime.TypeObject:
+		return "Dict[str, Any]"
+	default:
+		return "Any"
+	}
+}
+
+// describeJSON describes the Python value of a JSON parameter.
+func describeJSON(t runtime.Type) string {
+	switch t {
+	case runtime.TypeList:
+		return "a list of strings"
+	case runtime.TypeObject:
+		return "a dict"
+	default:
+		return "a Python value"
+	}
+}
+
 // Workdir implementation.
 func (r Runtime) Workdir(path string) (string, error) {
 	return r.Root(path)
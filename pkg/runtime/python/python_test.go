@@ -4,6 +4,8 @@ import (
 	"context"
 	"testing"
 
+	"github.com/airplanedev/lib/pkg/runtime"
+	"github.com/airplanedev/lib/pkg/runtime/runtimetest"
 	"github.com/airplanedev/lib/pkg/utils/logger"
 	"github.com/stretchr/testify/require"
 )
@@ -15,3 +17,16 @@ func TestCheckPythonInstalled(t *testing.T) {
 	err := checkPythonInstalled(context.Background(), &logger.MockLogger{})
 	require.NoError(err)
 }
+
+func TestGenerate(t *testing.T) {
+	runtimetest.Golden(t, Runtime{}, &runtime.Task{URL: "https://app.airplane.dev/t/python_simple"}, "fixtures/generate/empty.py.golden")
+	runtimetest.Golden(t, Runtime{}, &runtimetest.GenerateTask, "fixtures/generate/params.py.golden")
+	// Slugs that aren't identifiers need the functional TypedDict syntax.
+	runtimetest.Golden(t, Runtime{}, &runtime.Task{
+		URL: "https://app.airplane.dev/t/python_keywords",
+		Parameters: runtime.Parameters{
+			{Name: "From", Slug: "from", Type: runtime.TypeDate},
+			{Name: "2FA code", Slug: "2fa_code", Type: runtime.TypeString, Optional: true},
+		},
+	}, "fixtures/generate/keywords.py.golden")
+}
@@ -92,7 +92,8 @@ type PrepareRunOptions struct {
 	Path string
 
 	// ParamValues specifies the user-provided parameter values to
-	// execute this run with.
+	// execute this run with. Values typed by users can be converted
+	// with params.Coerce.
 	ParamValues Values
 
 	// KindOptions specifies any runtime-specific task configuration.
@@ -4,6 +4,8 @@ package runtimetest
 
 import (
 	"context"
+	"flag"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -19,6 +21,44 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+var update = flag.Bool("update", false, "update golden files")
+
+// GenerateTask is a task with a parameter of every type.
+var GenerateTask = runtime.Task{
+	URL: "https://app.airplane.dev/t/generate",
+	Parameters: runtime.Parameters{
+		{Name: "Name", Slug: "name", Type: runtime.TypeString},
+		{Name: "Dry run", Slug: "dry_run", Type: runtime.TypeBoolean},
+		{Name: "Report", Slug: "report", Type: runtime.TypeUpload, Optional: true},
+		{Name: "Count", Slug: "count", Type: runtime.TypeInteger},
+		{Name: "Ratio", Slug: "ratio", Type: runtime.TypeFloat, Optional: true},
+		{Name: "Day", Slug: "day", Type: runtime.TypeDate},
+		{Name: "Started at", Slug: "started_at", Type: runtime.TypeDatetime},
+		{Name: "Database", Slug: "db", Type: runtime.TypeConfigVar},
+		{Name: "Payload", Slug: "payload", Type: runtime.TypeJSON},
+		{Name: "Tags", Slug: "tags", Type: runtime.TypeList},
+		{Name: "Options", Slug: "options", Type: runtime.TypeObject, Optional: true},
+	},
+}
+
+// Golden generates code for task with r and compares it to the golden file
+// at path. Run tests with `-update` to rewrite golden files.
+func Golden(t *testing.T, r runtime.Interface, task *runtime.Task, path string) {
+	require := require.New(t)
+
+	code, _, err := r.Generate(task)
+	require.NoError(err)
+
+	if *update {
+		require.NoError(os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(ioutil.WriteFile(path, code, 0644))
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	require.NoError(err, "unable to read golden file, run with -update to create it")
+	require.Equal(string(expected), string(code))
+}
+
 type Test struct {
 	Kind build.TaskKind
 	Opts runtime.PrepareRunOptions
@@ -3,12 +3,13 @@ package shell
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
-	"html/template"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
 
 	"github.com/airplanedev/lib/pkg/build"
 	"github.com/airplanedev/lib/pkg/runtime"
@@ -31,6 +32,12 @@ var code = template.Must(template.New("sh").Parse(`#!/bin/bash
 
 {{end -}}
 # Params are in environment variables as PARAM_{SLUG}, e.g. PARAM_USER_ID
+{{- range .Params }}
+#   {{ .Var }} ({{ .Type }}{{ if .Optional }}, optional{{ end }}){{ with .Name }}: {{ . }}{{ end }}
+{{- end }}
+{{- range .JSONParams }}
+# PARAM_{{ . }} is JSON-encoded, decode it with jq, e.g. echo "$PARAM_{{ . }}" | jq .
+{{- end }}
 echo "Hello World!"
 echo "Printing env for debugging purposes:"
 env
@@ -39,6 +46,18 @@ env
 // Data represents the data template.
 type data struct {
 	Comment string
+	Params  []param
+	// JSONParams are the uppercased slugs of parameters whose values are
+	// JSON-encoded.
+	JSONParams []string
+}
+
+// Param documents the environment variable of a parameter.
+type param struct {
+	Var      string
+	Name     string
+	Type     runtime.Type
+	Optional bool
 }
 
 // Runtime implementation.
@@ -92,7 +111,17 @@ func (r Runtime) PrepareRun(ctx context.Context, logger logger.Logger, opts runt
 		filepath.Join(root, entrypoint),
 	}
 	// TODO: this is a rough approximation of how interpolateParameters works in prod
-	for slug := range opts.ParamValues {
+	for slug, v := range opts.ParamValues {
+		switch v.(type) {
+		case []interface{}, map[string]interface{}:
+			// Lists and objects are passed as JSON.
+			buf, err := json.Marshal(v)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "serializing param value")
+			}
+			cmd = append(cmd, fmt.Sprintf("%s=%s", slug, buf))
+			continue
+		}
 		tmpl := fmt.Sprintf("%s={{%s}}", slug, slug)
 		val, err := handlebars.Render(tmpl, opts.ParamValues)
 		if err != nil {
@@ -108,6 +137,17 @@ func (r Runtime) Generate(t *runtime.Task) ([]byte, os.FileMode, error) {
 	d := data{}
 	if t != nil {
 		d.Comment = runtime.Comment(r, t.URL)
+		for _, p := range t.Parameters {
+			d.Params = append(d.Params, param{
+				Var:      "PARAM_" + strings.ToUpper(p.Slug),
+				Name:     p.Name,
+				Type:     p.Type,
+				Optional: p.Optional,
+			})
+			if p.Type.IsJSON() {
+				d.JSONParams = append(d.JSONParams, strings.ToUpper(p.Slug))
+			}
+		}
 	}
 
 	var buf bytes.Buffer
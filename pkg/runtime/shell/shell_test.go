@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/airplanedev/lib/pkg/runtime"
+	"github.com/airplanedev/lib/pkg/runtime/runtimetest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,3 +27,7 @@ echo "Printing env for debugging purposes:"
 env
 `, string(code))
 }
+
+func TestGenerate(t *testing.T) {
+	runtimetest.Golden(t, Runtime{}, &runtimetest.GenerateTask, "fixtures/generate/params.sh.golden")
+}
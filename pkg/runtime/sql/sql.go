@@ -1,11 +1,14 @@
 package sql
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
 
 	"github.com/airplanedev/lib/pkg/build"
 	"github.com/airplanedev/lib/pkg/runtime"
@@ -17,11 +20,23 @@ func init() {
 	runtime.Register(".sql", Runtime{})
 }
 
-// Code.
-var code = []byte(`-- Add your SQL queries here.
+// Code template.
+var code = template.Must(template.New("sql").Parse(`-- Add your SQL queries here.
 -- See SQL documentation: https://docs.airplane.dev/creating-tasks/sql
+{{- with .Params }}
+-- Parameters are passed as query args, e.g. :{{ index . 0 }}. Map them to
+-- parameters with queryArgs in the task definition.
+SELECT {{ range $i, $p := . }}{{ if $i }}, {{ end }}:{{ $p }}{{ end }};
+{{- else }}
 SELECT 1;
-`)
+{{- end }}
+`))
+
+// Data represents the data template.
+type data struct {
+	// Params are the slugs of the parameters.
+	Params []string
+}
 
 // Runtime implementation.
 type Runtime struct{}
@@ -33,7 +48,19 @@ func (r Runtime) PrepareRun(ctx context.Context, logger logger.Logger, opts runt
 
 // Generate implementation.
 func (r Runtime) Generate(t *runtime.Task) ([]byte, os.FileMode, error) {
-	return code, 0644, nil
+	d := data{}
+	if t != nil {
+		for _, p := range t.Parameters {
+			d.Params = append(d.Params, p.Slug)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := code.Execute(&buf, d); err != nil {
+		return nil, 0, fmt.Errorf("sql: template execute - %w", err)
+	}
+
+	return buf.Bytes(), 0644, nil
 }
 
 // Workdir implementation.
@@ -0,0 +1,13 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/airplanedev/lib/pkg/runtime"
+	"github.com/airplanedev/lib/pkg/runtime/runtimetest"
+)
+
+func TestGenerate(t *testing.T) {
+	runtimetest.Golden(t, Runtime{}, &runtime.Task{}, "fixtures/generate/empty.sql.golden")
+	runtimetest.Golden(t, Runtime{}, &runtimetest.GenerateTask, "fixtures/generate/params.sql.golden")
+}
@@ -19,8 +19,24 @@ const (
 	TypeDate      Type = "date"
 	TypeDatetime  Type = "datetime"
 	TypeConfigVar Type = "configvar"
+	// TypeJSON is any JSON value.
+	TypeJSON Type = "json"
+	// TypeList is a list of strings.
+	TypeList Type = "list"
+	// TypeObject is a JSON object.
+	TypeObject Type = "object"
 )
 
+// IsJSON returns true if values of the type are passed to tasks as JSON
+// values, rather than as scalars.
+func (t Type) IsJSON() bool {
+	switch t {
+	case TypeJSON, TypeList, TypeObject:
+		return true
+	}
+	return false
+}
+
 type Parameters []Parameter
 
 // Parameter represents a task parameter.
@@ -28,6 +44,8 @@ type Parameter struct {
 	Name string
 	Slug string
 	Type Type
+	// Optional is true if the parameter may be left out of runs.
+	Optional bool
 }
 
 // Values represent parameters values.
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"io/fs"
+	"regexp"
+	"strconv"
 	"text/template"
 
 	"github.com/airplanedev/lib/pkg/runtime"
@@ -22,7 +24,7 @@ var code = template.Must(template.New("ts").Parse(`{{with .Comment -}}
 {{end -}}
 type Params = {
   {{- range .Params }}
-  {{ .Name }}: {{ .Type }}
+  {{ .Name }}{{ if .Optional }}?{{ end }}: {{ .Type }}
   {{- end }}
 }
 
@@ -47,10 +49,14 @@ type data struct {
 
 // Param represents the parameter.
 type param struct {
-	Name string
-	Type string
+	Name     string
+	Type     string
+	Optional bool
 }
 
+// identifier matches property names that don't need to be quoted.
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
 // Runtime implementaton.
 type Runtime struct {
 	javascript.Runtime
@@ -62,9 +68,14 @@ func (r Runtime) Generate(t *runtime.Task) ([]byte, fs.FileMode, error) {
 	if t != nil {
 		d.Comment = runtime.Comment(r, t.URL)
 		for _, p := range t.Parameters {
+			name := p.Slug
+			if !identifier.MatchString(name) {
+				name = strconv.Quote(name)
+			}
 			d.Params = append(d.Params, param{
-				Name: p.Slug,
-				Type: typeof(p.Type),
+				Name:     name,
+				Type:     javascript.TypeOf(p.Type),
+				Optional: p.Optional,
 			})
 		}
 	}
@@ -76,21 +87,3 @@ func (r Runtime) Generate(t *runtime.Task) ([]byte, fs.FileMode, error) {
 
 	return buf.Bytes(), 0644, nil
 }
-
-// Typeof translates the given type to typescript.
-func typeof(t runtime.Type) string {
-	switch t {
-	case runtime.TypeInteger, runtime.TypeFloat:
-		return "number"
-	case runtime.TypeDate, runtime.TypeDatetime:
-		return "string"
-	case runtime.TypeBoolean:
-		return "boolean"
-	case runtime.TypeString:
-		return "string"
-	case runtime.TypeUpload:
-		return "string"
-	default:
-		return "unknown"
-	}
-}
@@ -110,3 +110,14 @@ func TestDev(tt *testing.T) {
 
 	runtimetest.Run(tt, ctx, tests)
 }
+
+func TestGenerate(t *testing.T) {
+	runtimetest.Golden(t, Runtime{}, &runtime.Task{URL: "https://app.airplane.dev/t/typescript_simple"}, "fixtures/generate/empty.ts.golden")
+	runtimetest.Golden(t, Runtime{}, &runtimetest.GenerateTask, "fixtures/generate/params.ts.golden")
+	runtimetest.Golden(t, Runtime{}, &runtime.Task{
+		URL: "https://app.airplane.dev/t/typescript_quoted",
+		Parameters: runtime.Parameters{
+			{Name: "2FA code", Slug: "2fa_code", Type: runtime.TypeString},
+		},
+	}, "fixtures/generate/quoted.ts.golden")
+}
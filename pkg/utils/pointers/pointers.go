@@ -11,3 +11,11 @@ func String(s string) *string {
 func Bool(b bool) *bool {
 	return &b
 }
+
+func Int(i int) *int {
+	return &i
+}
+
+func Float64(f float64) *float64 {
+	return &f
+}
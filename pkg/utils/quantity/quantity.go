@@ -0,0 +1,136 @@
+// Package quantity parses Kubernetes-style resource quantities, such as
+// `500m` CPUs or `2Gi` of memory.
+package quantity
+
+import (
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Quantity is an exact, non-negative amount of a resource.
+type Quantity struct {
+	s string
+	v *big.Rat
+}
+
+var suffixes = map[string]*big.Rat{
+	"":   big.NewRat(1, 1),
+	"n":  big.NewRat(1, 1e9),
+	"u":  big.NewRat(1, 1e6),
+	"m":  big.NewRat(1, 1e3),
+	"k":  big.NewRat(1e3, 1),
+	"M":  big.NewRat(1e6, 1),
+	"G":  big.NewRat(1e9, 1),
+	"T":  big.NewRat(1e12, 1),
+	"P":  big.NewRat(1e15, 1),
+	"E":  big.NewRat(1e18, 1),
+	"Ki": new(big.Rat).SetInt64(1 << 10),
+	"Mi": new(big.Rat).SetInt64(1 << 20),
+	"Gi": new(big.Rat).SetInt64(1 << 30),
+	"Ti": new(big.Rat).SetInt64(1 << 40),
+	"Pi": new(big.Rat).SetInt64(1 << 50),
+	"Ei": new(big.Rat).SetInt64(1 << 60),
+}
+
+var quantityRegex = regexp.MustCompile(`^(\d+(?:\.\d*)?|\.\d+)([a-zA-Z]*|[eE][+-]?\d+)$`)
+
+// Parse parses a quantity: a number, optionally followed by a decimal
+// suffix (n, u, m, k, M, G, T, P or E), a binary suffix (Ki, Mi, Gi, Ti, Pi
+// or Ei) or an exponent, e.g. `1.5`, `500m`, `2Gi` or `1e3`.
+func Parse(s string) (Quantity, error) {
+	m := quantityRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Quantity{}, errors.Errorf("invalid quantity %q", s)
+	}
+
+	v, ok := new(big.Rat).SetString(m[1])
+	if !ok {
+		return Quantity{}, errors.Errorf("invalid quantity %q", s)
+	}
+	suffix := m[2]
+	if len(suffix) > 0 && (suffix[0] == 'e' || suffix[0] == 'E') {
+		exp, err := strconv.Atoi(suffix[1:])
+		if err != nil || exp > 18 || exp < -9 {
+			return Quantity{}, errors.Errorf("invalid exponent in quantity %q", s)
+		}
+		scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exp))), nil))
+		if exp < 0 {
+			scale.Inv(scale)
+		}
+		v.Mul(v, scale)
+	} else {
+		mult, ok := suffixes[suffix]
+		if !ok {
+			return Quantity{}, errors.Errorf("invalid suffix %q in quantity %q", suffix, s)
+		}
+		v.Mul(v, mult)
+	}
+	return Quantity{s: strings.TrimSpace(s), v: v}, nil
+}
+
+// MustParse is like Parse, but panics if s is not a valid quantity.
+func MustParse(s string) Quantity {
+	q, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// String returns the quantity as it was written.
+func (q Quantity) String() string {
+	return q.s
+}
+
+// IsZero returns true if the quantity is zero.
+func (q Quantity) IsZero() bool {
+	return q.v == nil || q.v.Sign() == 0
+}
+
+// IsInteger returns true if the quantity is a whole number, e.g. of bytes.
+func (q Quantity) IsInteger() bool {
+	return q.v == nil || q.v.IsInt()
+}
+
+// Cmp compares two quantities, returning -1, 0 or 1 if q is less than, equal
+// to or greater than other.
+func (q Quantity) Cmp(other Quantity) int {
+	return q.rat().Cmp(other.rat())
+}
+
+// Value returns the quantity rounded up to a whole number.
+func (q Quantity) Value() int64 {
+	return ceil(q.rat())
+}
+
+// MilliValue returns the quantity in thousandths, rounded up, e.g. 500 for
+// `0.5` CPUs.
+func (q Quantity) MilliValue() int64 {
+	return ceil(new(big.Rat).Mul(q.rat(), big.NewRat(1000, 1)))
+}
+
+func (q Quantity) rat() *big.Rat {
+	if q.v == nil {
+		return new(big.Rat)
+	}
+	return q.v
+}
+
+func ceil(r *big.Rat) int64 {
+	n, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() > 0 {
+		n.Add(n, big.NewInt(1))
+	}
+	return n.Int64()
+}
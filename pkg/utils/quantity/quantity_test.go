@@ -0,0 +1,77 @@
+package quantity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		in    string
+		milli int64
+		value int64
+	}{
+		{"1", 1000, 1},
+		{"0.5", 500, 1},
+		{".25", 250, 1},
+		{"500m", 500, 1},
+		{"100u", 1, 1},
+		{"2k", 2000000, 2000},
+		{"1.5G", 1500000000000, 1500000000},
+		{"1Ki", 1024000, 1024},
+		{"2Gi", 2147483648000, 2147483648},
+		{"1e3", 1000000, 1000},
+		{"5E-1", 500, 1},
+		{"0", 0, 0},
+	} {
+		t.Run(test.in, func(t *testing.T) {
+			require := require.New(t)
+			q, err := Parse(test.in)
+			require.NoError(err)
+			require.Equal(test.in, q.String())
+			require.Equal(test.milli, q.MilliValue())
+			require.Equal(test.value, q.Value())
+		})
+	}
+
+	for in, msg := range map[string]string{
+		"":      `invalid quantity ""`,
+		"-1":    `invalid quantity "-1"`,
+		"1 Gi":  `invalid quantity "1 Gi"`,
+		"1GB":   `invalid suffix "GB" in quantity "1GB"`,
+		"1e100": `invalid exponent in quantity "1e100"`,
+	} {
+		_, err := Parse(in)
+		require.EqualError(t, err, msg)
+	}
+}
+
+func TestCmp(t *testing.T) {
+	require := require.New(t)
+
+	gi, err := Parse("1Gi")
+	require.NoError(err)
+	g, err := Parse("1G")
+	require.NoError(err)
+	mi, err := Parse("1024Mi")
+	require.NoError(err)
+
+	require.Equal(1, gi.Cmp(g))
+	require.Equal(-1, g.Cmp(gi))
+	require.Equal(0, gi.Cmp(mi))
+	require.True(gi.IsInteger())
+
+	half, err := Parse("500m")
+	require.NoError(err)
+	require.False(half.IsInteger())
+	require.False(half.IsZero())
+}
+
+func TestMustParse(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(-1, MustParse("0.0001").Cmp(MustParse("1m")))
+	require.Equal(0, MustParse("0.001").Cmp(MustParse("1m")))
+	require.Panics(func() { MustParse("1x") })
+}
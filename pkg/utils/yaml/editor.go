@@ -0,0 +1,871 @@
+package yaml
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Editor edits a YAML document in place.
+//
+// Rather than re-encoding the document, which discards blank lines and
+// reformats everything, the editor splices each change into the original
+// text. Comments, blank lines, key order, quoting and indentation are
+// preserved for everything that is not edited.
+//
+// Nodes are addressed by a path of mapping keys and sequence indexes, e.g.
+// `parameters[0].slug` or `node.envVars.API_KEY`. The empty path addresses
+// the top-level mapping. Keys that contain dots or brackets cannot be
+// addressed.
+type Editor struct {
+	src []byte
+	doc yaml.Node
+	// lines are the offsets of the start of each line in src.
+	lines []int
+}
+
+// NewEditor parses buf for editing.
+func NewEditor(buf []byte) (*Editor, error) {
+	e := &Editor{}
+	if err := e.reset(buf); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// EditFile applies edit to the YAML file at path. The file is only
+// rewritten if it changed.
+func EditFile(path string, edit func(e *Editor) error) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", path)
+	}
+	e, err := NewEditor(buf)
+	if err != nil {
+		return errors.Wrapf(err, "parsing %s", path)
+	}
+	if err := edit(e); err != nil {
+		return err
+	}
+	if bytes.Equal(buf, e.Bytes()) {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", path)
+	}
+	if err := ioutil.WriteFile(path, e.Bytes(), info.Mode()); err != nil {
+		return errors.Wrapf(err, "writing %s", path)
+	}
+	return nil
+}
+
+// Bytes returns the edited document.
+func (e *Editor) Bytes() []byte {
+	return e.src
+}
+
+// Get returns the node at path, or nil if there is none.
+func (e *Editor) Get(path string) (*yaml.Node, error) {
+	elems, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := e.resolve(elems)
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) != len(elems)+1 {
+		return nil, nil
+	}
+	return steps[len(steps)-1].node, nil
+}
+
+// Set sets the value at path, which may be any value that can be encoded
+// as YAML, including a *yaml.Node.
+//
+// If the path exists, its value is replaced; quoting is preserved when a
+// string replaces a string. Otherwise, the missing mappings along the path
+// are created and the key is added after the last entry of its mapping.
+// Sequence items cannot be created with Set, see Insert.
+func (e *Editor) Set(path string, value interface{}) error {
+	elems, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	return e.set(elems, value)
+}
+
+// InsertKey adds key to the mapping at path, in front of the entry that is
+// currently at position index. An index that is negative or past the last
+// entry appends the key. If key already exists, its value is replaced in
+// place instead.
+func (e *Editor) InsertKey(path string, index int, key string, value interface{}) error {
+	elems, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	return e.insertKey(elems, index, key, value)
+}
+
+// Insert adds value to the sequence at path, in front of the item that is
+// currently at position index. An index that is negative or equal to the
+// length of the sequence appends the item. If there is no sequence at path,
+// one is created.
+func (e *Editor) Insert(path string, index int, value interface{}) error {
+	elems, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	v, err := toNode(value)
+	if err != nil {
+		return err
+	}
+
+	steps, err := e.resolve(elems)
+	if err != nil {
+		return err
+	}
+	if len(steps) != len(elems)+1 || isNull(steps[len(steps)-1].node) {
+		return e.set(elems, &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{v}})
+	}
+	seq := steps[len(steps)-1].node
+	if seq.Kind != yaml.SequenceNode {
+		return errors.Errorf("%s is not a sequence", path)
+	}
+	n := len(seq.Content)
+	if index < 0 {
+		index = n
+	}
+	if index > n {
+		return errors.Errorf("cannot insert at %s[%d]: index out of range", path, index)
+	}
+
+	if f := flowIndex(steps, len(steps)); f >= 0 {
+		seq.Content = append(seq.Content[:index], append([]*yaml.Node{v}, seq.Content[index:]...)...)
+		return e.rerender(steps[f].node)
+	}
+
+	indent := seq.Column - 1
+	item, err := render(&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{v}}, indent)
+	if err != nil {
+		return err
+	}
+	text := spaces(indent) + item + "\n"
+	if index < n {
+		line := e.commentBlockStart(e.dashLine(seq, seq.Content[index]))
+		return e.splice(e.lines[line-1], e.lines[line-1], text)
+	}
+	return e.spliceAfterLine(e.endLine(seq.Content[n-1], indent), text)
+}
+
+// Append adds value to the end of the sequence at path.
+func (e *Editor) Append(path string, value interface{}) error {
+	return e.Insert(path, -1, value)
+}
+
+// Rename changes the key of the mapping entry at path, keeping its value.
+func (e *Editor) Rename(path string, key string) error {
+	elems, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	steps, err := e.resolve(elems)
+	if err != nil {
+		return err
+	}
+	if len(steps) != len(elems)+1 {
+		return errors.Errorf("cannot rename %s: not found", path)
+	}
+	s := steps[len(steps)-1]
+	if s.key == nil {
+		return errors.Errorf("cannot rename %s: not a mapping entry", path)
+	}
+	if existing := mappingValue(s.parent, key); existing != nil {
+		return errors.Errorf("cannot rename %s: %s already exists", path, key)
+	}
+
+	k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	if f := flowIndex(steps, len(steps)-1); f >= 0 {
+		s.parent.Content[2*s.index] = k
+		return e.rerender(steps[f].node)
+	}
+	start, end, ok := e.scalarSpan(s.key)
+	if !ok {
+		return errors.Errorf("cannot rename %s: unsupported key", path)
+	}
+	text, err := render(k, 0)
+	if err != nil {
+		return err
+	}
+	return e.splice(start, end, text)
+}
+
+// Delete removes the mapping entry or sequence item at path, along with
+// the comments directly above it. Deleting a path that does not exist is a
+// no-op.
+func (e *Editor) Delete(path string) error {
+	elems, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	if len(elems) == 0 {
+		return errors.New("cannot delete the root of the document")
+	}
+	steps, err := e.resolve(elems)
+	if err != nil {
+		return err
+	}
+	if len(steps) != len(elems)+1 {
+		return nil
+	}
+
+	s := steps[len(steps)-1]
+	parent := s.parent
+	if f := flowIndex(steps, len(steps)-1); f >= 0 {
+		if parent.Kind == yaml.MappingNode {
+			parent.Content = append(parent.Content[:2*s.index], parent.Content[2*s.index+2:]...)
+		} else {
+			parent.Content = append(parent.Content[:s.index], parent.Content[s.index+1:]...)
+		}
+		return e.rerender(steps[f].node)
+	}
+
+	// An empty block collection cannot be written, so replace the parent
+	// with an empty flow collection instead.
+	count := len(parent.Content)
+	if parent.Kind == yaml.MappingNode {
+		count /= 2
+	}
+	if count == 1 {
+		empty := &yaml.Node{Kind: parent.Kind, Tag: parent.Tag, Style: yaml.FlowStyle}
+		if len(steps) == 2 {
+			start, _ := e.entrySpan(s)
+			return e.splice(start, e.lineEnd(e.entryEndLine(s)), "{}\n")
+		}
+		return e.replace(steps[:len(steps)-1], empty)
+	}
+
+	if parent.Kind == yaml.MappingNode && !e.startsLine(s.key) {
+		// This is the first key of a mapping that starts on the same line
+		// as a sequence dash, e.g. `- slug: foo`. Move the next key up.
+		next := parent.Content[2*s.index+2]
+		return e.splice(e.offset(s.key.Line, s.key.Column), e.offset(next.Line, next.Column), "")
+	}
+
+	startLine := e.commentBlockStart(e.lineOf(e.entryStart(s)))
+	endLine := e.entryEndLine(s)
+	end := e.lineEnd(endLine)
+	// Avoid leaving two blank lines behind.
+	if (startLine == 1 || e.isBlank(startLine-1)) && endLine < len(e.lines) && e.isBlank(endLine+1) {
+		end = e.lineEnd(endLine + 1)
+	}
+	return e.splice(e.lines[startLine-1], end, "")
+}
+
+func (e *Editor) set(elems []pathElem, value interface{}) error {
+	if len(elems) == 0 {
+		return errors.New("cannot set the root of the document")
+	}
+	v, err := toNode(value)
+	if err != nil {
+		return err
+	}
+	steps, err := e.resolve(elems)
+	if err != nil {
+		return err
+	}
+	if len(steps) == len(elems)+1 {
+		return e.replace(steps, v)
+	}
+
+	rest := elems[len(steps)-1:]
+	for i := len(rest) - 1; i >= 1; i-- {
+		if rest[i].isIndex {
+			return errors.Errorf("cannot set %s: index out of range", formatPath(elems))
+		}
+		v = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: rest[i].key}, v,
+		}}
+	}
+	if rest[0].isIndex {
+		return errors.Errorf("cannot set %s: index out of range", formatPath(elems))
+	}
+	return e.insertEntry(steps, -1, rest[0].key, v)
+}
+
+func (e *Editor) insertKey(elems []pathElem, index int, key string, value interface{}) error {
+	v, err := toNode(value)
+	if err != nil {
+		return err
+	}
+	steps, err := e.resolve(elems)
+	if err != nil {
+		return err
+	}
+	if len(steps) != len(elems)+1 {
+		return e.set(append(elems, pathElem{key: key}), v)
+	}
+	if m := steps[len(steps)-1].node; m != nil && m.Kind == yaml.MappingNode && mappingValue(m, key) != nil {
+		return e.set(append(elems, pathElem{key: key}), v)
+	}
+	return e.insertEntry(steps, index, key, v)
+}
+
+// insertEntry adds a key to the mapping at the end of steps.
+func (e *Editor) insertEntry(steps []step, index int, key string, v *yaml.Node) error {
+	m := steps[len(steps)-1].node
+	k := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	entry := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{k, v}}
+
+	switch {
+	case m == nil:
+		// The document is empty.
+		text, err := render(entry, 0)
+		if err != nil {
+			return err
+		}
+		return e.spliceAfterLine(len(e.lines), text+"\n")
+
+	case isNull(m):
+		if len(steps) == 1 {
+			return errors.New("cannot edit document: top-level value is null")
+		}
+		return e.replace(steps, entry)
+
+	case m.Kind != yaml.MappingNode:
+		return errors.Errorf("cannot set %s: not a mapping", key)
+	}
+
+	n := len(m.Content) / 2
+	if index < 0 || index > n {
+		index = n
+	}
+	if f := flowIndex(steps, len(steps)); f >= 0 {
+		m.Content = append(m.Content[:2*index], append([]*yaml.Node{k, v}, m.Content[2*index:]...)...)
+		return e.rerender(steps[f].node)
+	}
+
+	first := m.Content[0]
+	indent := first.Column - 1
+	text, err := render(entry, indent)
+	if err != nil {
+		return err
+	}
+	if index == n {
+		last := step{parent: m, key: m.Content[2*n-2], node: m.Content[2*n-1], index: n - 1}
+		return e.spliceAfterLine(e.entryEndLine(last), spaces(indent)+text+"\n")
+	}
+
+	next := m.Content[2*index]
+	if !e.startsLine(next) {
+		at := e.offset(next.Line, next.Column)
+		return e.splice(at, at, text+"\n"+spaces(indent))
+	}
+	at := e.lines[e.commentBlockStart(next.Line)-1]
+	return e.splice(at, at, spaces(indent)+text+"\n")
+}
+
+// replace replaces the node at the end of steps with v.
+func (e *Editor) replace(steps []step, v *yaml.Node) error {
+	s := steps[len(steps)-1]
+	if s.parent == nil {
+		return errors.New("cannot replace the root of the document")
+	}
+
+	old := s.node
+	if old.Kind == yaml.ScalarNode && v.Kind == yaml.ScalarNode && old.Tag == "!!str" && v.Tag == "!!str" &&
+		v.Style == 0 && old.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		v.Style = old.Style
+	}
+
+	if f := flowIndex(steps, len(steps)-1); f >= 0 {
+		if s.parent.Kind == yaml.MappingNode {
+			s.parent.Content[2*s.index+1] = v
+		} else {
+			s.parent.Content[s.index] = v
+		}
+		return e.rerender(steps[f].node)
+	}
+
+	switch {
+	case old.Kind == yaml.ScalarNode && v.Kind == yaml.ScalarNode:
+		if start, end, ok := e.scalarSpan(old); ok {
+			text, err := render(v, 0)
+			if err != nil {
+				return err
+			}
+			if !strings.Contains(text, "\n") {
+				return e.splice(start, end, text)
+			}
+		}
+		if v.LineComment == "" {
+			v.LineComment = old.LineComment
+		}
+	case old.Kind == v.Kind && old.Style&yaml.FlowStyle != 0 && v.Style == 0:
+		v.Style = yaml.FlowStyle
+	}
+
+	// Replace the entire entry.
+	var node *yaml.Node
+	var indent int
+	if s.parent.Kind == yaml.MappingNode {
+		k := *s.key
+		k.HeadComment, k.FootComment = "", ""
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{&k, v}}
+		indent = s.key.Column - 1
+	} else {
+		node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{v}}
+		indent = s.parent.Column - 1
+	}
+	text, err := render(node, indent)
+	if err != nil {
+		return err
+	}
+	start, endLine := e.entrySpan(s)
+	return e.splice(start, e.lineEnd(endLine), text+"\n")
+}
+
+// rerender replaces the text of a flow collection with the encoding of its
+// (edited) node.
+func (e *Editor) rerender(n *yaml.Node) error {
+	start := e.offset(n.Line, n.Column)
+	end, ok := e.flowEnd(start)
+	if !ok {
+		return errors.New("cannot find the end of flow collection")
+	}
+	text, err := render(n, 0)
+	if err != nil {
+		return err
+	}
+	return e.splice(start, end, text)
+}
+
+func (e *Editor) splice(start, end int, text string) error {
+	buf := make([]byte, 0, len(e.src)-(end-start)+len(text))
+	buf = append(buf, e.src[:start]...)
+	buf = append(buf, text...)
+	buf = append(buf, e.src[end:]...)
+	if err := e.reset(buf); err != nil {
+		return errors.Wrap(err, "edit produced invalid yaml")
+	}
+	return nil
+}
+
+// spliceAfterLine inserts text after line, which may be zero.
+func (e *Editor) spliceAfterLine(line int, text string) error {
+	at := e.lineEnd(line)
+	if at > 0 && e.src[at-1] != '\n' {
+		text = "\n" + text
+	}
+	return e.splice(at, at, text)
+}
+
+func (e *Editor) reset(buf []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(buf, &doc); err != nil {
+		return errors.Wrap(err, "parsing yaml")
+	}
+	e.src = buf
+	e.doc = doc
+	e.lines = []int{0}
+	for i, b := range buf {
+		if b == '\n' && i+1 < len(buf) {
+			e.lines = append(e.lines, i+1)
+		}
+	}
+	if len(buf) == 0 {
+		e.lines = nil
+	}
+	return nil
+}
+
+// step is a node along a path.
+type step struct {
+	// parent is the collection that contains node, or nil for the root.
+	parent *yaml.Node
+	// key is the key of node if parent is a mapping.
+	key  *yaml.Node
+	node *yaml.Node
+	// index is the position of node in parent, counting mapping entries
+	// rather than nodes.
+	index int
+}
+
+// resolve follows elems from the root. The first step is the root, which
+// is nil for an empty document. Resolution stops at the first path element
+// that does not exist, or at a null value.
+func (e *Editor) resolve(elems []pathElem) ([]step, error) {
+	var root *yaml.Node
+	if len(e.doc.Content) > 0 {
+		root = e.doc.Content[0]
+	}
+	steps := []step{{node: root}}
+	if root == nil {
+		return steps, nil
+	}
+	if root.Kind != yaml.MappingNode && !isNull(root) {
+		return nil, errors.New("top-level value is not a mapping")
+	}
+
+	n := root
+	for i, el := range elems {
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		}
+		if isNull(n) {
+			break
+		}
+		if el.isIndex {
+			if n.Kind != yaml.SequenceNode {
+				return nil, errors.Errorf("%s is not a sequence", formatPath(elems[:i]))
+			}
+			if el.index >= len(n.Content) {
+				break
+			}
+			steps = append(steps, step{parent: n, node: n.Content[el.index], index: el.index})
+			n = n.Content[el.index]
+			continue
+		}
+
+		if n.Kind != yaml.MappingNode {
+			return nil, errors.Errorf("%s is not a mapping", formatPath(elems[:i]))
+		}
+		found := false
+		for j := 0; j+1 < len(n.Content); j += 2 {
+			if n.Content[j].Value == el.key {
+				steps = append(steps, step{parent: n, key: n.Content[j], node: n.Content[j+1], index: j / 2})
+				n = n.Content[j+1]
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return steps, nil
+}
+
+// entrySpan returns the offset at which the mapping entry or sequence item
+// at s starts, and the line on which it ends.
+func (e *Editor) entrySpan(s step) (int, int) {
+	return e.entryStart(s), e.entryEndLine(s)
+}
+
+func (e *Editor) entryStart(s step) int {
+	if s.parent.Kind == yaml.MappingNode {
+		return e.offset(s.key.Line, s.key.Column)
+	}
+	return e.lines[e.dashLine(s.parent, s.node)-1] + s.parent.Column - 1
+}
+
+func (e *Editor) entryEndLine(s step) int {
+	if s.parent.Kind == yaml.MappingNode {
+		end := e.endLine(s.node, s.key.Column-1)
+		if end < s.key.Line {
+			end = s.key.Line
+		}
+		return end
+	}
+	return e.endLine(s.node, s.parent.Column-1)
+}
+
+// endLine returns the last line of n, including any lines that follow it
+// and are indented further than indent, such as the rest of a block scalar
+// or trailing comments.
+func (e *Editor) endLine(n *yaml.Node, indent int) int {
+	end := e.lastLine(n)
+	for l := end + 1; l <= len(e.lines); l++ {
+		if e.isBlank(l) {
+			continue
+		}
+		if e.indentOf(l) <= indent {
+			break
+		}
+		end = l
+	}
+	return end
+}
+
+// lastLine returns the line of the last token of n.
+func (e *Editor) lastLine(n *yaml.Node) int {
+	if n.Kind == yaml.AliasNode {
+		return n.Line
+	}
+	if n.Style&yaml.FlowStyle != 0 && (n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode) {
+		if end, ok := e.flowEnd(e.offset(n.Line, n.Column)); ok {
+			return e.lineOf(end - 1)
+		}
+	}
+	last := n.Line
+	for _, c := range n.Content {
+		if l := e.lastLine(c); l > last {
+			last = l
+		}
+	}
+	return last
+}
+
+// dashLine returns the line of the dash that starts item in seq.
+func (e *Editor) dashLine(seq, item *yaml.Node) int {
+	indent := seq.Column - 1
+	for l := item.Line; l >= seq.Line; l-- {
+		line := e.line(l)
+		if e.indentOf(l) == indent && strings.HasPrefix(strings.TrimSpace(line), "-") {
+			return l
+		}
+	}
+	return item.Line
+}
+
+// commentBlockStart returns the first line of the comments directly above
+// line, or line itself if there are none.
+func (e *Editor) commentBlockStart(line int) int {
+	for line > 1 && strings.HasPrefix(strings.TrimSpace(e.line(line-1)), "#") {
+		line--
+	}
+	return line
+}
+
+// scalarSpan returns the offsets of a scalar that is written on a single
+// line in plain or quoted style.
+func (e *Editor) scalarSpan(n *yaml.Node) (int, int, bool) {
+	if n.Kind != yaml.ScalarNode || n.Line == 0 {
+		return 0, 0, false
+	}
+	start := e.offset(n.Line, n.Column)
+	lineEnd := e.lineEnd(n.Line)
+	rest := e.src[start:lineEnd]
+
+	switch n.Style {
+	case 0, yaml.FlowStyle:
+		if strings.Contains(n.Value, "\n") || !bytes.HasPrefix(rest, []byte(n.Value)) {
+			return 0, 0, false
+		}
+		return start, start + len(n.Value), true
+	case yaml.DoubleQuotedStyle, yaml.SingleQuotedStyle:
+		if end, ok := quotedEnd(rest); ok {
+			return start, start + end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// quotedEnd returns the offset just past the quoted string at the start of
+// b, if it ends within b.
+func quotedEnd(b []byte) (int, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+	q := b[0]
+	for i := 1; i < len(b); i++ {
+		switch {
+		case q == '"' && b[i] == '\\':
+			i++
+		case b[i] == q && q == '\'' && i+1 < len(b) && b[i+1] == '\'':
+			i++
+		case b[i] == q:
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// flowEnd returns the offset just past the flow collection that starts at
+// start.
+func (e *Editor) flowEnd(start int) (int, bool) {
+	depth := 0
+	for i := start; i < len(e.src); i++ {
+		switch c := e.src[i]; c {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		case '"', '\'':
+			end, ok := quotedEnd(e.src[i:])
+			if !ok {
+				return 0, false
+			}
+			i += end - 1
+		case '#':
+			if i > 0 && (e.src[i-1] == ' ' || e.src[i-1] == '\t') {
+				for i < len(e.src) && e.src[i] != '\n' {
+					i++
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// offset converts a 1-based line and column into an offset into src.
+func (e *Editor) offset(line, column int) int {
+	o := e.lines[line-1]
+	for i := 1; i < column && o < len(e.src); i++ {
+		_, size := utf8.DecodeRune(e.src[o:])
+		o += size
+	}
+	return o
+}
+
+// lineOf returns the 1-based line that contains offset.
+func (e *Editor) lineOf(offset int) int {
+	return sort.Search(len(e.lines), func(i int) bool { return e.lines[i] > offset })
+}
+
+// lineEnd returns the offset of the start of the line after line.
+func (e *Editor) lineEnd(line int) int {
+	if line < len(e.lines) {
+		return e.lines[line]
+	}
+	return len(e.src)
+}
+
+func (e *Editor) line(line int) string {
+	return strings.TrimRight(string(e.src[e.lines[line-1]:e.lineEnd(line)]), "\r\n")
+}
+
+func (e *Editor) isBlank(line int) bool {
+	return strings.TrimSpace(e.line(line)) == ""
+}
+
+func (e *Editor) indentOf(line int) int {
+	l := e.line(line)
+	return len(l) - len(strings.TrimLeft(l, " "))
+}
+
+// startsLine reports whether n is the first token on its line.
+func (e *Editor) startsLine(n *yaml.Node) bool {
+	return strings.TrimSpace(string(e.src[e.lines[n.Line-1]:e.offset(n.Line, n.Column)])) == ""
+}
+
+// flowIndex returns the index of the outermost flow collection among the
+// first n steps, or -1 if there is none.
+func flowIndex(steps []step, n int) int {
+	for i := 0; i < n; i++ {
+		if node := steps[i].node; node != nil && node.Style&yaml.FlowStyle != 0 &&
+			(node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Tag == "!!null"
+}
+
+// mappingValue returns the value of key in the mapping m, if present.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func toNode(value interface{}) (*yaml.Node, error) {
+	if n, ok := value.(*yaml.Node); ok {
+		return n, nil
+	}
+	var n yaml.Node
+	if err := n.Encode(value); err != nil {
+		return nil, errors.Wrap(err, "encoding value")
+	}
+	return &n, nil
+}
+
+// render encodes n. Every line but the first is indented by indent, so
+// that the result can be written after a key or sequence dash.
+func render(n *yaml.Node, indent int) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return "", errors.Wrap(err, "encoding value")
+	}
+	if err := enc.Close(); err != nil {
+		return "", errors.Wrap(err, "encoding value")
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = spaces(indent) + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func spaces(n int) string {
+	return strings.Repeat(" ", n)
+}
+
+type pathElem struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parsePath parses paths like `parameters[0].slug`.
+func parsePath(path string) ([]pathElem, error) {
+	var elems []pathElem
+	if path == "" {
+		return elems, nil
+	}
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		if i := strings.Index(part, "["); i >= 0 {
+			key = part[:i]
+			part = part[i:]
+		} else {
+			part = ""
+		}
+		if key == "" {
+			return nil, errors.Errorf("invalid path %q: empty key", path)
+		}
+		elems = append(elems, pathElem{key: key})
+
+		for part != "" {
+			end := strings.Index(part, "]")
+			if !strings.HasPrefix(part, "[") || end < 0 {
+				return nil, errors.Errorf("invalid path %q", path)
+			}
+			index, err := strconv.Atoi(part[1:end])
+			if err != nil || index < 0 {
+				return nil, errors.Errorf("invalid path %q: bad index %q", path, part[1:end])
+			}
+			elems = append(elems, pathElem{index: index, isIndex: true})
+			part = part[end+1:]
+		}
+	}
+	return elems, nil
+}
+
+func formatPath(elems []pathElem) string {
+	var b strings.Builder
+	for i, el := range elems {
+		switch {
+		case el.isIndex:
+			b.WriteString("[" + strconv.Itoa(el.index) + "]")
+		case i > 0:
+			b.WriteString("." + el.key)
+		default:
+			b.WriteString(el.key)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,682 @@
+package yaml
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const editorDoc = `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`
+
+func TestEditor(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		edit     func(e *Editor) error
+		expected string
+	}{
+		{
+			name: "set scalar keeps quotes and comments",
+			edit: func(e *Editor) error {
+				return e.Set("name", "Your task")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "Your task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "set nested scalar",
+			edit: func(e *Editor) error {
+				return e.Set("parameters[1].type", "float")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: float
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "set block scalar",
+			edit: func(e *Editor) error {
+				return e.Set("description", "Short.")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: Short.
+`,
+		},
+		{
+			name: "add nested field",
+			edit: func(e *Editor) error {
+				return e.Set("node.envVars.DEBUG.value", "true")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+    DEBUG:
+      value: "true"
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "edit flow mapping",
+			edit: func(e *Editor) error {
+				return e.Set("node.envVars.FLAGS.value", "-vv")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-vv", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "append to flow sequence",
+			edit: func(e *Editor) error {
+				return e.Append("node.envVars.FLAGS.other", "c")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b, c]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "insert parameter",
+			edit: func(e *Editor) error {
+				return e.Insert("parameters", 1, map[string]interface{}{
+					"slug": "dry_run",
+					"type": "boolean",
+				})
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+- slug: dry_run
+  type: boolean
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "append parameter",
+			edit: func(e *Editor) error {
+				return e.Append("parameters", map[string]interface{}{"slug": "dry_run"})
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+- slug: dry_run
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "insert key at the top",
+			edit: func(e *Editor) error {
+				return e.InsertKey("", 0, "version", "0.3")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+version: "0.3"
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "insert key before the first key of an item",
+			edit: func(e *Editor) error {
+				return e.InsertKey("parameters[0]", 0, "name", "Name")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- name: Name
+  slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "delete entry with its comment",
+			edit: func(e *Editor) error {
+				return e.Delete("name")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "delete first key of an item",
+			edit: func(e *Editor) error {
+				return e.Delete("parameters[0].slug")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "delete item",
+			edit: func(e *Editor) error {
+				return e.Delete("parameters[1]")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "delete only entry",
+			edit: func(e *Editor) error {
+				return e.Delete("node.envVars.API_KEY.config")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY: {}
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "delete from flow mapping",
+			edit: func(e *Editor) error {
+				return e.Delete("node.envVars.FLAGS.other")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      config: api_key
+    FLAGS: {value: "-v"}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "replace mapping",
+			edit: func(e *Editor) error {
+				return e.Set("node.envVars.API_KEY", map[string]string{"value": "secret"})
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    API_KEY:
+      value: secret
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+		{
+			name: "rename",
+			edit: func(e *Editor) error {
+				return e.Rename("node.envVars.API_KEY", "TOKEN")
+			},
+			expected: `# Full reference: https://docs.airplane.dev/tasks/task-definition
+
+# Used by Airplane to identify your task. Do not change.
+slug: my_task
+
+# A human-readable name for your task.
+name: "My task" # shown in the UI
+
+parameters:
+- slug: name
+  type: shorttext
+  # The default.
+  default: Alfred
+-
+  slug: count
+  type: integer
+
+node:
+  entrypoint: my_task.ts
+  envVars:
+    TOKEN:
+      config: api_key
+    FLAGS: {value: "-v", other: [a, b]}
+
+description: |
+  Line one.
+  Line two.
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+			e, err := NewEditor([]byte(editorDoc))
+			require.NoError(err)
+			require.NoError(test.edit(e))
+			require.Equal(test.expected, string(e.Bytes()))
+		})
+	}
+}
+
+func TestEditorCreate(t *testing.T) {
+	require := require.New(t)
+
+	e, err := NewEditor([]byte("slug: my_task\nschedules:\n"))
+	require.NoError(err)
+	require.NoError(e.Set("schedules.daily.cron", "0 0 * * *"))
+	require.NoError(e.Append("resources", "db"))
+	require.Equal(`slug: my_task
+schedules:
+  daily:
+    cron: 0 0 * * *
+resources:
+  - db
+`, string(e.Bytes()))
+
+	n, err := e.Get("schedules.daily.cron")
+	require.NoError(err)
+	require.Equal("0 0 * * *", n.Value)
+	n, err = e.Get("schedules.weekly")
+	require.NoError(err)
+	require.Nil(n)
+}
+
+func TestEditorErrors(t *testing.T) {
+	require := require.New(t)
+
+	e, err := NewEditor([]byte("slug: my_task\nparameters:\n- slug: a\n"))
+	require.NoError(err)
+	require.Error(e.Set("slug.name", "x"))
+	require.Error(e.Set("parameters[3].slug", "x"))
+	require.Error(e.Set("parameters[", "x"))
+	require.Error(e.Insert("slug", 0, "x"))
+	require.Error(e.Rename("parameters", "slug"))
+	require.NoError(e.Delete("missing"))
+}
+
+func TestSetYAMLField(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "my_task.task.yaml")
+	require.NoError(ioutil.WriteFile(path, []byte("# My task.\n\nslug: my_task\n\nname: My task\n"), 0644))
+
+	require.NoError(SetYAMLField(path, "id", "tsk123"))
+	require.NoError(SetYAMLField(path, "name", "Renamed"))
+	buf, err := ioutil.ReadFile(path)
+	require.NoError(err)
+	require.Equal("# My task.\n\nid: tsk123\nslug: my_task\n\nname: Renamed\n", string(buf))
+}
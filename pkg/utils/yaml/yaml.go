@@ -1,8 +1,6 @@
 package yaml
 
 import (
-	"os"
-
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
@@ -27,68 +25,14 @@ func GetYAMLNode(node *yaml.Node, field string) (*yaml.Node, error) {
 	return nil, nil
 }
 
+// SetYAMLField sets a top-level field in the YAML file at path, adding it
+// to the top of the file if it does not exist yet. See Editor.
 func SetYAMLField(path, field, value string) error {
-	f, err := os.OpenFile(path, os.O_RDWR, 0)
-	if err != nil {
-		return errors.Wrap(err, "opening task definition")
-	}
-	defer f.Close()
-
-	root := yaml.Node{}
-	if err := yaml.NewDecoder(f).Decode(&root); err != nil {
-		return errors.Wrap(err, "unmarshalling task definition")
-	}
-
-	if len(root.Content) == 0 {
-		return errors.Errorf("cannot insert %s: yaml document empty", field)
-	}
-	// Find the root map, which may not be the first element due to comments.
-	var mapnode *yaml.Node
-	for _, subnode := range root.Content {
-		if subnode.Kind == yaml.MappingNode {
-			mapnode = subnode
-			break
+	return EditFile(path, func(e *Editor) error {
+		if len(e.doc.Content) == 0 {
+			return errors.Errorf("cannot insert %s: yaml document empty", field)
 		}
-	}
-	if mapnode == nil {
-		return errors.Errorf("cannot insert %s: yaml document has map field", field)
-	}
-
-	node, err := GetYAMLNode(mapnode, field)
-	if err != nil {
-		return err
-	}
-
-	if node != nil {
-		// This field already exists, so just update it's value.
-		node.Value = value
-	} else {
-		// This field does not exist yet, so prepend this field into the map.
-		mapnode.Content = append([]*yaml.Node{
-			{
-				Kind:  yaml.ScalarNode,
-				Tag:   "!!str",
-				Value: field,
-			},
-			{
-				Kind:  yaml.ScalarNode,
-				Tag:   "!!str",
-				Value: value,
-			},
-		}, mapnode.Content...)
-	}
-
-	if _, err := f.Seek(0, 0); err != nil {
-		return errors.Wrap(err, "seeking to start of task definition")
-	}
-	if err := f.Truncate(0); err != nil {
-		return errors.Wrap(err, "truncating file")
-	}
-	enc := yaml.NewEncoder(f)
-	enc.SetIndent(2)
-	if err := enc.Encode(&root); err != nil {
-		return errors.Wrap(err, "marshalling task definition")
-	}
-
-	return nil
+		// The field is not parsed as a path, so it may contain dots.
+		return e.insertKey(nil, 0, field, value)
+	})
 }